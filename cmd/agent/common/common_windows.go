@@ -6,8 +6,13 @@
 package common
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/util/defaultpaths"
@@ -19,6 +24,14 @@ import (
 // ServiceName is the name of the Windows Service the agent runs as
 const ServiceName = "DatadogAgent"
 
+// defaultMaxBackups is the number of pre-upgrade backups kept per config file
+// when CheckAndUpgradeConfigOptions.MaxBackups isn't set.
+const defaultMaxBackups = 5
+
+// backupTimestampLayout matches the "<file>.pre-upgrade-<timestamp>" naming
+// used for rotated config backups.
+const backupTimestampLayout = "2006-01-02T15-04-05"
+
 func init() {
 	_, err := winutil.GetProgramDataDir()
 	if err != nil {
@@ -26,9 +39,55 @@ func init() {
 	}
 }
 
+// CheckAndUpgradeConfigOptions controls how CheckAndUpgradeConfig performs the upgrade.
+type CheckAndUpgradeConfigOptions struct {
+	// DryRun, when true, reports the diff that would be written to datadog.yaml
+	// without creating a backup or touching the real config files; ImportConfig
+	// only runs against a temporary scratch directory.
+	DryRun bool
+	// BackupDir is where pre-upgrade backups of datadog.conf and datadog.yaml are
+	// written. Defaults to defaultpaths.ConfPath when empty.
+	BackupDir string
+	// MaxBackups bounds how many pre-upgrade backups are retained per file; older
+	// backups beyond this count are pruned after each successful backup. Defaults
+	// to defaultMaxBackups.
+	MaxBackups int
+}
+
+// ConfigUpgradeError is returned when the upgraded datadog.yaml fails
+// post-upgrade validation. RollbackErr is nil when the automatic restore of
+// the pre-upgrade backup succeeded.
+type ConfigUpgradeError struct {
+	Cause       error
+	RollbackErr error
+}
+
+func (e *ConfigUpgradeError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("config upgrade validation failed (%v), and restoring the previous config also failed: %v", e.Cause, e.RollbackErr)
+	}
+	return fmt.Sprintf("config upgrade validation failed, previous config was restored: %v", e.Cause)
+}
+
+// Unwrap allows errors.Is/As to see the validation failure that triggered the rollback.
+func (e *ConfigUpgradeError) Unwrap() error {
+	return e.Cause
+}
+
 // CheckAndUpgradeConfig checks to see if there's an old datadog.conf, and if
 // datadog.yaml is either missing or incomplete (no API key).  If so, upgrade it
 func CheckAndUpgradeConfig() error {
+	return CheckAndUpgradeConfigWithOptions(CheckAndUpgradeConfigOptions{})
+}
+
+// CheckAndUpgradeConfigWithOptions is CheckAndUpgradeConfig with explicit
+// dry-run and backup-retention controls, see CheckAndUpgradeConfigOptions.
+// In dry-run mode, the diff is logged and reported to the Event Viewer but
+// the real config files are not touched. Otherwise, datadog.conf and any partial
+// datadog.yaml are backed up before the upgrade runs; if the resulting
+// datadog.yaml fails validation, the backup is restored automatically and a
+// *ConfigUpgradeError is returned.
+func CheckAndUpgradeConfigWithOptions(opts CheckAndUpgradeConfigOptions) error {
 	datadogConfPath := filepath.Join(defaultpaths.ConfPath, "datadog.conf")
 	if _, err := os.Stat(datadogConfPath); os.IsNotExist(err) {
 		log.Debug("Previous config file not found, not upgrading")
@@ -43,10 +102,226 @@ func CheckAndUpgradeConfig() error {
 			return nil
 		}
 	}
+
+	if opts.DryRun {
+		diff, err := dryRunConfigUpgrade(defaultpaths.ConfPath)
+		if err != nil {
+			winutil.LogEventViewer(ServiceName, messagestrings.MSG_WARN_CONFIGUPGRADE_FAILED, err.Error())
+			return err
+		}
+		log.Info(diff)
+		winutil.LogEventViewer(ServiceName, messagestrings.MSG_CONFIGUPGRADE_DRYRUN_DIFF, diff)
+		return nil
+	}
+
+	backupDir := opts.BackupDir
+	if backupDir == "" {
+		backupDir = defaultpaths.ConfPath
+	}
+	maxBackups := opts.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+
+	suffix, err := backupConfigFiles(defaultpaths.ConfPath, backupDir, maxBackups)
+	if err != nil {
+		winutil.LogEventViewer(ServiceName, messagestrings.MSG_WARN_CONFIGUPGRADE_FAILED, err.Error())
+		return err
+	}
+	winutil.LogEventViewer(ServiceName, messagestrings.MSG_CONFIGUPGRADE_BACKUP_CREATED, suffix)
+
 	err = ImportConfig(defaultpaths.ConfPath, defaultpaths.ConfPath, false)
 	if err != nil {
 		winutil.LogEventViewer(ServiceName, messagestrings.MSG_WARN_CONFIGUPGRADE_FAILED, err.Error())
 		return err
 	}
+
+	if verr := validateUpgradedConfig(defaultpaths.ConfPath); verr != nil {
+		upgradeErr := &ConfigUpgradeError{Cause: verr}
+		if rerr := restoreConfigBackup(backupDir, defaultpaths.ConfPath, suffix); rerr != nil {
+			upgradeErr.RollbackErr = rerr
+			winutil.LogEventViewer(ServiceName, messagestrings.MSG_WARN_CONFIGUPGRADE_FAILED, upgradeErr.Error())
+			return upgradeErr
+		}
+		winutil.LogEventViewer(ServiceName, messagestrings.MSG_CONFIGUPGRADE_ROLLBACK, verr.Error())
+		return upgradeErr
+	}
+
+	winutil.LogEventViewer(ServiceName, messagestrings.MSG_CONFIGUPGRADE_APPLIED, defaultpaths.ConfPath)
 	return nil
 }
+
+// dryRunConfigUpgrade runs ImportConfig against a scratch directory so the
+// real datadog.yaml is never touched, then returns a unified-diff style
+// report between the current datadog.yaml (if any) and the one ImportConfig
+// would have produced.
+func dryRunConfigUpgrade(confDir string) (string, error) {
+	scratchDir, err := os.MkdirTemp("", "datadog-config-upgrade-dry-run")
+	if err != nil {
+		return "", fmt.Errorf("creating dry-run scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+
+	if err := ImportConfig(confDir, scratchDir, false); err != nil {
+		return "", fmt.Errorf("simulating config upgrade: %w", err)
+	}
+
+	before, err := readFileOrEmpty(filepath.Join(confDir, "datadog.yaml"))
+	if err != nil {
+		return "", err
+	}
+	after, err := readFileOrEmpty(filepath.Join(scratchDir, "datadog.yaml"))
+	if err != nil {
+		return "", err
+	}
+	return diffLines(before, after), nil
+}
+
+// diffLines renders a minimal unified-diff style report between two file
+// contents: lines only in before are prefixed "-", lines only in after "+".
+func diffLines(before, after string) string {
+	beforeLines := strings.Split(before, "\n")
+	afterLines := strings.Split(after, "\n")
+	beforeSet := make(map[string]bool, len(beforeLines))
+	for _, l := range beforeLines {
+		beforeSet[l] = true
+	}
+	afterSet := make(map[string]bool, len(afterLines))
+	for _, l := range afterLines {
+		afterSet[l] = true
+	}
+
+	var b strings.Builder
+	for _, l := range beforeLines {
+		if l != "" && !afterSet[l] {
+			fmt.Fprintf(&b, "-%s\n", l)
+		}
+	}
+	for _, l := range afterLines {
+		if l != "" && !beforeSet[l] {
+			fmt.Fprintf(&b, "+%s\n", l)
+		}
+	}
+	if b.Len() == 0 {
+		return "no changes: datadog.yaml would be unaffected by the upgrade"
+	}
+	return b.String()
+}
+
+func readFileOrEmpty(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// validateUpgradedConfig reloads datadog.yaml from confDir into a fresh
+// config instance so a validation failure doesn't poison the process-wide
+// pkgconfigsetup.Datadog() singleton.
+func validateUpgradedConfig(confDir string) error {
+	cfg := pkgconfigsetup.NewConfig("datadog", "DD", nil)
+	cfg.AddConfigPath(confDir)
+	if _, err := pkgconfigsetup.LoadWithoutSecret(cfg, nil); err != nil {
+		return fmt.Errorf("upgraded datadog.yaml failed validation: %w", err)
+	}
+	return nil
+}
+
+// backupConfigFiles copies datadog.conf and, if present, datadog.yaml from
+// confDir into backupDir as "<file>.pre-upgrade-<timestamp>", then prunes
+// backups beyond maxBackups per file. It returns the timestamp suffix used,
+// so the exact set of files it just wrote can be restored later.
+func backupConfigFiles(confDir, backupDir string, maxBackups int) (string, error) {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("creating backup dir %s: %w", backupDir, err)
+	}
+
+	suffix := time.Now().Format(backupTimestampLayout)
+	for _, name := range []string{"datadog.conf", "datadog.yaml"} {
+		src := filepath.Join(confDir, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		dst := backupPath(backupDir, name, suffix)
+		if err := copyFile(src, dst); err != nil {
+			return "", fmt.Errorf("backing up %s: %w", name, err)
+		}
+		if err := pruneOldBackups(backupDir, name, maxBackups); err != nil {
+			log.Warnf("could not prune old backups of %s: %v", name, err)
+		}
+	}
+	return suffix, nil
+}
+
+// restoreConfigBackup copies the "<file>.pre-upgrade-<suffix>" backups for
+// datadog.conf and datadog.yaml back over confDir, restoring whichever of the
+// two were actually backed up.
+func restoreConfigBackup(backupDir, confDir, suffix string) error {
+	restoredAny := false
+	for _, name := range []string{"datadog.conf", "datadog.yaml"} {
+		src := backupPath(backupDir, name, suffix)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(confDir, name)); err != nil {
+			return fmt.Errorf("restoring %s from backup: %w", name, err)
+		}
+		restoredAny = true
+	}
+	if !restoredAny {
+		return fmt.Errorf("no backup found for suffix %s in %s", suffix, backupDir)
+	}
+	return nil
+}
+
+func backupPath(backupDir, name, suffix string) string {
+	return filepath.Join(backupDir, fmt.Sprintf("%s.pre-upgrade-%s", name, suffix))
+}
+
+// pruneOldBackups removes the oldest "<name>.pre-upgrade-*" backups in dir
+// beyond maxBackups, relying on the timestamp suffix sorting lexicographically
+// in chronological order.
+func pruneOldBackups(dir, name string, maxBackups int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	prefix := name + ".pre-upgrade-"
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+	if len(backups) <= maxBackups {
+		return nil
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-maxBackups] {
+		if err := os.Remove(filepath.Join(dir, old)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLinesNoChanges(t *testing.T) {
+	assert.Equal(t, "no changes: datadog.yaml would be unaffected by the upgrade", diffLines("api_key: abc\n", "api_key: abc\n"))
+}
+
+func TestDiffLinesAddedAndRemoved(t *testing.T) {
+	diff := diffLines("api_key: abc\nsite: datadoghq.com\n", "api_key: abc\nsite: datadoghq.eu\n")
+	assert.Contains(t, diff, "-site: datadoghq.com")
+	assert.Contains(t, diff, "+site: datadoghq.eu")
+	assert.NotContains(t, diff, "api_key: abc")
+}
+
+func TestBackupConfigFilesCreatesMissingBackupDir(t *testing.T) {
+	confDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "datadog.conf"), []byte("legacy config"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "datadog.yaml"), []byte("api_key: abc"), 0644))
+
+	backupDir := filepath.Join(t.TempDir(), "nested", "backups")
+	suffix, err := backupConfigFiles(confDir, backupDir, defaultMaxBackups)
+	require.NoError(t, err)
+
+	confBackup, err := os.ReadFile(backupPath(backupDir, "datadog.conf", suffix))
+	require.NoError(t, err)
+	assert.Equal(t, "legacy config", string(confBackup))
+
+	yamlBackup, err := os.ReadFile(backupPath(backupDir, "datadog.yaml", suffix))
+	require.NoError(t, err)
+	assert.Equal(t, "api_key: abc", string(yamlBackup))
+}
+
+func TestBackupConfigFilesSkipsMissingYAML(t *testing.T) {
+	confDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "datadog.conf"), []byte("legacy config"), 0644))
+
+	backupDir := t.TempDir()
+	suffix, err := backupConfigFiles(confDir, backupDir, defaultMaxBackups)
+	require.NoError(t, err)
+
+	_, err = os.Stat(backupPath(backupDir, "datadog.yaml", suffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRestoreConfigBackupRoundTrip(t *testing.T) {
+	confDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "datadog.conf"), []byte("original"), 0644))
+
+	backupDir := t.TempDir()
+	suffix, err := backupConfigFiles(confDir, backupDir, defaultMaxBackups)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "datadog.conf"), []byte("upgraded"), 0644))
+
+	require.NoError(t, restoreConfigBackup(backupDir, confDir, suffix))
+
+	restored, err := os.ReadFile(filepath.Join(confDir, "datadog.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(restored))
+}
+
+func TestRestoreConfigBackupMissingSuffix(t *testing.T) {
+	err := restoreConfigBackup(t.TempDir(), t.TempDir(), "2000-01-01T00-00-00")
+	assert.Error(t, err)
+}
+
+func TestPruneOldBackupsKeepsMostRecent(t *testing.T) {
+	dir := t.TempDir()
+	suffixes := []string{
+		"2024-01-01T00-00-00",
+		"2024-01-02T00-00-00",
+		"2024-01-03T00-00-00",
+	}
+	for _, s := range suffixes {
+		require.NoError(t, os.WriteFile(backupPath(dir, "datadog.conf", s), []byte("x"), 0644))
+	}
+
+	require.NoError(t, pruneOldBackups(dir, "datadog.conf", 2))
+
+	_, err := os.Stat(backupPath(dir, "datadog.conf", suffixes[0]))
+	assert.True(t, os.IsNotExist(err), "expected the oldest backup to be pruned")
+
+	for _, s := range suffixes[1:] {
+		_, err := os.Stat(backupPath(dir, "datadog.conf", s))
+		assert.NoError(t, err, "expected the 2 most recent backups to survive")
+	}
+}
+
+func TestPruneOldBackupsNoopUnderLimit(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(backupPath(dir, "datadog.conf", "2024-01-01T00-00-00"), []byte("x"), 0644))
+
+	require.NoError(t, pruneOldBackups(dir, "datadog.conf", defaultMaxBackups))
+
+	_, err := os.Stat(backupPath(dir, "datadog.conf", "2024-01-01T00-00-00"))
+	assert.NoError(t, err)
+}
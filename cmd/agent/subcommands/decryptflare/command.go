@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package decryptflare implements 'agent decrypt-flare'.
+package decryptflare
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/util/archive"
+)
+
+// CliParams are the command-line arguments for this subcommand
+type CliParams struct {
+	*command.GlobalParams
+
+	// ArchivePath is the age-encrypted flare archive to decrypt.
+	ArchivePath string
+
+	// IdentityPath is the path to the age identity file (as produced by
+	// `age-keygen -o`) matching one of the archive's recipients.
+	IdentityPath string
+
+	// Format is the archive's container format: zip, tar.gz, or tar.zst.
+	Format string
+
+	// OutputDir is where the decrypted archive is extracted to.
+	OutputDir string
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &CliParams{
+		GlobalParams: globalParams,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "decrypt-flare <archive>",
+		Short: "Decrypt and extract a flare archive encrypted with 'EncryptTo'",
+		Long:  ``,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cliParams.ArchivePath = args[0]
+			return decryptFlare(cliParams)
+		},
+	}
+	cmd.Flags().StringVar(&cliParams.IdentityPath, "identity", "", "Path to the age identity file matching one of the archive's recipients")
+	cmd.Flags().StringVar(&cliParams.Format, "format", "zip", "Archive container format: zip, tar.gz, or tar.zst")
+	cmd.Flags().StringVarP(&cliParams.OutputDir, "output", "o", ".", "Directory to extract the decrypted archive into")
+	_ = cmd.MarkFlagRequired("identity")
+
+	return []*cobra.Command{cmd}
+}
+
+func decryptFlare(cliParams *CliParams) error {
+	identity, err := readIdentity(cliParams.IdentityPath)
+	if err != nil {
+		return fmt.Errorf("reading identity file %q: %w", cliParams.IdentityPath, err)
+	}
+
+	if err := archive.DecryptFlare(cliParams.ArchivePath, identity, archive.Format(cliParams.Format), cliParams.OutputDir); err != nil {
+		return fmt.Errorf("decrypting %q: %w", cliParams.ArchivePath, err)
+	}
+
+	fmt.Printf("Decrypted %q into %q\n", cliParams.ArchivePath, cliParams.OutputDir)
+	return nil
+}
+
+// readIdentity extracts the AGE-SECRET-KEY-1... line from an identity file
+// as produced by `age-keygen -o`, skipping blank lines and "#" comments.
+func readIdentity(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line, nil
+	}
+	return "", fmt.Errorf("no identity found in %q", path)
+}
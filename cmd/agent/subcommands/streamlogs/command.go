@@ -7,12 +7,12 @@
 package streamlogs
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"math/rand"
+	"regexp"
 	"time"
 
 	"go.uber.org/fx"
@@ -36,6 +36,10 @@ type CliParams struct {
 
 	filters diagnostic.Filters
 
+	// messageRe is filters.MessageRegex, compiled once in PreRunE so the
+	// per-line loop doesn't recompile it for every streamed line.
+	messageRe *regexp.Regexp
+
 	// Output represents the output file path to write the log stream to.
 	FilePath string
 
@@ -44,6 +48,23 @@ type CliParams struct {
 
 	//	Quiet represents whether the log stream should be quiet.
 	Quiet bool
+
+	// Format selects how each streamed line is rendered: text, json, or ndjson.
+	Format string
+
+	// MaxSize is the maximum size in bytes of the output file before it's
+	// rotated. Zero disables rotation.
+	MaxSize int64
+
+	// MaxFiles bounds how many rotated output segments are kept.
+	MaxFiles int
+
+	// Compress selects how rotated output segments are compressed: none, gzip, or zstd.
+	Compress string
+
+	// Follow keeps streaming across transient connection errors, reconnecting
+	// with backoff until Duration elapses (or forever if Duration is 0).
+	Follow bool
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -68,14 +89,41 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	cmd.Flags().StringVar(&cliParams.filters.Type, "type", "", "Filter by type")
 	cmd.Flags().StringVar(&cliParams.filters.Source, "source", "", "Filter by source")
 	cmd.Flags().StringVar(&cliParams.filters.Service, "service", "", "Filter by service")
+	cmd.Flags().StringVar(&cliParams.filters.MessageRegex, "message-regex", "", "Filter by a regular expression matched against the log message")
+	cmd.Flags().StringArrayVar(&cliParams.filters.Tags, "tag", nil, "Filter by tag, in key:value form (can be repeated)")
 	cmd.Flags().StringVarP(&cliParams.FilePath, "output", "o", "", "Output file path to write the log stream")
 	cmd.Flags().DurationVarP(&cliParams.Duration, "duration", "d", 0, "Duration of the log stream (default: 0, infinite)")
 	cmd.Flags().BoolVarP(&cliParams.Quiet, "quiet", "q", false, "Quiet mode (no output to stdout)")
-	// PreRunE is used to validate duration before stream-logs is run.
+	cmd.Flags().StringVar(&cliParams.Format, "format", string(diagnostic.FormatText), "Output format: text, json, or ndjson")
+	cmd.Flags().Int64Var(&cliParams.MaxSize, "max-size", 0, "Rotate the output file once it reaches this size in bytes (default: 0, no rotation)")
+	cmd.Flags().IntVar(&cliParams.MaxFiles, "max-files", 0, "Number of rotated output files to keep (default: 5)")
+	cmd.Flags().StringVar(&cliParams.Compress, "compress", string(filesystem.RotatorCompressionNone), "Compress rotated output files: none, gzip, or zstd")
+	cmd.Flags().BoolVar(&cliParams.Follow, "follow", false, "Reconnect with backoff on transient errors instead of exiting")
+	// PreRunE is used to validate duration, format, and rotation flags before stream-logs is run.
 	cmd.PreRunE = func(_ *cobra.Command, _ []string) error {
 		if cliParams.Duration < 0 {
 			return fmt.Errorf("duration must be a positive value")
 		}
+		if !diagnostic.StreamFormat(cliParams.Format).IsValid() {
+			return fmt.Errorf("format must be one of text, json, or ndjson")
+		}
+		cliParams.filters.Format = diagnostic.StreamFormat(cliParams.Format)
+		messageRe, err := cliParams.filters.CompileMessageRegex()
+		if err != nil {
+			return fmt.Errorf("invalid --message-regex: %w", err)
+		}
+		cliParams.messageRe = messageRe
+		switch filesystem.RotatorCompression(cliParams.Compress) {
+		case filesystem.RotatorCompressionNone, filesystem.RotatorCompressionGzip, filesystem.RotatorCompressionZstd:
+		default:
+			return fmt.Errorf("compress must be one of none, gzip, or zstd")
+		}
+		if cliParams.MaxSize < 0 {
+			return fmt.Errorf("max-size must be a positive value")
+		}
+		if cliParams.MaxFiles < 0 {
+			return fmt.Errorf("max-files must be a positive value")
+		}
 		return nil
 	}
 
@@ -84,6 +132,19 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 
 //nolint:revive // TODO(AML) Fix revive linter
 func streamLogs(lc log.Component, config config.Component, cliParams *CliParams) error {
+	return streamLogsWithOptions(lc, config, cliParams, StreamOptions{
+		OnReconnect: func(attempt int, err error) {
+			lc.Warnf("stream-logs: reconnecting (attempt %d) after error: %v", attempt, err)
+		},
+	})
+}
+
+// streamLogsWithOptions is the shared implementation behind streamLogs and
+// StreamLogsWithOptions: it opens (and rotates) the output file, filters and
+// renders each streamed chunk, and dispatches to streamRequest or
+// streamWithReconnect depending on cliParams.Follow. opts is only consulted
+// when cliParams.Follow is set.
+func streamLogsWithOptions(lc log.Component, config config.Component, cliParams *CliParams, opts StreamOptions) error {
 	ipcAddress, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
 	if err != nil {
 		return err
@@ -97,8 +158,7 @@ func streamLogs(lc log.Component, config config.Component, cliParams *CliParams)
 
 	urlstr := fmt.Sprintf("https://%v:%v/agent/stream-logs", ipcAddress, config.GetInt("cmd_port"))
 
-	var f *os.File
-	var bufWriter *bufio.Writer
+	var rotator *filesystem.Rotator
 
 	if cliParams.FilePath != "" {
 		if err = filesystem.EnsureParentDirsExist(cliParams.FilePath); err != nil {
@@ -106,30 +166,211 @@ func streamLogs(lc log.Component, config config.Component, cliParams *CliParams)
 		}
 
 		lc.Infof("Opening file %s for writing logs. This file will be used to store streamlog output.", cliParams.FilePath)
-		f, bufWriter, err = filesystem.OpenFileForWriting(cliParams.FilePath)
+		rotator, err = filesystem.NewRotator(cliParams.FilePath, filesystem.RotatorOptions{
+			MaxSize:     cliParams.MaxSize,
+			MaxFiles:    cliParams.MaxFiles,
+			Compression: filesystem.RotatorCompression(cliParams.Compress),
+		})
 		if err != nil {
 			return fmt.Errorf("error opening file %s for writing: %v", cliParams.FilePath, err)
 		}
 		defer func() {
-			err := bufWriter.Flush()
-			if err != nil {
-				fmt.Printf("Error flushing buffer for log stream: %v", err)
+			if err := rotator.Close(); err != nil {
+				fmt.Printf("Error closing log stream output %s: %v", cliParams.FilePath, err)
 			}
-			f.Close()
 		}()
 	}
 
-	return streamRequest(urlstr, body, cliParams.Duration, func(chunk []byte) {
+	format := diagnostic.StreamFormat(cliParams.Format)
+	onChunk := func(chunk []byte) {
+		chunk = filterChunk(&cliParams.filters, cliParams.messageRe, chunk)
+		if len(chunk) == 0 {
+			return
+		}
+		rendered := renderChunk(format, chunk)
+
 		if !cliParams.Quiet {
-			fmt.Print(string(chunk))
+			fmt.Print(string(rendered))
 		}
 
-		if bufWriter != nil {
-			if _, err = bufWriter.Write(chunk); err != nil {
+		if rotator != nil {
+			if _, err := rotator.Write(rendered); err != nil {
 				fmt.Printf("Error writing stream-logs to file %s: %v", cliParams.FilePath, err)
 			}
 		}
-	})
+	}
+
+	if !cliParams.Follow {
+		return streamRequest(urlstr, body, cliParams.Duration, onChunk)
+	}
+
+	return streamWithReconnect(urlstr, body, cliParams.Duration, opts, onChunk)
+}
+
+// filterChunk drops lines from chunk that don't satisfy filters' MessageRegex
+// and Tags predicates, so --message-regex and --tag actually narrow what's
+// streamed instead of being silently ignored. messageRe is filters'
+// compiled MessageRegex (nil if unset). A line's tags are derived from its
+// own content via extractLineTags, since the plain-text wire protocol
+// carries no structured per-line metadata to check against.
+func filterChunk(filters *diagnostic.Filters, messageRe *regexp.Regexp, chunk []byte) []byte {
+	if messageRe == nil && len(filters.Tags) == 0 {
+		return chunk
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(chunk, "\n"), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !filters.Matches(messageRe, extractLineTags(line), string(line)) {
+			continue
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// extractLineTags pulls the "key:value" tokens out of a raw log line, the
+// same shape --tag expects, so filterChunk can check a line's own tags
+// rather than assuming it carries whatever tags were requested.
+func extractLineTags(line []byte) []string {
+	var tags []string
+	for _, tok := range bytes.Fields(line) {
+		tok = bytes.Trim(tok, ",;")
+		if bytes.Contains(tok, []byte(":")) {
+			tags = append(tags, string(tok))
+		}
+	}
+	return tags
+}
+
+// renderChunk renders a raw chunk of streamed log lines according to format.
+// In FormatText it's passed through unchanged; in FormatJSON/FormatNDJSON each
+// non-empty line is wrapped in a diagnostic.Record and written one per line
+// (FormatJSON indents each record, FormatNDJSON keeps it compact), so file
+// output and stdout output always agree on shape. Record.Tags is derived from
+// the line itself (see extractLineTags) rather than the filter criteria, so
+// it reflects what the line actually carries instead of what was asked for.
+func renderChunk(format diagnostic.StreamFormat, chunk []byte) []byte {
+	if format == diagnostic.FormatText || format == "" {
+		return chunk
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(chunk, "\n"), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		record := diagnostic.Record{
+			Timestamp: time.Now(),
+			Tags:      extractLineTags(line),
+			Message:   string(line),
+		}
+
+		var data []byte
+		var err error
+		if format == diagnostic.FormatJSON {
+			data, err = json.MarshalIndent(record, "", "  ")
+		} else {
+			data, err = json.Marshal(record)
+		}
+		if err != nil {
+			continue
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// defaultInitialBackoff and defaultMaxBackoff bound the exponential backoff
+// used between reconnect attempts when StreamOptions doesn't set MaxBackoff.
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// StreamOptions controls the reconnect/backoff behavior of streamWithReconnect
+// when --follow is set. The zero value uses defaultInitialBackoff,
+// defaultMaxBackoff, and unlimited retries.
+type StreamOptions struct {
+	// MaxBackoff caps the exponential delay between reconnect attempts.
+	MaxBackoff time.Duration
+	// MaxRetries bounds how many consecutive reconnect attempts are made
+	// before giving up. Zero means unlimited retries.
+	MaxRetries int
+	// OnReconnect, if set, is called before each reconnect attempt with the
+	// attempt number (starting at 1) and the error that triggered it.
+	OnReconnect func(attempt int, err error)
+}
+
+// streamWithReconnect calls streamRequest repeatedly, reconnecting with
+// exponential backoff and jitter whenever the underlying chunked transfer
+// errors before duration has elapsed in total (duration bounds the whole
+// session, not any single connection). It gives up and returns the last
+// error once opts.MaxRetries consecutive reconnects have been attempted.
+func streamWithReconnect(url string, body []byte, duration time.Duration, opts StreamOptions, onChunk func([]byte)) error {
+	return streamWithReconnectUsing(streamRequest, url, body, duration, opts, onChunk)
+}
+
+// streamWithReconnectUsing is streamWithReconnect with the request function
+// injected, so the reconnect/backoff loop can be tested without making real
+// HTTP calls.
+func streamWithReconnectUsing(request func(url string, body []byte, duration time.Duration, onChunk func([]byte)) error, url string, body []byte, duration time.Duration, opts StreamOptions, onChunk func([]byte)) error {
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	start := time.Now()
+	backoff := defaultInitialBackoff
+	attempt := 0
+
+	for {
+		var remaining time.Duration
+		if duration > 0 {
+			remaining = duration - time.Since(start)
+			if remaining <= 0 {
+				return nil
+			}
+		}
+
+		err := request(url, body, remaining, onChunk)
+		if err == nil {
+			return nil
+		}
+		if duration > 0 && time.Since(start) >= duration {
+			return nil
+		}
+
+		attempt++
+		if opts.MaxRetries > 0 && attempt > opts.MaxRetries {
+			return fmt.Errorf("stream-logs: giving up after %d reconnect attempts: %w", opts.MaxRetries, err)
+		}
+		if opts.OnReconnect != nil {
+			opts.OnReconnect(attempt, err)
+		}
+
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		if sleep > maxBackoff {
+			sleep = maxBackoff
+		}
+		if duration > 0 {
+			if remaining := duration - time.Since(start); remaining < sleep {
+				sleep = remaining
+			}
+		}
+		if sleep > 0 {
+			time.Sleep(sleep)
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
 }
 
 func streamRequest(url string, body []byte, duration time.Duration, onChunk func([]byte)) error {
@@ -159,3 +400,12 @@ func streamRequest(url string, body []byte, duration time.Duration, onChunk func
 func StreamLogs(log log.Component, config config.Component, cliParams *CliParams) error {
 	return streamLogs(log, config, cliParams)
 }
+
+// StreamLogsWithOptions is StreamLogs for callers that need to drive the
+// --follow reconnect behavior programmatically (e.g. in tests), without
+// shelling out to the CLI. opts is only consulted when cliParams.Follow is
+// set. It shares its entire implementation (including file/rotation output)
+// with streamLogs via streamLogsWithOptions, rather than forking it.
+func StreamLogsWithOptions(lc log.Component, config config.Component, cliParams *CliParams, opts StreamOptions) error {
+	return streamLogsWithOptions(lc, config, cliParams, opts)
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package streamlogs
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+)
+
+func TestExtractLineTags(t *testing.T) {
+	tags := extractLineTags([]byte("level=info env:prod, service:web; plain words here"))
+	want := []string{"env:prod", "service:web"}
+	if len(tags) != len(want) {
+		t.Fatalf("got %v, want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("got %v, want %v", tags, want)
+		}
+	}
+}
+
+func TestFilterChunkMessageRegex(t *testing.T) {
+	filters := &diagnostic.Filters{}
+	messageRe := regexp.MustCompile("boom")
+
+	out := filterChunk(filters, messageRe, []byte("all good\nsomething went boom\nfine again\n"))
+	if string(out) != "something went boom\n" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestFilterChunkTags(t *testing.T) {
+	filters := &diagnostic.Filters{Tags: []string{"env:prod"}}
+
+	out := filterChunk(filters, nil, []byte("msg env:staging\nmsg env:prod\n"))
+	if string(out) != "msg env:prod\n" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestFilterChunkNoPredicatesPassesThrough(t *testing.T) {
+	filters := &diagnostic.Filters{}
+	chunk := []byte("unchanged\n")
+	out := filterChunk(filters, nil, chunk)
+	if string(out) != string(chunk) {
+		t.Errorf("got %q, want %q", out, chunk)
+	}
+}
+
+func TestRenderChunkText(t *testing.T) {
+	chunk := []byte("raw line\n")
+	out := renderChunk(diagnostic.FormatText, chunk)
+	if string(out) != string(chunk) {
+		t.Errorf("got %q, want %q", out, chunk)
+	}
+}
+
+func TestRenderChunkNDJSONUsesLineTags(t *testing.T) {
+	out := renderChunk(diagnostic.FormatNDJSON, []byte("hello env:prod\n"))
+
+	var record diagnostic.Record
+	if err := json.Unmarshal(out, &record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record.Message != "hello env:prod" {
+		t.Errorf("got message %q", record.Message)
+	}
+	if len(record.Tags) != 1 || record.Tags[0] != "env:prod" {
+		t.Errorf("got tags %v", record.Tags)
+	}
+}
+
+func TestStreamWithReconnectGivesUpAfterMaxRetries(t *testing.T) {
+	attempts := 0
+	request := func(_ string, _ []byte, _ time.Duration, _ func([]byte)) error {
+		attempts++
+		return errors.New("boom")
+	}
+
+	var reconnects int
+	opts := StreamOptions{
+		MaxRetries:  2,
+		MaxBackoff:  time.Millisecond,
+		OnReconnect: func(int, error) { reconnects++ },
+	}
+
+	err := streamWithReconnectUsing(request, "url", nil, 0, opts, nil)
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if reconnects != 2 {
+		t.Errorf("expected 2 reconnect notifications, got %d", reconnects)
+	}
+}
+
+func TestStreamWithReconnectStopsOnSuccess(t *testing.T) {
+	attempts := 0
+	request := func(_ string, _ []byte, _ time.Duration, _ func([]byte)) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}
+
+	err := streamWithReconnectUsing(request, "url", nil, 0, StreamOptions{MaxBackoff: time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
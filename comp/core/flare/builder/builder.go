@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package builder defines the types shared between flare callers and the
+// concrete FlareBuilder implementation in comp/core/flare/helpers: what a
+// caller can ask for (FlareArgs) and what it can do with the builder it gets
+// back (FlareBuilder).
+package builder
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/archive"
+)
+
+// FlareArgs carries the optional configuration for a single flare collection
+// run.
+type FlareArgs struct {
+	// ArchiveFormat selects the container format Save packages the flare
+	// into. The zero value is archive.FormatZip, matching the agent's
+	// historical flare format.
+	ArchiveFormat archive.Format
+
+	// Scrubbers pre-registers additional scrubbers for this run, keyed by
+	// name exactly as RegisterScrubber would. It exists so a component that
+	// builds its own FlareBuilder (rather than receiving one built
+	// elsewhere) can register its scrubbers up front.
+	Scrubbers map[string]Scrubber
+
+	// EncryptTo, if set, makes Save and SaveTo age-encrypt the archive for
+	// these recipients (see archive.Recipient) instead of producing it in
+	// the clear, so only a recipient's matching identity can open it.
+	EncryptTo []archive.Recipient
+
+	// MaxConcurrentCollectors bounds how many AddFileFromFuncCtx producers
+	// run at once when Save/SaveTo flush the collector queue. The zero
+	// value picks a small built-in default.
+	MaxConcurrentCollectors int
+
+	// DefaultCollectorTimeout is the timeout an AddFileFromFuncCtx call
+	// gets if it passes zero itself. The zero value picks a built-in
+	// default.
+	DefaultCollectorTimeout time.Duration
+}
+
+// Scrubber is a caller-registered secret redactor that runs in addition to
+// the built-in rules (pkg/util/scrubber) for any destination path its Glob
+// matches. It mirrors the builder's own two scrubbing styles: ScrubBytes for
+// line-based redaction of arbitrary content, and YAMLKeys for node-based
+// redaction that only applies to .yaml/.yml destinations (matching the
+// AddFile/CopyFile* YAML detection). At least one of the two should be set;
+// if both are, both run.
+type Scrubber struct {
+	// Glob selects which destination paths this scrubber runs against. It's
+	// matched with filepath.Match both against the full destination path and
+	// against its base name alone, so a plain extension glob like "*.yaml"
+	// matches regardless of where the file lands in the flare.
+	Glob string
+
+	// ScrubBytes, if set, redacts data the same way the built-in line-based
+	// scrubber does: find and replace secrets in place, leaving everything
+	// else untouched.
+	ScrubBytes func(data []byte) ([]byte, error)
+
+	// YAMLKeys, if set, lists mapping keys whose value should be replaced
+	// wholesale wherever they appear in a YAML destination file. It's
+	// ignored for non-YAML destinations.
+	YAMLKeys []string
+}
+
+// FlareBuilder collects files into a flare and packages them into a single
+// downloadable archive. Implementations scrub known secrets (API keys,
+// passwords, tokens, ...) from everything added through AddFile,
+// AddFileFromFunc, CopyFile, CopyFileTo and CopyDirTo; the "WithoutScrubbing"
+// variants skip that step for data the caller has already sanitized or that
+// must be preserved verbatim.
+type FlareBuilder interface {
+	// AddFileFromFunc calls collect and writes its result as destFile,
+	// relative to the flare root directory, after scrubbing it. If collect
+	// returns an error, that error is returned and no file is written.
+	//
+	// collect runs synchronously, on the caller's goroutine, before
+	// AddFileFromFunc returns; a slow or hung collector stalls whoever calls
+	// it. Prefer AddFileFromFuncCtx for a collector that might be slow.
+	AddFileFromFunc(destFile string, collect func() ([]byte, error)) error
+
+	// AddFileFromFuncCtx queues collect to run later, during Save/SaveTo, on
+	// a bounded worker pool (FlareArgs.MaxConcurrentCollectors) alongside
+	// every other queued collector - so N independent, slow collectors
+	// finish in roughly max(their durations) rather than their sum. collect
+	// is canceled via ctx if it doesn't return within timeout (or
+	// FlareArgs.DefaultCollectorTimeout, if timeout is zero); a canceled or
+	// failing collect doesn't fail the flare or abort other collectors, it's
+	// just logged to flare_creation.log and destFile is left unwritten.
+	AddFileFromFuncCtx(destFile string, timeout time.Duration, collect func(ctx context.Context) ([]byte, error)) error
+
+	// AddFile writes data as destFile, relative to the flare root directory,
+	// after scrubbing it.
+	AddFile(destFile string, data []byte) error
+
+	// AddFileWithoutScrubbing writes data as destFile, relative to the flare
+	// root directory, without scrubbing it.
+	AddFileWithoutScrubbing(destFile string, data []byte) error
+
+	// CopyFile copies srcFile into the flare root, under its base name,
+	// after scrubbing it.
+	CopyFile(srcFile string) error
+
+	// CopyFileTo copies srcFile into the flare as destFile, relative to the
+	// flare root directory, after scrubbing it.
+	CopyFileTo(srcFile, destFile string) error
+
+	// CopyDirTo copies every file under srcDir for which filter returns true
+	// into the flare, under destDir relative to the flare root directory,
+	// after scrubbing it. filter receives the absolute source path of each
+	// file.
+	CopyDirTo(srcDir, destDir string, filter func(string) bool) error
+
+	// CopyDirToWithoutScrubbing behaves like CopyDirTo but does not scrub the
+	// copied files.
+	CopyDirToWithoutScrubbing(srcDir, destDir string, filter func(string) bool) error
+
+	// PrepareFilePath ensures the parent directory of destFile (relative to
+	// the flare root directory) exists, and returns destFile's absolute path.
+	PrepareFilePath(destFile string) (string, error)
+
+	// RegisterDirPerm records the current permissions of path and everything
+	// under it in the builder. The recorded permissions are not written into
+	// the archive that Save or SaveTo produces.
+	RegisterDirPerm(path string)
+
+	// RegisterScrubber adds s to the scrubbers run against every file added
+	// through AddFile, AddFileFromFunc, CopyFile, CopyFileTo and CopyDirTo
+	// whose destination path matches s.Glob, in addition to the built-in
+	// rules. Registering under a name already in use replaces it.
+	RegisterScrubber(name string, s Scrubber)
+
+	// Save packages every file added so far into a single archive (in
+	// FlareArgs.ArchiveFormat, or archive.FormatZip by default) and returns
+	// its path. If FlareArgs.EncryptTo is set, the archive is age-encrypted
+	// for those recipients and the returned path gets an extra
+	// archive.AgeExtension suffix. The builder's staging directory is
+	// removed whether or not Save succeeds.
+	Save() (string, error)
+
+	// SaveTo streams every file added so far directly into w as a single
+	// archive (in format, or archive.FormatZip if format is empty), without
+	// requiring the second on-disk staging file Save creates for the
+	// archive itself. If FlareArgs.EncryptTo is set, w receives the
+	// age-encrypted archive instead of the plain one. It stops early if ctx
+	// is canceled. The builder's staging directory is removed whether or
+	// not SaveTo succeeds.
+	SaveTo(ctx context.Context, w io.Writer, format archive.Format) error
+}
@@ -0,0 +1,638 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package helpers implements the concrete FlareBuilder used to collect and
+// package diagnostic data into a flare archive; see
+// comp/core/flare/builder for the types callers interact with.
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	flarebuilder "github.com/DataDog/datadog-agent/comp/core/flare/builder"
+	"github.com/DataDog/datadog-agent/pkg/util/archive"
+	"github.com/DataDog/datadog-agent/pkg/util/hostname"
+	"github.com/DataDog/datadog-agent/pkg/util/hostname/validate"
+	"github.com/DataDog/datadog-agent/pkg/util/scrubber"
+)
+
+// flareCreationLogFile is written to the flare root as soon as the builder
+// is created, so a flare that fails partway through collection still
+// records when (and whether) it was generated locally.
+const flareCreationLogFile = "flare_creation.log"
+
+// flareManifestFile is written to the flare root during Save/SaveTo, once
+// every file has been added, recording each entry's provenance and
+// integrity info (see manifestEntry).
+const flareManifestFile = "flare_manifest.json"
+
+// defaultMaxConcurrentCollectors bounds AddFileFromFuncCtx concurrency when
+// FlareArgs.MaxConcurrentCollectors isn't set.
+const defaultMaxConcurrentCollectors = 4
+
+// defaultCollectorTimeout bounds an AddFileFromFuncCtx collector's runtime
+// when neither it nor FlareArgs.DefaultCollectorTimeout set one.
+const defaultCollectorTimeout = 30 * time.Second
+
+// pendingCollector is a producer queued by AddFileFromFuncCtx, to be run
+// later by runPendingCollectors.
+type pendingCollector struct {
+	destFile string
+	timeout  time.Duration
+	collect  func(ctx context.Context) ([]byte, error)
+	// source identifies the caller of AddFileFromFuncCtx, captured at queue
+	// time since by the time runCollector runs it's on a worker goroutine
+	// with no useful call stack of its own.
+	source string
+}
+
+// manifestEntry records one written file's provenance and integrity info,
+// for flare_manifest.json: support tooling can use it to confirm a flare
+// hasn't been tampered with after upload, and to filter which files were
+// found to contain secrets.
+type manifestEntry struct {
+	// Path is destFile, relative to the flare root directory, as forward
+	// slashes regardless of OS.
+	Path string `json:"path"`
+	// Size is len(data), after scrubbing.
+	Size int64 `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 of data, after scrubbing.
+	SHA256 string `json:"sha256"`
+	// Source names the call that produced this entry: AddFile,
+	// AddFileWithoutScrubbing, CopyFile, CopyFileTo, CopyDirTo,
+	// CopyDirToWithoutScrubbing, or AddFileFromFunc(Ctx):<caller>, where
+	// <caller> is the calling function's fully-qualified name.
+	Source string `json:"source"`
+	// Scrubbed is true if at least one redaction rule (built-in or
+	// registered via RegisterScrubber) changed this file's content.
+	Scrubbed bool `json:"scrubbed"`
+	// Rules lists the IDs of every rule that fired (see
+	// scrubber.ScrubBytesRules), plus the name of any registered
+	// flarebuilder.Scrubber that changed the content. Empty if Scrubbed is
+	// false.
+	Rules []string `json:"rules,omitempty"`
+}
+
+// builder is the concrete flarebuilder.FlareBuilder implementation: it
+// stages files under flareDir (itself named after the host, so the
+// resulting archive's top-level entries are namespaced by hostname) and
+// packages flareDir's parent, tmpDir, into a single archive on Save.
+type builder struct {
+	// tmpDir is the staging directory's root; it is removed entirely once
+	// Save (or clean) runs.
+	tmpDir string
+	// flareDir is the directory files are actually written under; it is
+	// tmpDir/<cleaned hostname>, so archive entries come out as
+	// "<hostname>/<path>".
+	flareDir string
+	// local records whether this flare was generated without a running
+	// Agent (e.g. "flare local" from the CLI), which is noted in
+	// flare_creation.log.
+	local bool
+	args  flarebuilder.FlareArgs
+
+	// permsInfos records the permissions of every path passed to
+	// RegisterDirPerm (including nested files and directories), keyed by
+	// path. Neither Save nor SaveTo writes them into the archive.
+	permsInfos map[string]os.FileMode
+
+	// scrubbers holds scrubbers registered via RegisterScrubber (and
+	// FlareArgs.Scrubbers, at construction time), keyed by name, run in
+	// addition to the built-in rules.
+	scrubbers map[string]flarebuilder.Scrubber
+
+	// collectorsMu guards pendingCollectors, since AddFileFromFuncCtx may be
+	// called from multiple goroutines collecting data in parallel.
+	collectorsMu      sync.Mutex
+	pendingCollectors []pendingCollector
+
+	// logMu guards appends to flare_creation.log, since collector failures
+	// are logged from the runPendingCollectors worker pool.
+	logMu sync.Mutex
+
+	// manifestMu guards manifest, since entries are recorded from the
+	// runPendingCollectors worker pool as well as the caller's goroutine.
+	manifestMu sync.Mutex
+	manifest   []manifestEntry
+}
+
+// NewFlareBuilder creates a FlareBuilder with a fresh staging directory.
+// local indicates whether the flare is being generated without a running
+// Agent. The caller must call Save (which cleans up regardless of outcome)
+// or clean exactly once.
+func NewFlareBuilder(local bool, args flarebuilder.FlareArgs) (flarebuilder.FlareBuilder, error) {
+	tmpDir, err := os.MkdirTemp("", "flare")
+	if err != nil {
+		return nil, fmt.Errorf("creating flare staging directory: %w", err)
+	}
+
+	hname, err := hostname.Get(context.Background())
+	if err != nil {
+		hname = "unknown"
+	}
+	hname = validate.CleanHostnameDir(hname)
+
+	flareDir := filepath.Join(tmpDir, hname)
+	if err := os.MkdirAll(flareDir, os.ModePerm); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("creating flare root directory: %w", err)
+	}
+
+	scrubbers := make(map[string]flarebuilder.Scrubber, len(args.Scrubbers))
+	for name, s := range args.Scrubbers {
+		scrubbers[name] = s
+	}
+
+	b := &builder{
+		tmpDir:     tmpDir,
+		flareDir:   flareDir,
+		local:      local,
+		args:       args,
+		permsInfos: map[string]os.FileMode{},
+		scrubbers:  scrubbers,
+	}
+
+	logLine := fmt.Sprintf("flare created at %s\n", time.Now().UTC().Format(time.RFC3339))
+	if local {
+		logLine += "this flare was generated without a running Agent\n"
+	}
+	if err := os.WriteFile(filepath.Join(flareDir, flareCreationLogFile), []byte(logLine), os.ModePerm); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("writing %s: %w", flareCreationLogFile, err)
+	}
+
+	return b, nil
+}
+
+// clean removes the builder's entire staging directory.
+func (b *builder) clean() {
+	os.RemoveAll(b.tmpDir)
+}
+
+// Save packages flareDir into a single archive under os.TempDir and removes
+// the staging directory, whether or not packaging succeeded. If
+// FlareArgs.EncryptTo is set, the archive is age-encrypted and the returned
+// path gets an extra archive.AgeExtension suffix.
+func (b *builder) Save() (string, error) {
+	defer b.clean()
+
+	format := b.args.ArchiveFormat
+	if format == "" {
+		format = archive.FormatZip
+	}
+
+	ext := archive.Extension(format)
+	if len(b.args.EncryptTo) > 0 {
+		ext += archive.AgeExtension
+	}
+	archivePath := filepath.Join(os.TempDir(), fmt.Sprintf("datadog-agent-flare-%d%s", time.Now().UnixNano(), ext))
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("creating %q: %w", archivePath, err)
+	}
+	defer out.Close()
+
+	if err := b.saveTo(context.Background(), out, format); err != nil {
+		return "", err
+	}
+	return archivePath, nil
+}
+
+// SaveTo streams every file added so far directly into w, skipping the
+// second on-disk temp file Save creates for the archive itself. Individual
+// entries were already scrubbed when AddFile/CopyFile* staged them under
+// flareDir, so streaming packages exactly what Save would, without doubling
+// disk usage on constrained hosts. If FlareArgs.EncryptTo is set, w receives
+// the age-encrypted archive instead of the plain one.
+func (b *builder) SaveTo(ctx context.Context, w io.Writer, format archive.Format) error {
+	defer b.clean()
+
+	if format == "" {
+		format = archive.FormatZip
+	}
+	return b.saveTo(ctx, w, format)
+}
+
+// saveTo flushes any collectors queued by AddFileFromFuncCtx, writes
+// flare_manifest.json, then packages the staging directory into format and
+// writes it to w, age-encrypting it first if FlareArgs.EncryptTo is set. It
+// doesn't clean up the staging directory; Save and SaveTo do that
+// themselves.
+func (b *builder) saveTo(ctx context.Context, w io.Writer, format archive.Format) error {
+	b.runPendingCollectors()
+
+	if err := b.writeManifest(); err != nil {
+		return err
+	}
+
+	if len(b.args.EncryptTo) == 0 {
+		if err := archive.CreateTo(ctx, format, b.tmpDir, w); err != nil {
+			return fmt.Errorf("packaging flare archive: %w", err)
+		}
+		return nil
+	}
+
+	enc, err := archive.NewEncryptWriter(w, b.args.EncryptTo)
+	if err != nil {
+		return fmt.Errorf("opening flare encryption stream: %w", err)
+	}
+
+	if err := archive.CreateTo(ctx, format, b.tmpDir, enc); err != nil {
+		return fmt.Errorf("packaging flare archive: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("finalizing flare encryption: %w", err)
+	}
+	return nil
+}
+
+// resolvePath joins destFile onto the flare root directory, rejecting any
+// path that would escape it (e.g. via ".." components).
+func (b *builder) resolvePath(destFile string) (string, error) {
+	fullPath := filepath.Join(b.flareDir, destFile)
+	if fullPath != b.flareDir && !strings.HasPrefix(fullPath, b.flareDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("the destination path is not local to the flare root path")
+	}
+	return fullPath, nil
+}
+
+// scrubForPath scrubs data according to destFile's extension: .yaml/.yml
+// files go through the YAML-node-aware scrubber (so a secret held in a list
+// or nested mapping is still fully redacted), falling back to the
+// line-based scrubber for anything else, or if the YAML parse fails. Any
+// scrubbers registered via RegisterScrubber (or FlareArgs.Scrubbers) whose
+// Glob matches destFile then run in addition. It also returns the IDs of
+// every rule that changed data, for the flare manifest: built-in rule IDs
+// (see scrubber.ScrubBytesRules) plus the name of any registered scrubber
+// that fired.
+func (b *builder) scrubForPath(destFile string, data []byte) ([]byte, []string, error) {
+	var ruleIDs []string
+
+	isYAML := false
+	if ext := strings.ToLower(filepath.Ext(destFile)); ext == ".yaml" || ext == ".yml" {
+		isYAML = true
+		if scrubbed, ids, ok := scrubber.ScrubYAMLRules(data); ok {
+			data = scrubbed
+			ruleIDs = append(ruleIDs, ids...)
+		} else if scrubbed, ids, err := scrubber.ScrubBytesRules(data); err == nil {
+			data = scrubbed
+			ruleIDs = append(ruleIDs, ids...)
+		} else {
+			return nil, nil, err
+		}
+	} else {
+		scrubbed, ids, err := scrubber.ScrubBytesRules(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = scrubbed
+		ruleIDs = append(ruleIDs, ids...)
+	}
+
+	for name, s := range b.scrubbers {
+		matched, err := scrubberMatches(s.Glob, destFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scrubber %q: %w", name, err)
+		}
+		if !matched {
+			continue
+		}
+
+		fired := false
+		if isYAML && len(s.YAMLKeys) > 0 {
+			if scrubbed, ok := scrubber.ScrubYAMLKeys(data, s.YAMLKeys); ok {
+				if !bytes.Equal(scrubbed, data) {
+					fired = true
+				}
+				data = scrubbed
+			}
+		}
+		if s.ScrubBytes != nil {
+			scrubbed, err := s.ScrubBytes(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("scrubber %q: %w", name, err)
+			}
+			if !bytes.Equal(scrubbed, data) {
+				fired = true
+			}
+			data = scrubbed
+		}
+		if fired {
+			ruleIDs = append(ruleIDs, name)
+		}
+	}
+
+	return data, ruleIDs, nil
+}
+
+// scrubberMatches reports whether glob matches destFile, either in full or
+// against its base name alone, so a plain extension glob like "*.yaml"
+// applies regardless of where the file lands in the flare.
+func scrubberMatches(glob, destFile string) (bool, error) {
+	if matched, err := filepath.Match(glob, destFile); err != nil {
+		return false, err
+	} else if matched {
+		return true, nil
+	}
+	return filepath.Match(glob, filepath.Base(destFile))
+}
+
+func (b *builder) writeFile(destFile string, data []byte, scrub bool, source string) error {
+	fullPath, err := b.resolvePath(destFile)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+		return fmt.Errorf("creating directory for %q: %w", destFile, err)
+	}
+
+	var ruleIDs []string
+	if scrub {
+		scrubbed, ids, err := b.scrubForPath(destFile, data)
+		if err != nil {
+			return fmt.Errorf("scrubbing %q: %w", destFile, err)
+		}
+		data = scrubbed
+		ruleIDs = ids
+	}
+
+	if err := os.WriteFile(fullPath, data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing %q: %w", destFile, err)
+	}
+
+	b.recordManifestEntry(destFile, data, source, ruleIDs)
+	return nil
+}
+
+// recordManifestEntry appends a manifestEntry for destFile to b.manifest.
+func (b *builder) recordManifestEntry(destFile string, data []byte, source string, ruleIDs []string) {
+	sum := sha256.Sum256(data)
+	entry := manifestEntry{
+		Path:     filepath.ToSlash(destFile),
+		Size:     int64(len(data)),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Source:   source,
+		Scrubbed: len(ruleIDs) > 0,
+		Rules:    ruleIDs,
+	}
+
+	b.manifestMu.Lock()
+	b.manifest = append(b.manifest, entry)
+	b.manifestMu.Unlock()
+}
+
+// writeManifest writes flare_manifest.json to the flare root, listing every
+// entry recorded by writeFile so far. It's called by saveTo, after
+// runPendingCollectors, so the manifest covers files added via
+// AddFileFromFuncCtx too.
+func (b *builder) writeManifest() error {
+	b.manifestMu.Lock()
+	entries := append([]manifestEntry(nil), b.manifest...)
+	b.manifestMu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("building flare manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(b.flareDir, flareManifestFile), data, os.ModePerm); err != nil {
+		return fmt.Errorf("writing %s: %w", flareManifestFile, err)
+	}
+	return nil
+}
+
+// callerFuncName returns the fully-qualified name of whoever called the
+// function that called callerFuncName (e.g. AddFileFromFunc's caller), for
+// attributing an AddFileFromFunc(Ctx) manifest entry to the component that
+// queued it.
+func callerFuncName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	return fn.Name()
+}
+
+func (b *builder) AddFile(destFile string, data []byte) error {
+	return b.writeFile(destFile, data, true, "AddFile")
+}
+
+func (b *builder) AddFileWithoutScrubbing(destFile string, data []byte) error {
+	return b.writeFile(destFile, data, false, "AddFileWithoutScrubbing")
+}
+
+func (b *builder) AddFileFromFunc(destFile string, collect func() ([]byte, error)) error {
+	if _, err := b.resolvePath(destFile); err != nil {
+		return err
+	}
+	caller := callerFuncName()
+
+	data, err := collect()
+	if err != nil {
+		return fmt.Errorf("error collecting data for '%s': %w", destFile, err)
+	}
+
+	return b.writeFile(destFile, data, true, fmt.Sprintf("AddFileFromFunc:%s", caller))
+}
+
+func (b *builder) AddFileFromFuncCtx(destFile string, timeout time.Duration, collect func(ctx context.Context) ([]byte, error)) error {
+	if _, err := b.resolvePath(destFile); err != nil {
+		return err
+	}
+	source := fmt.Sprintf("AddFileFromFuncCtx:%s", callerFuncName())
+
+	b.collectorsMu.Lock()
+	b.pendingCollectors = append(b.pendingCollectors, pendingCollector{destFile: destFile, timeout: timeout, collect: collect, source: source})
+	b.collectorsMu.Unlock()
+	return nil
+}
+
+// runPendingCollectors runs every collector queued by AddFileFromFuncCtx on
+// a worker pool bounded by FlareArgs.MaxConcurrentCollectors, and waits for
+// all of them (or their timeout) before returning. It's called by Save and
+// SaveTo before packaging, so a collector queued after the last Save/SaveTo
+// call still gets picked up by the next one.
+func (b *builder) runPendingCollectors() {
+	b.collectorsMu.Lock()
+	pending := b.pendingCollectors
+	b.pendingCollectors = nil
+	b.collectorsMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	concurrency := b.args.MaxConcurrentCollectors
+	if concurrency <= 0 {
+		concurrency = defaultMaxConcurrentCollectors
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, c := range pending {
+		c := c
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.runCollector(c)
+		}()
+	}
+	wg.Wait()
+}
+
+// runCollector runs a single queued collector with its timeout, writing its
+// result like AddFileFromFunc would on success, or logging the failure to
+// flare_creation.log (without aborting anything else) on error or timeout.
+func (b *builder) runCollector(c pendingCollector) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = b.args.DefaultCollectorTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultCollectorTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := c.collect(ctx)
+		done <- result{data, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			b.logCollectorFailure(c.destFile, res.err)
+			return
+		}
+		if err := b.writeFile(c.destFile, res.data, true, c.source); err != nil {
+			b.logCollectorFailure(c.destFile, err)
+		}
+	case <-ctx.Done():
+		b.logCollectorFailure(c.destFile, ctx.Err())
+	}
+}
+
+func (b *builder) logCollectorFailure(destFile string, err error) {
+	b.appendLog(fmt.Sprintf("collector for %q failed: %v\n", destFile, err))
+}
+
+// appendLog appends line to flare_creation.log, guarding against concurrent
+// writes from the collector worker pool.
+func (b *builder) appendLog(line string) {
+	b.logMu.Lock()
+	defer b.logMu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(b.flareDir, flareCreationLogFile), os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(line)
+}
+
+func (b *builder) CopyFileTo(srcFile, destFile string) error {
+	return b.copyFileTo(srcFile, destFile, true, "CopyFileTo")
+}
+
+func (b *builder) CopyFile(srcFile string) error {
+	return b.copyFileTo(srcFile, filepath.Base(srcFile), true, "CopyFile")
+}
+
+func (b *builder) copyFileTo(srcFile, destFile string, scrub bool, source string) error {
+	data, err := os.ReadFile(srcFile)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", srcFile, err)
+	}
+	return b.writeFile(destFile, data, scrub, source)
+}
+
+func (b *builder) CopyDirTo(srcDir, destDir string, filter func(string) bool) error {
+	return b.copyDirTo(srcDir, destDir, filter, true, "CopyDirTo")
+}
+
+func (b *builder) CopyDirToWithoutScrubbing(srcDir, destDir string, filter func(string) bool) error {
+	return b.copyDirTo(srcDir, destDir, filter, false, "CopyDirToWithoutScrubbing")
+}
+
+func (b *builder) copyDirTo(srcDir, destDir string, filter func(string) bool, scrub bool, source string) error {
+	if _, err := b.resolvePath(destDir); err != nil {
+		return err
+	}
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if filter != nil && !filter(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %q: %w", path, err)
+		}
+
+		return b.writeFile(filepath.Join(destDir, rel), data, scrub, source)
+	})
+}
+
+func (b *builder) PrepareFilePath(destFile string) (string, error) {
+	fullPath, err := b.resolvePath(destFile)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+		return "", fmt.Errorf("creating directory for %q: %w", destFile, err)
+	}
+	return fullPath, nil
+}
+
+func (b *builder) RegisterScrubber(name string, s flarebuilder.Scrubber) {
+	b.scrubbers[name] = s
+}
+
+func (b *builder) RegisterDirPerm(path string) {
+	_ = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // a path we can't stat just isn't recorded
+		}
+		b.permsInfos[p] = info.Mode()
+		return nil
+	})
+}
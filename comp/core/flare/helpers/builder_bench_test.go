@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	flarebuilder "github.com/DataDog/datadog-agent/comp/core/flare/builder"
+)
+
+// benchFlareSize is the total size of the synthetic flare both benchmarks
+// populate, matching the large-flare scenario (Fargate tasks, small VMs)
+// that motivates SaveTo.
+const benchFlareSize = 500 * 1024 * 1024
+
+// benchChunkSize keeps the AddFileWithoutScrubbing call count reasonable
+// while still reaching benchFlareSize.
+const benchChunkSize = 1 << 20
+
+func populateBenchFlare(b *testing.B, fb *builder) {
+	b.Helper()
+
+	chunk := bytes.Repeat([]byte("x"), benchChunkSize)
+	for i := 0; i*benchChunkSize < benchFlareSize; i++ {
+		if err := fb.AddFileWithoutScrubbing(fmt.Sprintf("data/file-%04d.bin", i), chunk); err != nil {
+			b.Fatalf("populating synthetic flare: %v", err)
+		}
+	}
+}
+
+func reportHeapAlloc(b *testing.B) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	b.ReportMetric(float64(mem.HeapAlloc), "heap-bytes")
+}
+
+// BenchmarkSaveTwoPhase measures the existing Save path: every entry already
+// lives under flareDir, and Save packages it into a second on-disk temp file
+// that the caller then has to read back.
+func BenchmarkSaveTwoPhase(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		f, err := NewFlareBuilder(false, flarebuilder.FlareArgs{})
+		if err != nil {
+			b.Fatalf("creating builder: %v", err)
+		}
+		fb := f.(*builder)
+		populateBenchFlare(b, fb)
+		b.StartTimer()
+
+		archivePath, err := fb.Save()
+		if err != nil {
+			b.Fatalf("Save: %v", err)
+		}
+		os.RemoveAll(archivePath)
+	}
+	reportHeapAlloc(b)
+}
+
+// BenchmarkSaveToStreaming measures SaveTo, which streams straight into
+// io.Discard (standing in for an HTTP upload or an S3 multipart put)
+// instead of materializing the archive on disk.
+func BenchmarkSaveToStreaming(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		f, err := NewFlareBuilder(false, flarebuilder.FlareArgs{})
+		if err != nil {
+			b.Fatalf("creating builder: %v", err)
+		}
+		fb := f.(*builder)
+		populateBenchFlare(b, fb)
+		b.StartTimer()
+
+		if err := fb.SaveTo(context.Background(), io.Discard, ""); err != nil {
+			b.Fatalf("SaveTo: %v", err)
+		}
+	}
+	reportHeapAlloc(b)
+}
@@ -7,11 +7,18 @@ package helpers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"filippo.io/age"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -76,7 +83,61 @@ func TestNewFlareBuilder(t *testing.T) {
 }
 
 func TestSave(t *testing.T) {
-	fb := getNewBuilder(t)
+	cases := []struct {
+		name    string
+		format  archive.Format
+		extract func(archivePath, destDir string) error
+	}{
+		{name: "zip", format: archive.FormatZip, extract: archive.Unzip},
+		{name: "tar.gz", format: archive.FormatTarGzip, extract: archive.UntarGzip},
+		{name: "tar.zst", format: archive.FormatTarZstd, extract: archive.UntarZstd},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewFlareBuilder(false, flarebuilder.FlareArgs{ArchiveFormat: tc.format})
+			require.NoError(t, err)
+			fb, success := f.(*builder)
+			require.True(t, success, "FlareBuilder returned by NewFlareBuilder is not a *builder type")
+
+			root := setupDirWithData(t)
+			fb.CopyDirTo(root, "test", func(string) bool { return true })
+			fb.AddFile("test.data", []byte("some data"))
+
+			archivePath, err := fb.Save()
+			require.NoError(t, err)
+			assert.NoDirExists(t, fb.tmpDir)
+			require.FileExists(t, archivePath)
+
+			defer os.RemoveAll(archivePath)
+
+			tmpDir := t.TempDir()
+
+			hname, err := hostname.Get(context.TODO())
+			if err != nil {
+				hname = "unknown"
+			}
+			hname = validate.CleanHostnameDir(hname)
+
+			err = tc.extract(archivePath, tmpDir)
+			assert.NoError(t, err)
+			assert.FileExists(t, filepath.Join(tmpDir, hname, "test.data"))
+			assert.FileExists(t, filepath.Join(tmpDir, hname, "test/depth1/depth2/test4"))
+		})
+	}
+}
+
+func TestSaveEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	f, err := NewFlareBuilder(false, flarebuilder.FlareArgs{
+		ArchiveFormat: archive.FormatZip,
+		EncryptTo:     []archive.Recipient{{Age: identity.Recipient().String()}},
+	})
+	require.NoError(t, err)
+	fb, success := f.(*builder)
+	require.True(t, success, "FlareBuilder returned by NewFlareBuilder is not a *builder type")
 
 	root := setupDirWithData(t)
 	fb.CopyDirTo(root, "test", func(string) bool { return true })
@@ -84,23 +145,26 @@ func TestSave(t *testing.T) {
 
 	archivePath, err := fb.Save()
 	require.NoError(t, err)
-	assert.NoDirExists(t, fb.tmpDir)
-	require.FileExists(t, archivePath)
-
+	assert.Equal(t, ".zip.age", archivePath[len(archivePath)-len(".zip.age"):])
 	defer os.RemoveAll(archivePath)
 
 	tmpDir := t.TempDir()
+	require.NoError(t, archive.DecryptFlare(archivePath, identity.String(), archive.FormatZip, tmpDir))
 
-	hostname, err := hostname.Get(context.TODO())
+	hname, err := hostname.Get(context.TODO())
 	if err != nil {
-		hostname = "unknown"
+		hname = "unknown"
 	}
-	hostname = validate.CleanHostnameDir(hostname)
+	hname = validate.CleanHostnameDir(hname)
 
-	err = archive.Unzip(archivePath, tmpDir)
-	assert.Nil(t, err)
-	assert.FileExists(t, filepath.Join(tmpDir, hostname, "test.data"))
-	assert.FileExists(t, filepath.Join(tmpDir, hostname, "test/depth1/depth2/test4"))
+	assert.FileExists(t, filepath.Join(tmpDir, hname, "test.data"))
+	assert.FileExists(t, filepath.Join(tmpDir, hname, "test/depth1/depth2/test4"))
+
+	// Decrypting with a different identity fails.
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	err = archive.DecryptFlare(archivePath, other.String(), archive.FormatZip, t.TempDir())
+	assert.Error(t, err)
 }
 
 func TestAddFileFromFunc(t *testing.T) {
@@ -125,6 +189,72 @@ func TestAddFileFromFunc(t *testing.T) {
 	assert.NoFileExists(t, filepath.Join(fb.flareDir, "test", "AddFileFromFunc_error"))
 }
 
+// TestAddFileFromFuncSynchronous confirms AddFileFromFunc keeps running its
+// collector (and writing the result) immediately, rather than deferring it
+// to Save like AddFileFromFuncCtx does - existing callers shouldn't see any
+// behavior change from the AddFileFromFuncCtx queue.
+func TestAddFileFromFuncSynchronous(t *testing.T) {
+	fb := getNewBuilder(t)
+	defer fb.clean()
+
+	var ran atomic.Bool
+	err := fb.AddFileFromFunc(FromSlash("test/sync"), func() ([]byte, error) {
+		ran.Store(true)
+		return []byte("some data"), nil
+	})
+	require.NoError(t, err)
+
+	assert.True(t, ran.Load(), "collector should have run before AddFileFromFunc returned")
+	assertFileContent(t, fb, "some data", "test/sync")
+	assert.Empty(t, fb.pendingCollectors)
+}
+
+func TestAddFileFromFuncCtxTimeout(t *testing.T) {
+	fb := getNewBuilder(t)
+	defer fb.clean()
+
+	err := fb.AddFileFromFuncCtx(FromSlash("test/hung"), 50*time.Millisecond, func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err, "queuing a collector never fails")
+	assert.NoFileExists(t, filepath.Join(fb.flareDir, "test", "hung"))
+
+	fb.runPendingCollectors()
+	assert.NoFileExists(t, filepath.Join(fb.flareDir, "test", "hung"), "a timed-out collector must not write its file")
+
+	logContent, err := os.ReadFile(filepath.Join(fb.flareDir, flareCreationLogFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(logContent), `collector for "test/hung" failed`)
+}
+
+func TestAddFileFromFuncCtxConcurrency(t *testing.T) {
+	fb, err := NewFlareBuilder(false, flarebuilder.FlareArgs{MaxConcurrentCollectors: 5})
+	require.NoError(t, err)
+	b := fb.(*builder)
+	defer b.clean()
+
+	const n = 5
+	const perCollector = 200 * time.Millisecond
+	for i := 0; i < n; i++ {
+		i := i
+		err := b.AddFileFromFuncCtx(FromSlash(fmt.Sprintf("test/slow-%d", i)), time.Second, func(_ context.Context) ([]byte, error) {
+			time.Sleep(perCollector)
+			return []byte(fmt.Sprintf("data-%d", i)), nil
+		})
+		require.NoError(t, err)
+	}
+
+	start := time.Now()
+	b.runPendingCollectors()
+	elapsed := time.Since(start)
+
+	assert.Less(t, elapsed, time.Duration(n)*perCollector, "collectors should run concurrently, not sum their durations")
+	for i := 0; i < n; i++ {
+		assertFileContent(t, b, fmt.Sprintf("data-%d", i), fmt.Sprintf("test/slow-%d", i))
+	}
+}
+
 func TestAddFile(t *testing.T) {
 	fb := getNewBuilder(t)
 	defer fb.clean()
@@ -266,6 +396,58 @@ func TestCopyDirTo(t *testing.T) {
 	assertFileContent(t, fb, "some data", filepath.Join("test", "depth1", "depth2", "test4"))
 }
 
+func TestManifest(t *testing.T) {
+	fb := getNewBuilder(t)
+	defer fb.clean()
+
+	root := setupDirWithData(t)
+
+	require.NoError(t, fb.CopyDirTo(
+		root,
+		"test",
+		func(f string) bool {
+			return filepath.Base(f) != "test3"
+		},
+	))
+
+	require.NoError(t, fb.writeManifest())
+
+	data, err := os.ReadFile(filepath.Join(fb.flareDir, flareManifestFile))
+	require.NoError(t, err)
+
+	var entries []manifestEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+
+	byPath := make(map[string]manifestEntry, len(entries))
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+
+	for _, path := range []string{"test/test1", "test/test2", "test/depth1/depth2/test4"} {
+		require.Contains(t, byPath, path)
+	}
+	assert.NotContains(t, byPath, "test/depth1/test3")
+
+	test1 := byPath["test/test1"]
+	assert.Equal(t, "CopyDirTo", test1.Source)
+	assert.False(t, test1.Scrubbed)
+	assert.Empty(t, test1.Rules)
+	expectedContent, err := os.ReadFile(filepath.Join(fb.flareDir, FromSlash("test/test1")))
+	require.NoError(t, err)
+	assert.EqualValues(t, len(expectedContent), test1.Size)
+	sum := sha256.Sum256(expectedContent)
+	assert.Equal(t, hex.EncodeToString(sum[:]), test1.SHA256)
+
+	test2 := byPath["test/test2"]
+	assert.Equal(t, "CopyDirTo", test2.Source)
+	assert.True(t, test2.Scrubbed)
+	assert.Contains(t, test2.Rules, "api_key")
+	scrubbedContent, err := os.ReadFile(filepath.Join(fb.flareDir, FromSlash("test/test2")))
+	require.NoError(t, err)
+	sum2 := sha256.Sum256(scrubbedContent)
+	assert.Equal(t, hex.EncodeToString(sum2[:]), test2.SHA256)
+}
+
 func TestCopyDirToWithoutScrubbing(t *testing.T) {
 	fb := getNewBuilder(t)
 	defer fb.clean()
@@ -312,6 +494,55 @@ func TestCopyFileYamlDetection(t *testing.T) {
 	assertFileContent(t, fb, redacted, "test2.yaml")
 }
 
+func TestRegisterScrubberLineBased(t *testing.T) {
+	fb := getNewBuilder(t)
+	defer fb.clean()
+
+	pdnsKey := regexp.MustCompile(`(?i)(pdns_api_key)\s*:\s*(\S*)`)
+	fb.RegisterScrubber("pdns", flarebuilder.Scrubber{
+		Glob: "*.conf",
+		ScrubBytes: func(data []byte) ([]byte, error) {
+			return pdnsKey.ReplaceAll(data, []byte(`$1: "********"`)), nil
+		},
+	})
+
+	clear := []byte(`init_config:
+instances:
+- host: 127.0.0.1
+  pdns_api_key: supersecretkeythatisntacovered`)
+	redacted := `init_config:
+instances:
+- host: 127.0.0.1
+  pdns_api_key: "********"`
+
+	fb.AddFile("test.conf", clear)
+	assertFileContent(t, fb, redacted, "test.conf")
+
+	// Only files matching the glob go through the registered scrubber.
+	fb.AddFile("test.other", clear)
+	assertFileContent(t, fb, string(clear), "test.other")
+}
+
+func TestRegisterScrubberYAMLKeys(t *testing.T) {
+	fb := getNewBuilder(t)
+	defer fb.clean()
+
+	fb.RegisterScrubber("forwarded-token", flarebuilder.Scrubber{
+		Glob:     "*.yaml",
+		YAMLKeys: []string{"x-forwarded-token"},
+	})
+
+	clear := []byte(`instances:
+- host: 127.0.0.1
+  x-forwarded-token: abcdef`)
+	redacted := `instances:
+  - host: 127.0.0.1
+    x-forwarded-token: "********"`
+
+	fb.AddFile("test.yaml", clear)
+	assertFileContent(t, fb, redacted, "test.yaml")
+}
+
 func TestPrepareFilePath(t *testing.T) {
 	fb := getNewBuilder(t)
 	defer fb.clean()
@@ -10,8 +10,12 @@ package docker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,6 +31,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/workloadmeta/collectors/util"
 	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/config/env"
 	errorspkg "github.com/DataDog/datadog-agent/pkg/errors"
 	"github.com/DataDog/datadog-agent/pkg/sbom/scanner"
@@ -47,6 +52,15 @@ const (
 // imageEventActionSbom is an event that we set to create a fake docker event.
 const imageEventActionSbom = events.Action("sbom")
 
+// podman labels its containers with pod-scoped metadata; surfacing them under
+// our own well-known label keys keeps tag extraction agnostic of the runtime
+// that produced them.
+const (
+	podmanPodIDLabel   = "io.podman.pod.id"
+	podmanPodNameLabel = "io.podman.pod.name"
+	podmanInfraLabel   = "io.podman.infra"
+)
+
 type resolveHook func(ctx context.Context, co container.InspectResponse) (string, error)
 
 type collector struct {
@@ -54,9 +68,19 @@ type collector struct {
 	store   workloadmeta.Component
 	catalog workloadmeta.AgentType
 
-	dockerUtil        *docker.DockerUtil
-	containerEventsCh <-chan *docker.ContainerEvent
-	imageEventsCh     <-chan *docker.ImageEvent
+	// runtime is reported on every Container this collector emits. It's
+	// Docker unless Start ends up routing through a Podman socket instead.
+	runtime workloadmeta.ContainerRuntime
+
+	dockerUtil           *docker.DockerUtil
+	containerEventsCh    <-chan *docker.ContainerEvent
+	imageEventsCh        <-chan *docker.ImageEvent
+	imagePullProgressCh  <-chan *docker.ImagePullProgress
+	imagePullProgressPub *imagePullProgressPublisher
+
+	// manifestCache persists getImageMetadata's results across tag/untag
+	// storms and agent restarts, keyed by image config digest.
+	manifestCache *manifestCache
 
 	// Images are updated from 2 goroutines: the one that handles docker
 	// events, and the one that extracts SBOMS.
@@ -73,26 +97,59 @@ type collector struct {
 func NewCollector() (workloadmeta.CollectorProvider, error) {
 	return workloadmeta.CollectorProvider{
 		Collector: &collector{
-			id:      collectorID,
-			catalog: workloadmeta.NodeAgent | workloadmeta.ProcessAgent,
+			id:            collectorID,
+			catalog:       workloadmeta.NodeAgent | workloadmeta.ProcessAgent,
+			runtime:       workloadmeta.ContainerRuntimeDocker,
+			manifestCache: newManifestCache(),
 		},
 	}, nil
 }
 
+// detectPodmanSocket reports whether a rootless Podman socket is available,
+// along with its path. Podman exposes a Docker-compatible REST API over this
+// socket, so the existing dockerUtil client works against it unmodified.
+func detectPodmanSocket() (string, bool) {
+	if !env.IsFeaturePresent(env.Podman) {
+		return "", false
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return "", false
+	}
+
+	socketPath := filepath.Join(runtimeDir, "podman", "podman.sock")
+	if _, err := os.Stat(socketPath); err != nil {
+		return "", false
+	}
+
+	return socketPath, true
+}
+
 // GetFxOptions returns the FX framework options for the collector
 func GetFxOptions() fx.Option {
 	return fx.Provide(NewCollector)
 }
 
 func (c *collector) Start(ctx context.Context, store workloadmeta.Component) error {
-	if !env.IsFeaturePresent(env.Docker) {
-		return errorspkg.NewDisabled(componentName, "Agent is not running on Docker")
+	podmanSocket, podmanPresent := detectPodmanSocket()
+	if !env.IsFeaturePresent(env.Docker) && !podmanPresent {
+		return errorspkg.NewDisabled(componentName, "Agent is not running on Docker or Podman")
 	}
 
 	c.store = store
 
 	var err error
-	c.dockerUtil, err = docker.GetDockerUtil()
+	if env.IsFeaturePresent(env.Docker) {
+		c.dockerUtil, err = docker.GetDockerUtil()
+	} else {
+		// No Docker Engine socket, but Podman's is present: route every
+		// event subscription and inspect call through it instead. Podman's
+		// Docker-compatible API means dockerUtil doesn't need to know the
+		// difference beyond which socket it's talking to.
+		c.runtime = workloadmeta.ContainerRuntimePodman
+		c.dockerUtil, err = docker.GetDockerUtilWithSocket(podmanSocket)
+	}
 	if err != nil {
 		return err
 	}
@@ -106,7 +163,7 @@ func (c *collector) Start(ctx context.Context, store workloadmeta.Component) err
 		log.Warnf("Can't get pause container filter, no filtering will be applied: %v", err)
 	}
 
-	c.containerEventsCh, c.imageEventsCh, err = c.dockerUtil.SubscribeToEvents(componentName, filter)
+	c.containerEventsCh, c.imageEventsCh, c.imagePullProgressCh, err = c.dockerUtil.SubscribeToEvents(componentName, filter)
 	if err != nil {
 		return err
 	}
@@ -121,6 +178,9 @@ func (c *collector) Start(ctx context.Context, store workloadmeta.Component) err
 		return err
 	}
 
+	c.imagePullProgressPub = newImagePullProgressPublisher(c.store)
+	go c.imagePullProgressPub.run(ctx)
+
 	go c.stream(ctx)
 
 	return nil
@@ -158,6 +218,9 @@ func (c *collector) stream(ctx context.Context) {
 				log.Warnf("%s", err.Error())
 			}
 
+		case progress := <-c.imagePullProgressCh:
+			c.handleImagePullProgress(progress)
+
 		case <-ctx.Done():
 			var err error
 
@@ -218,19 +281,19 @@ func (c *collector) generateEventsFromImageList(ctx context.Context) error {
 	events := make([]workloadmeta.CollectorEvent, 0, len(images))
 
 	for _, img := range images {
-		imgMetadata, err := c.getImageMetadata(ctx, img.ID, nil)
+		imgMetadataList, err := c.getImageMetadata(ctx, img.ID, nil)
 		if err != nil {
 			log.Warnf("%s", err.Error())
 			continue
 		}
 
-		event := workloadmeta.CollectorEvent{
-			Source: workloadmeta.SourceRuntime,
-			Type:   workloadmeta.EventTypeSet,
-			Entity: imgMetadata,
+		for _, imgMetadata := range imgMetadataList {
+			events = append(events, workloadmeta.CollectorEvent{
+				Source: workloadmeta.SourceRuntime,
+				Type:   workloadmeta.EventTypeSet,
+				Entity: imgMetadata,
+			})
 		}
-
-		events = append(events, event)
 	}
 
 	if len(events) > 0 {
@@ -301,19 +364,20 @@ func (c *collector) buildCollectorEvent(ctx context.Context, ev *docker.Containe
 			EntityID: entityID,
 			EntityMeta: workloadmeta.EntityMeta{
 				Name:   strings.TrimPrefix(container.Name, "/"),
-				Labels: container.Config.Labels,
+				Labels: withPodmanPodLabels(container.Config.Labels),
 			},
 			Image:   extractImage(ctx, container, c.dockerUtil.ResolveImageNameFromContainer, c.store),
 			EnvVars: extractEnvVars(container.Config.Env),
 			Ports:   extractPorts(container),
-			Runtime: workloadmeta.ContainerRuntimeDocker,
+			Runtime: c.runtime,
 			State: workloadmeta.ContainerState{
-				Running:    container.State.Running,
-				Status:     extractStatus(container.State),
-				Health:     extractHealth(container.Config.Labels, container.State.Health),
-				StartedAt:  startedAt,
-				FinishedAt: finishedAt,
-				CreatedAt:  createdAt,
+				Running:            container.State.Running,
+				Status:             extractStatus(container.State),
+				Health:             extractHealth(container.Config.Labels, container.State.Health),
+				HealthCheckHistory: extractHealthCheckHistory(container.State.Health),
+				StartedAt:          startedAt,
+				FinishedAt:         finishedAt,
+				CreatedAt:          createdAt,
 			},
 			NetworkIPs:   extractNetworkIPs(container.NetworkSettings.Networks),
 			Hostname:     container.Config.Hostname,
@@ -399,11 +463,42 @@ func extractImage(ctx context.Context, container container.InspectResponse, reso
 	image.Registry = registry
 	image.ShortName = shortName
 	image.Tag = tag
+	// container.Image is the digest Docker actually pulled onto this host,
+	// which - for a manifest list - is already the entry matching this
+	// host's runtime.GOARCH/GOOS: Docker never stores another platform's
+	// content locally, so there's no picking to do among the
+	// ManifestListDigest siblings getImageMetadata may have emitted.
 	image.ID = container.Image
 	image.RepoDigest = util.ExtractRepoDigestFromImage(image.ID, image.Registry, store) // "sha256:digest"
 	return image
 }
 
+// withPodmanPodLabels returns labels unchanged, except that when it
+// recognizes Podman's pod annotations it also copies them under our
+// well-known podman*Label keys, so tag extraction can pick up pod membership
+// without having to know Podman's own annotation naming.
+func withPodmanPodLabels(labels map[string]string) map[string]string {
+	podID, ok := labels["io.podman.annotations.pod-id"]
+	if !ok {
+		return labels
+	}
+
+	out := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		out[k] = v
+	}
+
+	out[podmanPodIDLabel] = podID
+	if podName, ok := labels["io.podman.annotations.pod-name"]; ok {
+		out[podmanPodNameLabel] = podName
+	}
+	if _, infra := labels["io.podman.annotations.infra"]; infra {
+		out[podmanInfraLabel] = "true"
+	}
+
+	return out
+}
+
 func extractEnvVars(env []string) map[string]string {
 	envMap := make(map[string]string)
 
@@ -532,25 +627,79 @@ func extractHealth(containerLabels map[string]string, containerHealth *container
 	return workloadmeta.ContainerHealthUnknown
 }
 
+// defaultHealthCheckHistorySize bounds HealthCheckHistory when
+// docker_health_check_history_size isn't set.
+const defaultHealthCheckHistorySize = 10
+
+// extractHealthCheckHistory returns the most recent healthcheck probes
+// Docker kept for this container, oldest first, bounded to
+// docker_health_check_history_size entries (defaultHealthCheckHistorySize if
+// unset) so a flapping container can't grow this without bound.
+func extractHealthCheckHistory(containerHealth *container.Health) []workloadmeta.HealthCheckResult {
+	if containerHealth == nil || len(containerHealth.Log) == 0 {
+		return nil
+	}
+
+	limit := config.Datadog().GetInt("docker_health_check_history_size")
+	if limit <= 0 {
+		limit = defaultHealthCheckHistorySize
+	}
+
+	entries := containerHealth.Log
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	history := make([]workloadmeta.HealthCheckResult, 0, len(entries))
+	for _, probe := range entries {
+		history = append(history, workloadmeta.HealthCheckResult{
+			ExitCode: probe.ExitCode,
+			Output:   probe.Output,
+			Start:    probe.Start,
+			End:      probe.End,
+		})
+	}
+
+	return history
+}
+
+// handleImagePullProgress forwards one layer's worth of Docker pull progress
+// (Downloading, Extracting, Pull complete, ...) to the coalescing
+// imagePullProgressPublisher, so long-running pulls stay observable without
+// waiting for the terminal ActionPull event handleImageEvent reacts to.
+func (c *collector) handleImagePullProgress(progress *docker.ImagePullProgress) {
+	c.imagePullProgressPub.update(progress.ImageID, workloadmeta.ContainerImagePullLayerProgress{
+		LayerID:         progress.LayerID,
+		Phase:           progress.Phase,
+		BytesDownloaded: progress.BytesDownloaded,
+		BytesTotal:      progress.BytesTotal,
+	})
+}
+
 func (c *collector) handleImageEvent(ctx context.Context, event *docker.ImageEvent, bom *workloadmeta.SBOM) error {
 	c.handleImagesMut.Lock()
 	defer c.handleImagesMut.Unlock()
 
 	switch event.Action {
 	case events.ActionPull, events.ActionTag, events.ActionUnTag, imageEventActionSbom:
-		imgMetadata, err := c.getImageMetadata(ctx, event.ImageID, bom)
+		imgMetadataList, err := c.getImageMetadata(ctx, event.ImageID, bom)
 		if err != nil {
 			return fmt.Errorf("could not get image metadata for image %q: %w", event.ImageID, err)
 		}
 
-		workloadmetaEvent := workloadmeta.CollectorEvent{
-			Source: workloadmeta.SourceRuntime,
-			Type:   workloadmeta.EventTypeSet,
-			Entity: imgMetadata,
+		workloadmetaEvents := make([]workloadmeta.CollectorEvent, 0, len(imgMetadataList))
+		for _, imgMetadata := range imgMetadataList {
+			workloadmetaEvents = append(workloadmetaEvents, workloadmeta.CollectorEvent{
+				Source: workloadmeta.SourceRuntime,
+				Type:   workloadmeta.EventTypeSet,
+				Entity: imgMetadata,
+			})
 		}
 
-		c.store.Notify([]workloadmeta.CollectorEvent{workloadmetaEvent})
+		c.store.Notify(workloadmetaEvents)
 	case events.ActionDelete:
+		c.manifestCache.delete(event.ImageID)
+
 		workloadmetaEvent := workloadmeta.CollectorEvent{
 			Source: workloadmeta.SourceRuntime,
 			Type:   workloadmeta.EventTypeUnset,
@@ -568,7 +717,16 @@ func (c *collector) handleImageEvent(ctx context.Context, event *docker.ImageEve
 	return nil
 }
 
-func (c *collector) getImageMetadata(ctx context.Context, imageID string, newSBOM *workloadmeta.SBOM) (*workloadmeta.ContainerImageMetadata, error) {
+func (c *collector) getImageMetadata(ctx context.Context, imageID string, newSBOM *workloadmeta.SBOM) ([]*workloadmeta.ContainerImageMetadata, error) {
+	// A fresh SBOM always needs fresh metadata to attach it to; everything
+	// else is a good candidate for the on-disk cache, since tag/untag events
+	// for an already-known image don't change its content.
+	if newSBOM == nil {
+		if cached, ok := c.manifestCache.get(imageID); ok {
+			return cached, nil
+		}
+	}
+
 	imgInspect, err := c.dockerUtil.ImageInspect(ctx, imageID)
 	if err != nil {
 		return nil, err
@@ -629,7 +787,7 @@ func (c *collector) getImageMetadata(ctx context.Context, imageID string, newSBO
 	// We add them here to make sure they are present.
 	sbom = util.UpdateSBOMRepoMetadata(sbom, imgInspect.RepoTags, imgInspect.RepoDigests)
 
-	return &workloadmeta.ContainerImageMetadata{
+	localPlatformMetadata := &workloadmeta.ContainerImageMetadata{
 		EntityID: workloadmeta.EntityID{
 			Kind: workloadmeta.KindContainerImageMetadata,
 			ID:   imgInspect.ID,
@@ -646,8 +804,76 @@ func (c *collector) getImageMetadata(ctx context.Context, imageID string, newSBO
 		Architecture: imgInspect.Architecture,
 		Variant:      imgInspect.Variant,
 		Layers:       layersFromDockerHistoryAndInspect(imageHistory, imgInspect),
-		SBOM:         sbom,
-	}, nil
+		// imgInspect.ID is already a config digest for schema2 images; expose
+		// it under its own name so callers don't have to know that.
+		ConfigDigest:   imgInspect.ID,
+		ManifestDigest: manifestDigestFromRepoDigests(imgInspect.RepoDigests),
+		SBOM:           sbom,
+	}
+
+	metadataList := []*workloadmeta.ContainerImageMetadata{localPlatformMetadata}
+
+	// imgInspect only ever describes the platform Docker actually pulled
+	// onto this host; ImageManifests additionally reports the registry's
+	// manifest list, when the image was published as one, so we can surface
+	// an entry per platform for SBOM/vuln correlation across architectures.
+	manifestListDigest, platforms, err := c.dockerUtil.ImageManifests(ctx, imageID)
+	if err != nil {
+		log.Debugf("could not get manifest list for image %q: %s", imageID, err)
+	}
+
+	if manifestListDigest != "" && len(platforms) > 1 {
+		localPlatformMetadata.ManifestListDigest = manifestListDigest
+
+		metadataList = metadataList[:0]
+		for _, platform := range platforms {
+			if platform.Architecture == imgInspect.Architecture && platform.OS == imgInspect.Os {
+				metadataList = append(metadataList, localPlatformMetadata)
+				continue
+			}
+
+			// The other platforms weren't pulled locally, so there's no
+			// layer or config digest information for them without a
+			// separate registry fetch - just enough to let extractImage,
+			// and SBOM/vuln correlation, tell them apart by platform.
+			metadataList = append(metadataList, &workloadmeta.ContainerImageMetadata{
+				EntityID: workloadmeta.EntityID{
+					Kind: workloadmeta.KindContainerImageMetadata,
+					ID:   platform.Digest,
+				},
+				EntityMeta: workloadmeta.EntityMeta{
+					Name:   imageName,
+					Labels: labels,
+				},
+				RepoTags:           imgInspect.RepoTags,
+				RepoDigests:        imgInspect.RepoDigests,
+				SizeBytes:          platform.SizeBytes,
+				OS:                 platform.OS,
+				Architecture:       platform.Architecture,
+				Variant:            platform.Variant,
+				ManifestListDigest: manifestListDigest,
+				SBOM:               sbom,
+			})
+		}
+	}
+
+	if err := c.manifestCache.set(imageID, metadataList); err != nil {
+		log.Debugf("could not cache manifest for image %q: %s", imageID, err)
+	}
+
+	return metadataList, nil
+}
+
+// manifestDigestFromRepoDigests extracts the compressed manifest digest
+// (e.g. "sha256:abc...") from a RepoDigests entry like
+// "datadog/agent@sha256:abc...", if one is available.
+func manifestDigestFromRepoDigests(repoDigests []string) string {
+	for _, repoDigest := range repoDigests {
+		if idx := strings.Index(repoDigest, "@"); idx != -1 {
+			return repoDigest[idx+1:]
+		}
+	}
+	return ""
 }
 
 // it has been observed that docker can return layers that are missing all metadata when inherited from a base container
@@ -655,8 +881,27 @@ func isInheritedLayer(layer image.HistoryResponseItem) bool {
 	return layer.CreatedBy == "" && layer.Size == 0
 }
 
+// chainIDsForDiffIDs computes the OCI "chain ID" for each RootFS diff ID, in
+// the same chronological order Docker returns them:
+// chainID[0] = diffID[0]; chainID[i] = sha256(chainID[i-1] + " " + diffID[i]).
+// This mirrors how containerd's content store keys layers, so SBOM/vuln
+// correlation can match findings against registry blobs.
+func chainIDsForDiffIDs(diffIDs []string) []string {
+	chainIDs := make([]string, len(diffIDs))
+	for i, diffID := range diffIDs {
+		if i == 0 {
+			chainIDs[i] = diffID
+			continue
+		}
+		sum := sha256.Sum256([]byte(chainIDs[i-1] + " " + diffID))
+		chainIDs[i] = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return chainIDs
+}
+
 func layersFromDockerHistoryAndInspect(history []image.HistoryResponseItem, inspect image.InspectResponse) []workloadmeta.ContainerImageLayer {
 	var layers []workloadmeta.ContainerImageLayer
+	chainIDs := chainIDsForDiffIDs(inspect.RootFS.Layers)
 
 	// Loop through history and check how many layers should be assigned a corresponding docker inspect digest
 	layersWithDigests := 0
@@ -691,11 +936,13 @@ func layersFromDockerHistoryAndInspect(history []image.HistoryResponseItem, insp
 		isInheritedLayer := isInheritedLayer(history[i])
 
 		digest := ""
+		chainID := ""
 		if shouldAssignDigests && (isInheritedLayer || !isEmptyLayer) {
 			if isInheritedLayer {
 				log.Debugf("detected an inherited layer for image ID: \"%s\", assigning it digest: \"%s\"", inspect.ID, inspect.RootFS.Layers[inspectIdx])
 			}
 			digest = inspect.RootFS.Layers[inspectIdx]
+			chainID = chainIDs[inspectIdx]
 			inspectIdx++
 		} else {
 			// Fallback to previous behavior
@@ -704,6 +951,7 @@ func layersFromDockerHistoryAndInspect(history []image.HistoryResponseItem, insp
 
 		layer := workloadmeta.ContainerImageLayer{
 			Digest:    digest,
+			ChainID:   chainID,
 			SizeBytes: history[i].Size,
 			History: &v1.History{
 				Created:    &created,
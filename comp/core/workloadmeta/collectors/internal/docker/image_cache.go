@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build docker
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// manifestCacheDirName holds the on-disk image metadata cache, laid out like
+// a containerd content store (blobs/<algo>/<hex>) so that repeated tag/untag
+// events for the same underlying image become cache hits instead of
+// re-inspecting the daemon, and the cache survives agent restarts.
+const manifestCacheDirName = "image-manifest-cache"
+
+// manifestCache persists getImageMetadata's results, keyed by image config
+// digest. Reads are lock-free: writes are published via atomic file rename,
+// so a reader never observes a partially written entry. Callers are
+// responsible for serializing writes among themselves (the collector does
+// this with handleImagesMut).
+type manifestCache struct {
+	dir string
+}
+
+func newManifestCache() *manifestCache {
+	return &manifestCache{
+		dir: filepath.Join(config.Datadog().GetString("run_path"), manifestCacheDirName),
+	}
+}
+
+func (c *manifestCache) blobPath(digest string) (string, error) {
+	algo, hex, ok := strings.Cut(digest, ":")
+	if !ok || algo == "" || hex == "" {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(c.dir, "blobs", algo, hex), nil
+}
+
+// get returns the cached metadata for digest: a single entry for a
+// single-platform image, or one entry per platform for a manifest list.
+func (c *manifestCache) get(digest string) ([]*workloadmeta.ContainerImageMetadata, bool) {
+	path, err := c.blobPath(digest)
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var metadataList []*workloadmeta.ContainerImageMetadata
+	if err := json.Unmarshal(content, &metadataList); err != nil {
+		log.Debugf("discarding corrupt image manifest cache entry for %q: %s", digest, err)
+		return nil, false
+	}
+
+	return metadataList, true
+}
+
+func (c *manifestCache) set(digest string, metadataList []*workloadmeta.ContainerImageMetadata) error {
+	path, err := c.blobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(metadataList)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below has succeeded
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Renaming into place is what makes concurrent reads lock-free: a reader
+	// either sees the old file or the fully-written new one, never a partial
+	// write.
+	return os.Rename(tmpPath, path)
+}
+
+func (c *manifestCache) delete(digest string) {
+	path, err := c.blobPath(digest)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Debugf("could not remove image manifest cache entry for %q: %s", digest, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build docker
+
+package docker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+)
+
+// imagePullProgressPublisher turns Docker's fine-grained pull progress
+// events into workloadmeta.EventTypeSet updates on a ContainerImagePullStatus
+// entity per image. Updates for the same image are coalesced: if a new one
+// arrives before the previous one has been delivered, it replaces it rather
+// than queuing up, so a slow subscriber never causes progress updates to
+// pile up behind it.
+type imagePullProgressPublisher struct {
+	store workloadmeta.Component
+
+	mu      sync.Mutex
+	pending map[string]*workloadmeta.ContainerImagePullStatus
+
+	wake chan struct{}
+}
+
+func newImagePullProgressPublisher(store workloadmeta.Component) *imagePullProgressPublisher {
+	return &imagePullProgressPublisher{
+		store:   store,
+		pending: make(map[string]*workloadmeta.ContainerImagePullStatus),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// update merges layer's progress into imageID's pending status, replacing
+// whatever progress for that layer hadn't been delivered yet.
+func (p *imagePullProgressPublisher) update(imageID string, layer workloadmeta.ContainerImagePullLayerProgress) {
+	p.mu.Lock()
+	status, ok := p.pending[imageID]
+	if !ok {
+		status = &workloadmeta.ContainerImagePullStatus{
+			EntityID: workloadmeta.EntityID{
+				Kind: workloadmeta.KindContainerImagePullStatus,
+				ID:   imageID,
+			},
+		}
+	}
+	status.Phase = layer.Phase
+	status.UpdatedAt = time.Now()
+	status.Layers = upsertLayerProgress(status.Layers, layer)
+	p.pending[imageID] = status
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+		// a flush is already pending; it'll pick up this update too.
+	}
+}
+
+// upsertLayerProgress replaces layers' entry for layer.LayerID, or appends it
+// if this is the first update seen for that layer.
+func upsertLayerProgress(layers []workloadmeta.ContainerImagePullLayerProgress, layer workloadmeta.ContainerImagePullLayerProgress) []workloadmeta.ContainerImagePullLayerProgress {
+	for i := range layers {
+		if layers[i].LayerID == layer.LayerID {
+			layers[i] = layer
+			return layers
+		}
+	}
+	return append(layers, layer)
+}
+
+// run delivers coalesced pull-progress updates to the store until ctx is
+// done.
+func (p *imagePullProgressPublisher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+			p.flush()
+		}
+	}
+}
+
+func (p *imagePullProgressPublisher) flush() {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = make(map[string]*workloadmeta.ContainerImagePullStatus, len(pending))
+	p.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	evs := make([]workloadmeta.CollectorEvent, 0, len(pending))
+	for _, status := range pending {
+		evs = append(evs, workloadmeta.CollectorEvent{
+			Source: workloadmeta.SourceRuntime,
+			Type:   workloadmeta.EventTypeSet,
+			Entity: status,
+		})
+	}
+
+	p.store.Notify(evs)
+}
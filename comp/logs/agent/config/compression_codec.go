@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// CompressionCodec builds the io.WriteCloser used to compress a single
+// outgoing batch, along with the Content-Encoding value the sender should
+// advertise for it. Implementations are looked up by the `compression_kind`
+// (or per-endpoint override) configured on the pipeline.
+type CompressionCodec interface {
+	// NewEncoder wraps w so that writes to the returned WriteCloser are
+	// compressed into w. Closing the WriteCloser flushes and finalizes the
+	// compressed stream.
+	NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error)
+}
+
+// NewCompressionCodec returns the CompressionCodec registered for kind, or an
+// error if kind isn't recognized. zstdDictionary is only used by the zstd codec.
+func NewCompressionCodec(kind string, zstdDictionary []byte) (CompressionCodec, error) {
+	switch kind {
+	case GzipCompressionKind:
+		return gzipCodec{}, nil
+	case ZstdCompressionKind:
+		return zstdCodec{dictionary: zstdDictionary}, nil
+	case Lz4CompressionKind:
+		return lz4Codec{}, nil
+	case SnappyCompressionKind:
+		return snappyCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression kind: %q", kind)
+	}
+}
+
+// CompressionCodec builds the CompressionCodec configured for this pipeline
+// (l.compressionKind()), loading the shared zstd dictionary from
+// l.zstdDictionaryPath() once, here at pipeline start, if one is configured
+// and the pipeline is using zstd.
+func (l *LogsConfigKeys) CompressionCodec() (CompressionCodec, error) {
+	kind := l.compressionKind()
+
+	var dictionary []byte
+	if kind == ZstdCompressionKind {
+		if path := l.zstdDictionaryPath(); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not load zstd dictionary from %q: %w", path, err)
+			}
+			dictionary = data
+			log.Debugf("Pipeline %s loaded zstd dictionary from %s (%d bytes)", l.prefix, path, len(data))
+		}
+	}
+
+	return NewCompressionCodec(kind, dictionary)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error) {
+	enc, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, "", err
+	}
+	return enc, "gzip", nil
+}
+
+type zstdCodec struct {
+	dictionary []byte
+}
+
+func (c zstdCodec) NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error) {
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level))}
+	if len(c.dictionary) > 0 {
+		opts = append(opts, zstd.WithEncoderDict(c.dictionary))
+	}
+	enc, err := zstd.NewWriter(w, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return enc, "zstd", nil
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error) {
+	enc := lz4.NewWriter(w)
+	if err := enc.Apply(lz4.CompressionLevelOption(lz4.CompressionLevel(level))); err != nil {
+		return nil, "", err
+	}
+	return enc, "lz4", nil
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) NewEncoder(w io.Writer, _ int) (io.WriteCloser, string, error) {
+	return snappy.NewBufferedWriter(w), "snappy", nil
+}
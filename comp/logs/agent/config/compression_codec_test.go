@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var payload = []byte(`{"message":"hello world","count":42,"message":"hello world","count":42}`)
+
+func TestGzipCodecRoundTrip(t *testing.T) {
+	codec, err := NewCompressionCodec(GzipCompressionKind, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	enc, contentEncoding, err := codec.NewEncoder(&buf, GzipCompressionLevel)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", contentEncoding)
+	_, err = enc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	r, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestLz4CodecRoundTrip(t *testing.T) {
+	codec, err := NewCompressionCodec(Lz4CompressionKind, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	enc, contentEncoding, err := codec.NewEncoder(&buf, Lz4CompressionLevel)
+	require.NoError(t, err)
+	assert.Equal(t, "lz4", contentEncoding)
+	_, err = enc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	decoded, err := io.ReadAll(lz4.NewReader(&buf))
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestSnappyCodecRoundTrip(t *testing.T) {
+	codec, err := NewCompressionCodec(SnappyCompressionKind, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	enc, contentEncoding, err := codec.NewEncoder(&buf, SnappyCompressionLevel)
+	require.NoError(t, err)
+	assert.Equal(t, "snappy", contentEncoding)
+	_, err = enc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	decoded, err := io.ReadAll(snappy.NewReader(&buf))
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestZstdCodecRoundTrip(t *testing.T) {
+	codec, err := NewCompressionCodec(ZstdCompressionKind, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	enc, contentEncoding, err := codec.NewEncoder(&buf, ZstdCompressionLevel)
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", contentEncoding)
+	_, err = enc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	dec, err := zstd.NewReader(&buf)
+	require.NoError(t, err)
+	defer dec.Close()
+	decoded, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestZstdCodecWithDictionaryRoundTrip(t *testing.T) {
+	dictionary := []byte(`{"message":"hello world","count":`)
+	codec, err := NewCompressionCodec(ZstdCompressionKind, dictionary)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	enc, _, err := codec.NewEncoder(&buf, ZstdCompressionLevel)
+	require.NoError(t, err)
+	_, err = enc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	dec, err := zstd.NewReader(&buf, zstd.WithDecoderDicts(dictionary))
+	require.NoError(t, err)
+	defer dec.Close()
+	decoded, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestNewCompressionCodecUnknownKind(t *testing.T) {
+	_, err := NewCompressionCodec("bogus", nil)
+	assert.Error(t, err)
+}
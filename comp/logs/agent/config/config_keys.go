@@ -25,12 +25,23 @@ type LogsConfigKeys struct {
 
 // CompressionKind constants
 const (
-	GzipCompressionKind  = "gzip"
-	GzipCompressionLevel = 6
-	ZstdCompressionKind  = "zstd"
-	ZstdCompressionLevel = 1
+	GzipCompressionKind    = "gzip"
+	GzipCompressionLevel   = 6
+	ZstdCompressionKind    = "zstd"
+	ZstdCompressionLevel   = 1
+	Lz4CompressionKind     = "lz4"
+	Lz4CompressionLevel    = 0
+	SnappyCompressionKind  = "snappy"
+	SnappyCompressionLevel = 0
 )
 
+// endpointCompressionOverride is the shape of the optional compression_kind /
+// compression_level fields inside an additional_endpoints entry.
+type endpointCompressionOverride struct {
+	CompressionKind  string `json:"compression_kind" mapstructure:"compression_kind"`
+	CompressionLevel int    `json:"compression_level" mapstructure:"compression_level"`
+}
+
 // defaultLogsConfigKeys defines the default YAML keys used to retrieve logs configuration
 func defaultLogsConfigKeys(config pkgconfigmodel.Reader) *LogsConfigKeys {
 	return NewLogsConfigKeys("logs_config.", config)
@@ -41,6 +52,15 @@ func defaultLogsConfigKeysWithVectorOverride(config pkgconfigmodel.Reader) *Logs
 	return NewLogsConfigKeysWithVector("logs_config.", "logs.", config)
 }
 
+// NewOTLPLogsConfigKeys returns a logs configuration keys set rooted at
+// otlp_config.logs, so an OTLP-fed logs pipeline can be configured and built
+// from the same datadog.yaml, independently of the main logs_config pipeline.
+// It reuses every existing knob (compression, batching, sender backoff, ...)
+// since they're all looked up relative to the prefix.
+func NewOTLPLogsConfigKeys(config pkgconfigmodel.Reader) *LogsConfigKeys {
+	return NewLogsConfigKeys("otlp_config.logs.", config)
+}
+
 // NewLogsConfigKeys returns a new logs configuration keys set
 func NewLogsConfigKeys(configPrefix string, config pkgconfigmodel.Reader) *LogsConfigKeys {
 	return &LogsConfigKeys{prefix: configPrefix, vectorPrefix: "", config: config}
@@ -118,19 +138,20 @@ func (l *LogsConfigKeys) devModeUseProto() bool {
 	return l.getConfig().GetBool(l.getConfigKey("dev_mode_use_proto"))
 }
 
+func isValidCompressionKind(kind string) bool {
+	switch kind {
+	case GzipCompressionKind, ZstdCompressionKind, Lz4CompressionKind, SnappyCompressionKind:
+		return true
+	default:
+		return false
+	}
+}
+
 func (l *LogsConfigKeys) compressionKind() string {
 	configKey := l.getConfigKey("compression_kind")
 	compressionKind := l.getConfig().GetString(configKey)
 
-	endpoints, _ := l.getAdditionalEndpoints()
-	if len(endpoints) > 0 {
-		if !l.config.IsConfigured(configKey) {
-			log.Debugf("Additional endpoints detected, pipeline: %s falling back to gzip compression for compatibility", l.prefix)
-			return GzipCompressionKind
-		}
-	}
-
-	if compressionKind == ZstdCompressionKind || compressionKind == GzipCompressionKind {
+	if isValidCompressionKind(compressionKind) {
 		pipelineName := "Main logs agent pipeline"
 		if !strings.Contains(l.prefix, "logs_config") {
 			pipelineName = "Pipeline " + l.prefix
@@ -155,6 +176,33 @@ func (l *LogsConfigKeys) compressionLevel() int {
 	return level
 }
 
+// zstdDictionaryPath returns the path to a shared, pre-trained zstd
+// dictionary used to improve compression ratios on repetitive, structured
+// log payloads (e.g. JSON). An empty string means no dictionary is configured.
+func (l *LogsConfigKeys) zstdDictionaryPath() string {
+	return l.getConfig().GetString(l.getConfigKey("zstd_dictionary_path"))
+}
+
+// compressionOverrideForEndpoint returns the compression_kind/compression_level
+// override declared on the nth entry of additional_endpoints, if any. Each
+// endpoint negotiates its own Content-Encoding explicitly rather than
+// silently falling back to gzip for the whole pipeline.
+func (l *LogsConfigKeys) compressionOverrideForEndpoint(index int) (kind string, level int, ok bool) {
+	var overrides []endpointCompressionOverride
+	configKey := l.getConfigKey("additional_endpoints")
+	if err := structure.UnmarshalKey(l.getConfig(), configKey, &overrides, structure.EnableSquash); err != nil {
+		return "", 0, false
+	}
+	if index < 0 || index >= len(overrides) {
+		return "", 0, false
+	}
+	override := overrides[index]
+	if !isValidCompressionKind(override.CompressionKind) {
+		return "", 0, false
+	}
+	return override.CompressionKind, override.CompressionLevel, true
+}
+
 func (l *LogsConfigKeys) useCompression() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_compression"))
 }
@@ -308,6 +356,24 @@ func (l *LogsConfigKeys) aggregationTimeout() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("aggregation_timeout")) * time.Millisecond
 }
 
+// DataStreamsEnabled reports whether Data Streams Monitoring checkpoints
+// should be attached to messages flowing through this pipeline.
+func (l *LogsConfigKeys) DataStreamsEnabled() bool {
+	return l.getConfig().GetBool(l.getConfigKey("data_streams.enabled"))
+}
+
+// DataStreamsService returns the service name reported on DSM checkpoints
+// emitted by this pipeline.
+func (l *LogsConfigKeys) DataStreamsService() string {
+	return l.getConfig().GetString(l.getConfigKey("data_streams.service"))
+}
+
+// DataStreamsEdgeTags returns the ordered edge tags used to compute the DSM
+// pathway hash for this pipeline (e.g. "direction:out", "type:logs").
+func (l *LogsConfigKeys) DataStreamsEdgeTags() []string {
+	return l.getConfig().GetStringSlice(l.getConfigKey("data_streams.edge_tags"))
+}
+
 func (l *LogsConfigKeys) useV2API() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_v2_api"))
 }
@@ -326,6 +392,42 @@ func (l *LogsConfigKeys) obsPipelineWorkerEnabled() bool {
 	return l.getConfig().GetBool(l.getObsPipelineConfigKey("vector", "enabled"))
 }
 
+// OTLPAttributeMapping describes how OTLP log record attributes are mapped
+// onto the agent's internal log message shape.
+type OTLPAttributeMapping struct {
+	ResourceAttributesAsTags bool
+	MessageField             string
+	SeverityField            string
+}
+
+// OTLPReceiverEnabled reports whether the OTLP-fed logs pipeline should be started.
+func (l *LogsConfigKeys) OTLPReceiverEnabled() bool {
+	return l.getConfig().GetBool(l.getConfigKey("enabled"))
+}
+
+// OTLPEndpoints returns the configured gRPC and HTTP receiver endpoints for
+// the OTLP-fed logs pipeline, in that order. An empty string means the
+// corresponding protocol is disabled.
+func (l *LogsConfigKeys) OTLPEndpoints() (grpcEndpoint, httpEndpoint string) {
+	return l.getConfig().GetString(l.getConfigKey("receiver.protocols.grpc.endpoint")),
+		l.getConfig().GetString(l.getConfigKey("receiver.protocols.http.endpoint"))
+}
+
+// OTLPBatchWait returns the batch wait duration for the OTLP-fed logs pipeline.
+func (l *LogsConfigKeys) OTLPBatchWait() time.Duration {
+	return l.getConfig().GetDuration(l.getConfigKey("batch_wait"))
+}
+
+// OTLPAttributeMapping returns how OTLP log record attributes should be
+// translated into the agent's internal log message.
+func (l *LogsConfigKeys) OTLPAttributeMapping() OTLPAttributeMapping {
+	return OTLPAttributeMapping{
+		ResourceAttributesAsTags: l.getConfig().GetBool(l.getConfigKey("resource_attributes_as_tags")),
+		MessageField:             l.getConfig().GetString(l.getConfigKey("message_field")),
+		SeverityField:            l.getConfig().GetString(l.getConfigKey("severity_field")),
+	}
+}
+
 func (l *LogsConfigKeys) getObsPipelineURL() (string, bool) {
 	if l.vectorPrefix != "" {
 		configKey := l.getObsPipelineConfigKey("observability_pipelines_worker", "url")
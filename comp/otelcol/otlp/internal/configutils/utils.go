@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+// Package configutils holds the confmap.ProviderFactory implementations the
+// OTLP pipeline's config resolver is built from.
+package configutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+// mapLocation is the fixed URI the map provider answers to.
+const mapLocation = "map:nop"
+
+// defaultHTTPTimeout and defaultRefreshInterval are used by NewProviderFactories
+// when the corresponding Option isn't passed.
+const (
+	defaultHTTPTimeout     = 10 * time.Second
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// NewMapFromYAMLString parses a YAML document into a *confmap.Conf, for
+// callers that already have config content in hand rather than a URI to fetch
+// it from (tests, in-memory defaults).
+func NewMapFromYAMLString(content string) (*confmap.Conf, error) {
+	var rawConf map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &rawConf); err != nil {
+		return nil, fmt.Errorf("could not parse YAML: %w", err)
+	}
+	return confmap.NewFromStringMap(rawConf), nil
+}
+
+// mapProvider answers mapLocation with a fixed, in-memory *confmap.Conf.
+type mapProvider struct {
+	conf *confmap.Conf
+}
+
+func (p *mapProvider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if uri != mapLocation {
+		return nil, fmt.Errorf("%q uri is not supported by the map provider", uri)
+	}
+	return confmap.NewRetrieved(p.conf.ToStringMap())
+}
+
+func (*mapProvider) Scheme() string { return "map" }
+
+func (*mapProvider) Shutdown(context.Context) error { return nil }
+
+// NewProviderFactory wraps cfgMap as a confmap.ProviderFactory that always
+// answers the fixed "map:nop" URI with cfgMap's content, so a constant config
+// can be swapped in wherever a URI-driven provider is expected.
+func NewProviderFactory(cfgMap *confmap.Conf) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+		return &mapProvider{conf: cfgMap}
+	})
+}
+
+// providerOptions holds the settings shared by the http, https, and remote
+// provider factories returned by NewProviderFactories.
+type providerOptions struct {
+	httpTimeout     time.Duration
+	refreshInterval time.Duration
+	verifySignature func([]byte) error
+	tlsConfig       *tls.Config
+}
+
+// Option configures the provider factories returned by NewProviderFactories.
+type Option func(*providerOptions)
+
+// WithHTTPTimeout bounds how long the http/https/remote providers wait for a
+// single fetch. Defaults to defaultHTTPTimeout.
+func WithHTTPTimeout(d time.Duration) Option {
+	return func(o *providerOptions) { o.httpTimeout = d }
+}
+
+// WithRefreshInterval sets how often the remote provider re-fetches its URL
+// to check for changes. Defaults to defaultRefreshInterval.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(o *providerOptions) { o.refreshInterval = d }
+}
+
+// WithSignatureVerifier installs a callback the remote provider runs against
+// every freshly fetched body before accepting it; a non-nil error rejects the update.
+func WithSignatureVerifier(verify func([]byte) error) Option {
+	return func(o *providerOptions) { o.verifySignature = verify }
+}
+
+// WithTLSConfig sources the TLS client config used by the https and remote
+// providers, typically derived from the agent's IPC component.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *providerOptions) { o.tlsConfig = tlsConfig }
+}
+
+// NewProviderFactories returns the confmap.ProviderFactory slice used to build
+// otelcol.ConfigProviderSettings.ResolverSettings.ProviderFactories: the
+// constant map provider wrapping cfgMap, plus http, https, and remote
+// (periodically re-fetched, optionally signature-verified) providers.
+func NewProviderFactories(cfgMap *confmap.Conf, opts ...Option) []confmap.ProviderFactory {
+	o := providerOptions{
+		httpTimeout:     defaultHTTPTimeout,
+		refreshInterval: defaultRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return []confmap.ProviderFactory{
+		NewProviderFactory(cfgMap),
+		newHTTPProviderFactory("http", o),
+		newHTTPProviderFactory("https", o),
+		newRemoteProviderFactory(o),
+	}
+}
+
+func httpClientFor(scheme string, o providerOptions) *http.Client {
+	client := &http.Client{Timeout: o.httpTimeout}
+	if scheme == "https" && o.tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: o.tlsConfig}
+	}
+	return client
+}
+
+// httpProvider fetches its config as YAML from a plain HTTP(S) URL, once per Retrieve.
+type httpProvider struct {
+	scheme string
+	client *http.Client
+}
+
+func newHTTPProviderFactory(scheme string, o providerOptions) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+		return &httpProvider{scheme: scheme, client: httpClientFor(scheme, o)}
+	})
+}
+
+func (p *httpProvider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	rawConf, _, err := fetchYAMLMap(ctx, p.client, uri)
+	if err != nil {
+		return nil, err
+	}
+	return confmap.NewRetrieved(rawConf)
+}
+
+func (p *httpProvider) Scheme() string { return p.scheme }
+
+func (*httpProvider) Shutdown(context.Context) error { return nil }
+
+// remoteProvider fetches its config as YAML from a "remote:<url>" URI and, once
+// a watcher is supplied, keeps polling that URL every refreshInterval,
+// triggering the watcher when the fetched bytes' hash changes.
+type remoteProvider struct {
+	client   *http.Client
+	interval time.Duration
+	verify   func([]byte) error
+
+	mu       sync.Mutex
+	lastHash [sha256.Size]byte
+	cancel   context.CancelFunc
+}
+
+func newRemoteProviderFactory(o providerOptions) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+		return &remoteProvider{
+			client:   httpClientFor("https", o),
+			interval: o.refreshInterval,
+			verify:   o.verifySignature,
+		}
+	})
+}
+
+func (*remoteProvider) Scheme() string { return "remote" }
+
+func (p *remoteProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	url := strings.TrimPrefix(uri, "remote:")
+	rawConf, body, err := fetchYAMLMap(ctx, p.client, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verifyBody(body); err != nil {
+		return nil, fmt.Errorf("verifying signature of config fetched from %s: %w", url, err)
+	}
+
+	p.mu.Lock()
+	p.lastHash = sha256.Sum256(body)
+	p.mu.Unlock()
+
+	if watcher != nil && p.interval > 0 {
+		watchCtx, cancel := context.WithCancel(context.Background())
+		p.mu.Lock()
+		prevCancel := p.cancel
+		p.cancel = cancel
+		p.mu.Unlock()
+		if prevCancel != nil {
+			prevCancel()
+		}
+		go p.watch(watchCtx, url, watcher)
+	}
+
+	return confmap.NewRetrieved(rawConf)
+}
+
+func (p *remoteProvider) verifyBody(body []byte) error {
+	if p.verify == nil {
+		return nil
+	}
+	return p.verify(body)
+}
+
+// watch polls url every p.interval, notifying watcher whenever the fetched
+// bytes' hash differs from the last accepted one, until ctx is canceled by Shutdown.
+func (p *remoteProvider) watch(ctx context.Context, url string, watcher confmap.WatcherFunc) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, body, err := fetchYAMLMap(ctx, p.client, url)
+			if err != nil {
+				watcher(&confmap.ChangeEvent{Error: err})
+				continue
+			}
+			if err := p.verifyBody(body); err != nil {
+				watcher(&confmap.ChangeEvent{Error: err})
+				continue
+			}
+
+			hash := sha256.Sum256(body)
+			p.mu.Lock()
+			changed := hash != p.lastHash
+			p.lastHash = hash
+			p.mu.Unlock()
+
+			if changed {
+				watcher(&confmap.ChangeEvent{})
+			}
+		}
+	}
+}
+
+func (p *remoteProvider) Shutdown(context.Context) error {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// fetchYAMLMap GETs uri and parses the response body as YAML, returning both
+// the parsed map and the raw bytes (so callers can hash/verify them).
+func fetchYAMLMap(ctx context.Context, client *http.Client, uri string) (map[string]interface{}, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", uri, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetching %s: unexpected status %s", uri, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading response from %s: %w", uri, err)
+	}
+
+	var rawConf map[string]interface{}
+	if err := yaml.Unmarshal(body, &rawConf); err != nil {
+		return nil, nil, fmt.Errorf("parsing config fetched from %s: %w", uri, err)
+	}
+	return rawConf, body, nil
+}
@@ -8,8 +8,12 @@ package configutils
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -89,3 +93,68 @@ func TestNewConfigProviderFromMap(t *testing.T) {
 
 	assert.Equal(t, cfg, defaultCfg, "Custom constant provider does not provide same config as default provider.")
 }
+
+func TestHTTPProviderFactory(t *testing.T) {
+	content, err := os.ReadFile(testPath)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	factories := NewProviderFactories(confmap.New(), WithHTTPTimeout(5*time.Second))
+	settings := otelcol.ConfigProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:               []string{server.URL},
+			ProviderFactories:  factories,
+			ConverterFactories: []confmap.ConverterFactory{},
+		},
+	}
+
+	provider, err := otelcol.NewConfigProvider(settings)
+	require.NoError(t, err)
+
+	cfg, err := provider.Get(context.Background(), buildTestFactories(t))
+	require.NoError(t, err)
+	assert.NotNil(t, cfg)
+}
+
+func TestRemoteProviderReload(t *testing.T) {
+	content, err := os.ReadFile(testPath)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	served := content
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write(served)
+	}))
+	defer server.Close()
+
+	factory := newRemoteProviderFactory(providerOptions{
+		httpTimeout:     5 * time.Second,
+		refreshInterval: 20 * time.Millisecond,
+	})
+	provider := factory.Create(confmap.ProviderSettings{})
+	defer provider.Shutdown(context.Background())
+
+	changed := make(chan struct{}, 1)
+	_, err = provider.Retrieve(context.Background(), "remote:"+server.URL, func(event *confmap.ChangeEvent) {
+		if event.Error == nil {
+			changed <- struct{}{}
+		}
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	served = append([]byte("extra_key: extra_value\n"), content...)
+	mu.Unlock()
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected remote provider to detect the config change and notify the watcher")
+	}
+}
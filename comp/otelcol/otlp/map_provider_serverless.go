@@ -0,0 +1,331 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+//go:build otlp && serverless
+
+// Package otlp builds the in-memory OpenTelemetry Collector configuration
+// used to run the OTLP ingest pipeline embedded in the serverless agent.
+package otlp
+
+import (
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// PipelineConfig holds the settings needed to build the OTLP Collector
+// configuration map via buildMap.
+type PipelineConfig struct {
+	// OTLPReceiverConfig holds the "protocols" section of the otlp receiver
+	// (grpc/http endpoints), as built by testutil.OTLPConfigFromPorts.
+	OTLPReceiverConfig map[string]interface{}
+
+	// TracePort is the port the trace agent's OTLP intake listens on; the
+	// traces pipeline's otlp exporter sends to localhost:TracePort.
+	TracePort int
+
+	// TracesEnabled wires up the traces pipeline when set.
+	TracesEnabled bool
+
+	// ExporterQueue configures the otlp exporter's sending_queue, including
+	// durable, on-disk buffering when StorageDirectory is set.
+	ExporterQueue QueueConfig
+	// ExporterRetry configures the otlp exporter's retry_on_failure.
+	ExporterRetry RetryConfig
+
+	// MetricsEnabled wires up the metrics pipeline when set.
+	MetricsEnabled bool
+	// Metrics is the serializer exporter's "metrics" configuration.
+	Metrics map[string]interface{}
+
+	// LogsEnabled wires up the logs pipeline when set.
+	LogsEnabled bool
+	// Logs is the logsagent exporter's "logs" configuration.
+	Logs map[string]interface{}
+
+	// MemoryLimiter, when non-nil, adds a memory_limiter processor ahead of
+	// every enabled pipeline's exporters, protecting the agent against a
+	// bursty OTLP client.
+	MemoryLimiter *MemoryLimiterConfig
+	// BatchProcessor, when non-nil, adds a batch processor at the end of
+	// every enabled pipeline, so spans/datapoints cross the exporter
+	// boundary in batches rather than one at a time.
+	BatchProcessor *BatchProcessorConfig
+
+	// Debug is the debug exporter's configuration; when its "verbosity" is a
+	// valid, non-empty value, the debug exporter is appended to every
+	// enabled pipeline.
+	Debug map[string]interface{}
+}
+
+// QueueConfig mirrors the subset of exporterhelper.QueueSettings the otlp
+// exporter exposes: a bounded in-memory queue, optionally backed by a
+// file_storage extension so queued items survive an agent restart.
+type QueueConfig struct {
+	// Enabled turns on the sending_queue; when false, the exporter blocks
+	// synchronously on the destination as before.
+	Enabled bool
+	// NumConsumers is the number of consumers draining the queue.
+	NumConsumers int
+	// QueueSize is the maximum number of batches the queue holds.
+	QueueSize int
+	// StorageDirectory, when set, makes the queue durable across restarts by
+	// backing it with a file_storage extension rooted at this directory.
+	StorageDirectory string
+}
+
+// RetryConfig mirrors the subset of exporterhelper.RetrySettings the otlp
+// exporter exposes.
+type RetryConfig struct {
+	// Enabled turns on retry_on_failure for transient errors.
+	Enabled bool
+	// InitialInterval is the time to wait before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the exponential backoff between retries.
+	MaxInterval time.Duration
+	// MaxElapsedTime bounds the total time spent retrying before giving up.
+	MaxElapsedTime time.Duration
+}
+
+// fileStorageExtension is the name used for the file_storage extension that
+// backs a durable sending_queue.
+const fileStorageExtension = "file_storage"
+
+// MemoryLimiterConfig configures the memory_limiter processor inserted
+// ahead of every enabled pipeline's exporters.
+type MemoryLimiterConfig struct {
+	// CheckInterval is how often memory usage is checked. Defaults to 1s.
+	CheckInterval time.Duration
+	// LimitMiB is the hard memory limit, in MiB. Defaults to 512.
+	LimitMiB int
+	// SpikeLimitMiB is the extra headroom, in MiB, above which the limiter
+	// starts refusing data even under LimitMiB. Defaults to 128.
+	SpikeLimitMiB int
+}
+
+// BatchProcessorConfig configures the batch processor appended at the end
+// of every enabled pipeline.
+type BatchProcessorConfig struct {
+	// SendBatchSize is the number of items a batch is flushed at. Defaults
+	// to 8192.
+	SendBatchSize int
+	// Timeout is the max duration a batch is held open before being
+	// flushed regardless of size. Defaults to 1s.
+	Timeout time.Duration
+	// SendBatchMaxSize hard-caps a batch's size; 0 leaves it unbounded.
+	SendBatchMaxSize int
+}
+
+const (
+	defaultBatchSendSize              = 8192
+	defaultBatchTimeout               = 1 * time.Second
+	defaultMemoryLimiterCheckInterval = 1 * time.Second
+	defaultMemoryLimiterLimitMiB      = 512
+	defaultMemoryLimiterSpikeLimitMiB = 128
+)
+
+// memoryLimiterMap builds the memory_limiter processor's configuration,
+// filling in defaults for any unset field.
+func memoryLimiterMap(cfg *MemoryLimiterConfig) map[string]interface{} {
+	checkInterval := cfg.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = defaultMemoryLimiterCheckInterval
+	}
+	limitMiB := cfg.LimitMiB
+	if limitMiB <= 0 {
+		limitMiB = defaultMemoryLimiterLimitMiB
+	}
+	spikeLimitMiB := cfg.SpikeLimitMiB
+	if spikeLimitMiB <= 0 {
+		spikeLimitMiB = defaultMemoryLimiterSpikeLimitMiB
+	}
+	return map[string]interface{}{
+		"check_interval":  checkInterval.String(),
+		"limit_mib":       limitMiB,
+		"spike_limit_mib": spikeLimitMiB,
+	}
+}
+
+// batchMap builds the batch processor's configuration, filling in defaults
+// for any unset field.
+func batchMap(cfg *BatchProcessorConfig) map[string]interface{} {
+	sendBatchSize := cfg.SendBatchSize
+	if sendBatchSize <= 0 {
+		sendBatchSize = defaultBatchSendSize
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultBatchTimeout
+	}
+	m := map[string]interface{}{
+		"send_batch_size": sendBatchSize,
+		"timeout":         timeout.String(),
+	}
+	if cfg.SendBatchMaxSize > 0 {
+		m["send_batch_max_size"] = cfg.SendBatchMaxSize
+	}
+	return m
+}
+
+// validVerbosity reports whether v is a verbosity level the debug exporter
+// accepts.
+func validVerbosity(v interface{}) bool {
+	switch v {
+	case "basic", "normal", "detailed":
+		return true
+	default:
+		return false
+	}
+}
+
+// buildMap builds the OpenTelemetry Collector configuration map for the
+// serverless OTLP pipeline described by cfg.
+func buildMap(cfg PipelineConfig) (*confmap.Conf, error) {
+	receivers := map[string]interface{}{
+		"otlp": map[string]interface{}{
+			"protocols": cfg.OTLPReceiverConfig,
+		},
+	}
+
+	exporters := map[string]interface{}{}
+	extensions := map[string]interface{}{}
+	serviceExtensions := []interface{}{}
+	pipelines := map[string]interface{}{}
+
+	processors := map[string]interface{}{}
+	var pipelineProcessors []interface{}
+	if cfg.MemoryLimiter != nil {
+		processors["memory_limiter"] = memoryLimiterMap(cfg.MemoryLimiter)
+		pipelineProcessors = append(pipelineProcessors, "memory_limiter")
+	}
+	if cfg.BatchProcessor != nil {
+		processors["batch"] = batchMap(cfg.BatchProcessor)
+		pipelineProcessors = append(pipelineProcessors, "batch")
+	}
+
+	debugVerbosity, hasDebug := cfg.Debug["verbosity"]
+	hasDebug = hasDebug && validVerbosity(debugVerbosity)
+	if hasDebug {
+		exporters["debug"] = map[string]interface{}{
+			"verbosity": debugVerbosity,
+		}
+	}
+
+	if cfg.TracesEnabled {
+		otlpExporter := map[string]interface{}{
+			"tls": map[string]interface{}{
+				"insecure": true,
+			},
+			"compression":   "none",
+			"endpoint":      localEndpoint(cfg.TracePort),
+			"sending_queue": sendingQueueMap(cfg.ExporterQueue),
+		}
+		if cfg.ExporterRetry.Enabled {
+			otlpExporter["retry_on_failure"] = map[string]interface{}{
+				"enabled":          true,
+				"initial_interval": cfg.ExporterRetry.InitialInterval.String(),
+				"max_interval":     cfg.ExporterRetry.MaxInterval.String(),
+				"max_elapsed_time": cfg.ExporterRetry.MaxElapsedTime.String(),
+			}
+		}
+		if cfg.ExporterQueue.Enabled && cfg.ExporterQueue.StorageDirectory != "" {
+			extensions[fileStorageExtension] = map[string]interface{}{
+				"directory": cfg.ExporterQueue.StorageDirectory,
+			}
+			serviceExtensions = append(serviceExtensions, fileStorageExtension)
+		}
+		exporters["otlp"] = otlpExporter
+		tracesExporters := []interface{}{"otlp"}
+		if hasDebug {
+			tracesExporters = append(tracesExporters, "debug")
+		}
+		pipelines["traces"] = pipelineMap(pipelineProcessors, tracesExporters)
+	}
+
+	if cfg.MetricsEnabled {
+		exporters["serializer"] = map[string]interface{}{
+			"metrics": cfg.Metrics,
+		}
+		metricsExporters := []interface{}{"serializer"}
+		if hasDebug {
+			metricsExporters = append(metricsExporters, "debug")
+		}
+		pipelines["metrics"] = pipelineMap(pipelineProcessors, metricsExporters)
+	}
+
+	if cfg.LogsEnabled {
+		exporters["logsagent"] = map[string]interface{}{
+			"logs": cfg.Logs,
+		}
+		logsExporters := []interface{}{"logsagent"}
+		if hasDebug {
+			logsExporters = append(logsExporters, "debug")
+		}
+		pipelines["logs"] = pipelineMap(pipelineProcessors, logsExporters)
+	}
+
+	service := map[string]interface{}{
+		"telemetry": map[string]interface{}{
+			"metrics": map[string]interface{}{"level": "none"},
+		},
+		"pipelines": pipelines,
+	}
+	if len(serviceExtensions) > 0 {
+		service["extensions"] = serviceExtensions
+	}
+
+	retMap := map[string]interface{}{
+		"receivers": receivers,
+		"exporters": exporters,
+		"service":   service,
+	}
+	if len(processors) > 0 {
+		retMap["processors"] = processors
+	}
+	if len(extensions) > 0 {
+		retMap["extensions"] = extensions
+	}
+	return confmap.NewFromStringMap(retMap), nil
+}
+
+// pipelineMap builds a single pipeline's entry in service.pipelines,
+// wiring the shared otlp receiver, processors (if any), and the given
+// exporters.
+func pipelineMap(processors []interface{}, exporters []interface{}) map[string]interface{} {
+	m := map[string]interface{}{
+		"receivers": []interface{}{"otlp"},
+		"exporters": exporters,
+	}
+	if len(processors) > 0 {
+		m["processors"] = processors
+	}
+	return m
+}
+
+// sendingQueueMap builds the otlp exporter's sending_queue configuration. A
+// disabled queue matches the exporter's pre-queue-support default.
+func sendingQueueMap(q QueueConfig) map[string]interface{} {
+	if !q.Enabled {
+		return map[string]interface{}{
+			"enabled": false,
+		}
+	}
+	m := map[string]interface{}{
+		"enabled":       true,
+		"num_consumers": q.NumConsumers,
+		"queue_size":    q.QueueSize,
+	}
+	if q.StorageDirectory != "" {
+		m["storage"] = fileStorageExtension
+	}
+	return m
+}
+
+// localEndpoint formats the address the traces pipeline's otlp exporter
+// sends to: the trace agent's OTLP intake, on the local host.
+func localEndpoint(port int) string {
+	return "localhost:" + strconv.Itoa(port)
+}
@@ -10,6 +10,7 @@ package otlp
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -471,6 +472,381 @@ func TestNewMap(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "only HTTP, only logs",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 0, 1234),
+				TracePort:          5003,
+				LogsEnabled:        true,
+				Logs: map[string]interface{}{
+					"use_compression": true,
+				},
+				Debug: map[string]interface{}{
+					"verbosity": "none",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"http": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"exporters": map[string]interface{}{
+					"logsagent": map[string]interface{}{
+						"logs": map[string]interface{}{
+							"use_compression": true,
+						},
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"logs": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"logsagent"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "only HTTP, traces and metrics and logs, logging with normal verbosity",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 0, 1234),
+				TracePort:          5003,
+				TracesEnabled:      true,
+				MetricsEnabled:     true,
+				Metrics: map[string]interface{}{
+					"delta_ttl":                   2000,
+					"resource_attributes_as_tags": true,
+					"histograms": map[string]interface{}{
+						"mode":                   "counters",
+						"send_count_sum_metrics": true,
+					},
+				},
+				LogsEnabled: true,
+				Logs: map[string]interface{}{
+					"use_compression": true,
+				},
+				Debug: map[string]interface{}{
+					"verbosity": "normal",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"http": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"exporters": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"tls": map[string]interface{}{
+							"insecure": true,
+						},
+						"compression": "none",
+						"endpoint":    "localhost:5003",
+						"sending_queue": map[string]interface{}{
+							"enabled": false,
+						},
+					},
+					"serializer": map[string]interface{}{
+						"metrics": map[string]interface{}{
+							"delta_ttl":                   2000,
+							"resource_attributes_as_tags": true,
+							"histograms": map[string]interface{}{
+								"mode":                   "counters",
+								"send_count_sum_metrics": true,
+							},
+						},
+					},
+					"logsagent": map[string]interface{}{
+						"logs": map[string]interface{}{
+							"use_compression": true,
+						},
+					},
+					"debug": map[string]interface{}{
+						"verbosity": "normal",
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"otlp", "debug"},
+						},
+						"metrics": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"serializer", "debug"},
+						},
+						"logs": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"logsagent", "debug"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "only gRPC, only Traces, in-memory queue and retry",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 1234, 0),
+				TracePort:          5003,
+				TracesEnabled:      true,
+				ExporterQueue: QueueConfig{
+					Enabled:      true,
+					NumConsumers: 10,
+					QueueSize:    1000,
+				},
+				ExporterRetry: RetryConfig{
+					Enabled:         true,
+					InitialInterval: 5 * time.Second,
+					MaxInterval:     30 * time.Second,
+					MaxElapsedTime:  5 * time.Minute,
+				},
+				Debug: map[string]interface{}{
+					"verbosity": "none",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"grpc": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"exporters": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"tls": map[string]interface{}{
+							"insecure": true,
+						},
+						"compression": "none",
+						"endpoint":    "localhost:5003",
+						"sending_queue": map[string]interface{}{
+							"enabled":       true,
+							"num_consumers": 10,
+							"queue_size":    1000,
+						},
+						"retry_on_failure": map[string]interface{}{
+							"enabled":          true,
+							"initial_interval": "5s",
+							"max_interval":     "30s",
+							"max_elapsed_time": "5m0s",
+						},
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"otlp"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "only gRPC, only Traces, persistent queue with file_storage",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 1234, 0),
+				TracePort:          5003,
+				TracesEnabled:      true,
+				ExporterQueue: QueueConfig{
+					Enabled:          true,
+					NumConsumers:     10,
+					QueueSize:        1000,
+					StorageDirectory: "/var/lib/datadog-agent/otlp-queue",
+				},
+				Debug: map[string]interface{}{
+					"verbosity": "none",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"grpc": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"extensions": map[string]interface{}{
+					"file_storage": map[string]interface{}{
+						"directory": "/var/lib/datadog-agent/otlp-queue",
+					},
+				},
+				"exporters": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"tls": map[string]interface{}{
+							"insecure": true,
+						},
+						"compression": "none",
+						"endpoint":    "localhost:5003",
+						"sending_queue": map[string]interface{}{
+							"enabled":       true,
+							"num_consumers": 10,
+							"queue_size":    1000,
+							"storage":       "file_storage",
+						},
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry":  map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"extensions": []interface{}{"file_storage"},
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"otlp"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "only gRPC, traces and metrics, memory_limiter and batch",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 1234, 0),
+				TracePort:          5003,
+				TracesEnabled:      true,
+				MetricsEnabled:     true,
+				Metrics: map[string]interface{}{
+					"delta_ttl": 2000,
+				},
+				MemoryLimiter: &MemoryLimiterConfig{
+					CheckInterval: 2 * time.Second,
+					LimitMiB:      1024,
+					SpikeLimitMiB: 256,
+				},
+				BatchProcessor: &BatchProcessorConfig{
+					SendBatchSize:    4096,
+					Timeout:          2 * time.Second,
+					SendBatchMaxSize: 8192,
+				},
+				Debug: map[string]interface{}{
+					"verbosity": "none",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"grpc": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"processors": map[string]interface{}{
+					"memory_limiter": map[string]interface{}{
+						"check_interval":  "2s",
+						"limit_mib":       1024,
+						"spike_limit_mib": 256,
+					},
+					"batch": map[string]interface{}{
+						"send_batch_size":     4096,
+						"timeout":             "2s",
+						"send_batch_max_size": 8192,
+					},
+				},
+				"exporters": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"tls": map[string]interface{}{
+							"insecure": true,
+						},
+						"compression": "none",
+						"endpoint":    "localhost:5003",
+						"sending_queue": map[string]interface{}{
+							"enabled": false,
+						},
+					},
+					"serializer": map[string]interface{}{
+						"metrics": map[string]interface{}{
+							"delta_ttl": 2000,
+						},
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers":  []interface{}{"otlp"},
+							"processors": []interface{}{"memory_limiter", "batch"},
+							"exporters":  []interface{}{"otlp"},
+						},
+						"metrics": map[string]interface{}{
+							"receivers":  []interface{}{"otlp"},
+							"processors": []interface{}{"memory_limiter", "batch"},
+							"exporters":  []interface{}{"serializer"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "only gRPC, only Traces, memory_limiter defaults",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 1234, 0),
+				TracePort:          5003,
+				TracesEnabled:      true,
+				MemoryLimiter:      &MemoryLimiterConfig{},
+				Debug: map[string]interface{}{
+					"verbosity": "none",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"grpc": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"processors": map[string]interface{}{
+					"memory_limiter": map[string]interface{}{
+						"check_interval":  "1s",
+						"limit_mib":       512,
+						"spike_limit_mib": 128,
+					},
+				},
+				"exporters": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"tls": map[string]interface{}{
+							"insecure": true,
+						},
+						"compression": "none",
+						"endpoint":    "localhost:5003",
+						"sending_queue": map[string]interface{}{
+							"enabled": false,
+						},
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers":  []interface{}{"otlp"},
+							"processors": []interface{}{"memory_limiter"},
+							"exporters":  []interface{}{"otlp"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, testInstance := range tests {
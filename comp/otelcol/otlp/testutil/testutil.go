@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+// Package testutil holds helpers shared by the otlp package's tests.
+package testutil
+
+import "strconv"
+
+// OTLPConfigFromPorts builds the otlp receiver's "protocols" configuration
+// for bindHost, enabling gRPC and/or HTTP depending on which ports are
+// non-zero.
+func OTLPConfigFromPorts(bindHost string, grpcPort uint, httpPort uint) map[string]interface{} {
+	protocols := map[string]interface{}{}
+	if grpcPort > 0 {
+		protocols["grpc"] = map[string]interface{}{
+			"endpoint": bindHost + ":" + strconv.FormatUint(uint64(grpcPort), 10),
+		}
+	}
+	if httpPort > 0 {
+		protocols["http"] = map[string]interface{}{
+			"endpoint": bindHost + ":" + strconv.FormatUint(uint64(httpPort), 10),
+		}
+	}
+	return protocols
+}
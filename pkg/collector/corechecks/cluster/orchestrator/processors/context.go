@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package processors holds the shared context passed to the orchestrator
+// check's Kubernetes resource transformers.
+package processors
+
+// K8sProcessorContext carries the per-check configuration a k8s transformer
+// needs while extracting an API object into its process-agent model
+// equivalent: which labels/annotations to promote to tags, and which
+// optional enrichments are enabled.
+type K8sProcessorContext struct {
+	// LabelsAsTags maps a Kubernetes label key to the tag key it should be
+	// emitted as.
+	LabelsAsTags map[string]string
+	// AnnotationsAsTags maps a Kubernetes annotation key to the tag key it
+	// should be emitted as.
+	AnnotationsAsTags map[string]string
+	// LintJobs enables running the job lint rules over each extracted Job
+	// and surfacing their findings as "kube_job_lint:<ruleid>" tags.
+	LintJobs bool
+	// DisabledLintRuleIDs lists the LintRule.ID() values to skip when
+	// LintJobs is enabled, so operators can silence individual rules (e.g. a
+	// noisy resources-unbounded finding) without turning off linting entirely.
+	DisabledLintRuleIDs []string
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build orchestrator
+
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	model "github.com/DataDog/agent-payload/v5/process"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/cluster/orchestrator/processors"
+)
+
+// ExtractJob converts a Kubernetes Job into its process-agent model
+// equivalent, mapping the labels/annotations configured in pctx to tags and
+// condition tags ("kube_condition_<type>:<status>") to the job's status. If
+// pctx.LintJobs is enabled, the job lint rules are also run (skipping any rule
+// ID listed in pctx.DisabledLintRuleIDs) and any findings are surfaced as
+// "kube_job_lint:<ruleid>" tags.
+func ExtractJob(pctx *processors.K8sProcessorContext, j *batchv1.Job) *model.Job {
+	job := model.Job{
+		Metadata: extractJobMetadata(&j.ObjectMeta),
+		Spec: &model.JobSpec{
+			BackoffLimit:         jobInt32(j.Spec.BackoffLimit),
+			Completions:          jobInt32(j.Spec.Completions),
+			Parallelism:          jobInt32(j.Spec.Parallelism),
+			Selectors:            extractJobLabelSelector(j.Spec.Selector),
+			ResourceRequirements: extractJobResourceRequirements(j.Spec.Template.Spec),
+		},
+		Status: extractJobStatus(&j.Status),
+	}
+
+	for k, v := range j.ObjectMeta.Labels {
+		if tag, ok := pctx.LabelsAsTags[k]; ok {
+			job.Tags = append(job.Tags, fmt.Sprintf("%s:%s", tag, v))
+		}
+	}
+	for k, v := range j.ObjectMeta.Annotations {
+		if tag, ok := pctx.AnnotationsAsTags[k]; ok {
+			job.Tags = append(job.Tags, fmt.Sprintf("%s:%s", tag, v))
+		}
+	}
+
+	for _, cond := range j.Status.Conditions {
+		job.Conditions = append(job.Conditions, &model.JobCondition{
+			LastProbeTime:      cond.LastProbeTime.Unix(),
+			LastTransitionTime: cond.LastTransitionTime.Unix(),
+			Message:            cond.Message,
+			Reason:             cond.Reason,
+			Status:             string(cond.Status),
+			Type:               string(cond.Type),
+		})
+		if cond.Status == corev1.ConditionTrue {
+			job.Tags = append(job.Tags, fmt.Sprintf("kube_condition_%s:%s", strings.ToLower(string(cond.Type)), strings.ToLower(string(cond.Status))))
+		}
+	}
+
+	if pctx.LintJobs {
+		rules := FilterLintRules(DefaultLintRules(), pctx.DisabledLintRuleIDs)
+		if findings := LintJob(j, rules); len(findings) > 0 {
+			job.Tags = append(job.Tags, LintTags(findings)...)
+		}
+	}
+
+	return &job
+}
+
+// extractJobMetadata converts an ObjectMeta into its model.Metadata
+// equivalent, rendering labels/annotations as "key:value" pairs.
+func extractJobMetadata(meta *metav1.ObjectMeta) *model.Metadata {
+	m := &model.Metadata{
+		Name:            meta.Name,
+		Namespace:       meta.Namespace,
+		Uid:             string(meta.UID),
+		ResourceVersion: meta.ResourceVersion,
+	}
+	if !meta.CreationTimestamp.IsZero() {
+		m.CreationTimestamp = meta.CreationTimestamp.Unix()
+	}
+	for k, v := range meta.Labels {
+		m.Labels = append(m.Labels, fmt.Sprintf("%s:%s", k, v))
+	}
+	for k, v := range meta.Annotations {
+		m.Annotations = append(m.Annotations, fmt.Sprintf("%s:%s", k, v))
+	}
+	for _, ref := range meta.OwnerReferences {
+		m.OwnerReferences = append(m.OwnerReferences, &model.OwnerReference{
+			Kind: ref.Kind,
+			Name: ref.Name,
+			Uid:  string(ref.UID),
+		})
+	}
+	return m
+}
+
+// extractJobStatus converts a JobStatus into its model.JobStatus
+// equivalent. ConditionMessage is taken from the last condition reporting a
+// true status with a non-empty message, e.g. a BackoffLimitExceeded failure.
+func extractJobStatus(status *batchv1.JobStatus) *model.JobStatus {
+	s := &model.JobStatus{
+		Active:    status.Active,
+		Succeeded: status.Succeeded,
+		Failed:    status.Failed,
+	}
+	if status.StartTime != nil {
+		s.StartTime = status.StartTime.Unix()
+	}
+	if status.CompletionTime != nil {
+		s.CompletionTime = status.CompletionTime.Unix()
+	}
+	for _, cond := range status.Conditions {
+		if cond.Status == corev1.ConditionTrue && cond.Message != "" {
+			s.ConditionMessage = cond.Message
+		}
+	}
+	return s
+}
+
+// extractJobLabelSelector converts a LabelSelector into the
+// model.LabelSelectorRequirement list used by the orchestrator model,
+// turning each matchLabels entry into an "In" requirement and copying
+// matchExpressions as-is. matchLabels keys are sorted for deterministic
+// output.
+func extractJobLabelSelector(sel *metav1.LabelSelector) []*model.LabelSelectorRequirement {
+	if sel == nil {
+		return nil
+	}
+
+	var reqs []*model.LabelSelectorRequirement
+
+	keys := make([]string, 0, len(sel.MatchLabels))
+	for k := range sel.MatchLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		reqs = append(reqs, &model.LabelSelectorRequirement{
+			Key:      k,
+			Operator: "In",
+			Values:   []string{sel.MatchLabels[k]},
+		})
+	}
+
+	for _, expr := range sel.MatchExpressions {
+		reqs = append(reqs, &model.LabelSelectorRequirement{
+			Key:      expr.Key,
+			Operator: string(expr.Operator),
+			Values:   expr.Values,
+		})
+	}
+
+	return reqs
+}
+
+// extractJobResourceRequirements converts each container's resource
+// requests/limits into the orchestrator model's ResourceRequirements,
+// skipping containers with neither set.
+func extractJobResourceRequirements(spec corev1.PodSpec) []*model.ResourceRequirements {
+	var out []*model.ResourceRequirements
+	for _, c := range spec.Containers {
+		if len(c.Resources.Requests) == 0 && len(c.Resources.Limits) == 0 {
+			continue
+		}
+		out = append(out, &model.ResourceRequirements{
+			Name:     c.Name,
+			Requests: resourceListToMap(c.Resources.Requests),
+			Limits:   resourceListToMap(c.Resources.Limits),
+		})
+	}
+	return out
+}
+
+// resourceListToMap converts a corev1.ResourceList into a map of resource
+// name to milli-value, the unit the orchestrator model uses so fractional
+// CPU requests survive the conversion.
+func resourceListToMap(list corev1.ResourceList) map[string]int64 {
+	if len(list) == 0 {
+		return nil
+	}
+	m := make(map[string]int64, len(list))
+	for name, qty := range list {
+		m[string(name)] = qty.MilliValue()
+	}
+	return m
+}
+
+// jobInt32 dereferences an optional int32 field, defaulting to 0 when unset.
+func jobInt32(v *int32) int32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build orchestrator
+
+package k8s
+
+import (
+	"testing"
+
+	model "github.com/DataDog/agent-payload/v5/process"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/cluster/orchestrator/processors"
+)
+
+func getTemplateWithResourceRequirements() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "worker",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("500m"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("1"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getExpectedModelResourceRequirements() []*model.ResourceRequirements {
+	return []*model.ResourceRequirements{
+		{
+			Name:     "worker",
+			Requests: map[string]int64{"cpu": 500},
+			Limits:   map[string]int64{"cpu": 1000},
+		},
+	}
+}
+
+func TestExtractJobRunsLintRulesWhenEnabled(t *testing.T) {
+	pctx := &processors.K8sProcessorContext{LintJobs: true}
+	job := batchv1.Job{}
+
+	actual := ExtractJob(pctx, &job)
+
+	assert.Contains(t, actual.Tags, "kube_job_lint:backoff-limit-unbounded")
+	assert.Contains(t, actual.Tags, "kube_job_lint:active-deadline-missing")
+	assert.Contains(t, actual.Tags, "kube_job_lint:resources-unbounded")
+}
+
+func TestExtractJobSkipsLintRulesByDefault(t *testing.T) {
+	pctx := &processors.K8sProcessorContext{}
+	job := batchv1.Job{}
+
+	actual := ExtractJob(pctx, &job)
+
+	assert.Empty(t, actual.Tags)
+}
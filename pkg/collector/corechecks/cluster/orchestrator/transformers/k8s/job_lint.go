@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build orchestrator
+
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// LintSeverity classifies how concerning a LintFinding is.
+type LintSeverity string
+
+const (
+	// LintSeverityWarning flags a risky but non-fatal configuration.
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityCritical flags a job that is failing or has already failed
+	// because of the condition the rule checks for.
+	LintSeverityCritical LintSeverity = "critical"
+)
+
+// LintFinding is a single issue raised by a LintRule against a Job.
+type LintFinding struct {
+	RuleID   string
+	Severity LintSeverity
+	Message  string
+}
+
+// Tag renders the finding as the "kube_job_lint:<ruleid>" tag used to filter
+// jobs by lint result.
+func (f LintFinding) Tag() string {
+	return fmt.Sprintf("kube_job_lint:%s", f.RuleID)
+}
+
+// LintRule inspects a Job and optionally reports a LintFinding. Operators can
+// disable individual rules via config by omitting their ID from the rule set
+// passed to LintJob.
+type LintRule interface {
+	// ID is the stable identifier surfaced in LintFinding.RuleID and tags.
+	ID() string
+	// Check returns a finding and true if job violates the rule.
+	Check(job *batchv1.Job) (LintFinding, bool)
+}
+
+// DefaultLintRules returns the built-in lint rules, in the order their
+// findings should be reported.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		backoffLimitRule{},
+		activeDeadlineSecondsRule{},
+		resourceRequirementsRule{},
+		backoffLimitExceededRule{},
+		activeDeadlineExceededRule{},
+	}
+}
+
+// FilterLintRules returns the rules in rules whose ID() is not in disabledIDs,
+// preserving order. It's used to apply K8sProcessorContext.DisabledLintRuleIDs
+// on top of DefaultLintRules so operators can disable individual rules via
+// config instead of all-or-nothing.
+func FilterLintRules(rules []LintRule, disabledIDs []string) []LintRule {
+	if len(disabledIDs) == 0 {
+		return rules
+	}
+	disabled := make(map[string]struct{}, len(disabledIDs))
+	for _, id := range disabledIDs {
+		disabled[id] = struct{}{}
+	}
+
+	enabled := make([]LintRule, 0, len(rules))
+	for _, rule := range rules {
+		if _, ok := disabled[rule.ID()]; ok {
+			continue
+		}
+		enabled = append(enabled, rule)
+	}
+	return enabled
+}
+
+// LintJob runs rules against job and returns every finding raised, in rule order.
+func LintJob(job *batchv1.Job, rules []LintRule) []LintFinding {
+	var findings []LintFinding
+	for _, rule := range rules {
+		if finding, ok := rule.Check(job); ok {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// LintTags renders findings as "kube_job_lint:<ruleid>" tags.
+func LintTags(findings []LintFinding) []string {
+	if len(findings) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(findings))
+	for _, f := range findings {
+		tags = append(tags, f.Tag())
+	}
+	return tags
+}
+
+// maxSaneBackoffLimit is the threshold past which a job's backoffLimit is
+// considered risky rather than merely unusual.
+const maxSaneBackoffLimit = 10
+
+type backoffLimitRule struct{}
+
+func (backoffLimitRule) ID() string { return "backoff-limit-unbounded" }
+
+func (r backoffLimitRule) Check(job *batchv1.Job) (LintFinding, bool) {
+	limit := job.Spec.BackoffLimit
+	if limit == nil {
+		return LintFinding{RuleID: r.ID(), Severity: LintSeverityWarning, Message: "backoffLimit is unset, job will retry up to the default of 6 times"}, true
+	}
+	if *limit > maxSaneBackoffLimit {
+		return LintFinding{RuleID: r.ID(), Severity: LintSeverityWarning, Message: fmt.Sprintf("backoffLimit is %d, higher than the recommended maximum of %d", *limit, maxSaneBackoffLimit)}, true
+	}
+	return LintFinding{}, false
+}
+
+type activeDeadlineSecondsRule struct{}
+
+func (activeDeadlineSecondsRule) ID() string { return "active-deadline-missing" }
+
+func (r activeDeadlineSecondsRule) Check(job *batchv1.Job) (LintFinding, bool) {
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		return LintFinding{RuleID: r.ID(), Severity: LintSeverityWarning, Message: "activeDeadlineSeconds is unset, a stuck job can run indefinitely"}, true
+	}
+	return LintFinding{}, false
+}
+
+type resourceRequirementsRule struct{}
+
+func (resourceRequirementsRule) ID() string { return "resources-unbounded" }
+
+func (r resourceRequirementsRule) Check(job *batchv1.Job) (LintFinding, bool) {
+	if len(job.Spec.Template.Spec.Containers) == 0 {
+		return LintFinding{RuleID: r.ID(), Severity: LintSeverityWarning, Message: "pod template has no containers with resource requests or limits"}, true
+	}
+	for _, container := range job.Spec.Template.Spec.Containers {
+		if len(container.Resources.Requests) == 0 && len(container.Resources.Limits) == 0 {
+			return LintFinding{RuleID: r.ID(), Severity: LintSeverityWarning, Message: fmt.Sprintf("container %q has no resource requests or limits", container.Name)}, true
+		}
+	}
+	return LintFinding{}, false
+}
+
+type backoffLimitExceededRule struct{}
+
+func (backoffLimitExceededRule) ID() string { return "backoff-limit-exceeded" }
+
+func (r backoffLimitExceededRule) Check(job *batchv1.Job) (LintFinding, bool) {
+	if job.Status.Failed == 0 {
+		return LintFinding{}, false
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Reason == "BackoffLimitExceeded" {
+			return LintFinding{RuleID: r.ID(), Severity: LintSeverityCritical, Message: "job has failed after exceeding backoffLimit"}, true
+		}
+	}
+	return LintFinding{}, false
+}
+
+type activeDeadlineExceededRule struct{}
+
+func (activeDeadlineExceededRule) ID() string { return "active-deadline-exceeded" }
+
+func (r activeDeadlineExceededRule) Check(job *batchv1.Job) (LintFinding, bool) {
+	if job.Status.Active == 0 || job.Spec.ActiveDeadlineSeconds == nil || job.Status.StartTime == nil {
+		return LintFinding{}, false
+	}
+	deadline := job.Status.StartTime.Add(time.Duration(*job.Spec.ActiveDeadlineSeconds) * time.Second)
+	if time.Now().After(deadline) {
+		return LintFinding{RuleID: r.ID(), Severity: LintSeverityCritical, Message: "job is still active past its activeDeadlineSeconds"}, true
+	}
+	return LintFinding{}, false
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build orchestrator
+
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+)
+
+func resourceQuantity(s string) resource.Quantity {
+	return resource.MustParse(s)
+}
+
+func TestLintJob(t *testing.T) {
+	startTime := metav1.NewTime(time.Now().Add(-1 * time.Hour))
+
+	tests := map[string]struct {
+		input         batchv1.Job
+		expectedRules []string
+	}{
+		"clean job": {
+			input: batchv1.Job{
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          pointer.Ptr(int32(3)),
+					ActiveDeadlineSeconds: pointer.Ptr(int64(600)),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name: "worker",
+									Resources: corev1.ResourceRequirements{
+										Requests: corev1.ResourceList{
+											corev1.ResourceCPU: resourceQuantity("100m"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedRules: nil,
+		},
+		"missing backoffLimit and activeDeadlineSeconds": {
+			input:         batchv1.Job{},
+			expectedRules: []string{"backoff-limit-unbounded", "active-deadline-missing", "resources-unbounded"},
+		},
+		"backoffLimit too high": {
+			input: batchv1.Job{
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          pointer.Ptr(int32(20)),
+					ActiveDeadlineSeconds: pointer.Ptr(int64(600)),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name: "worker",
+									Resources: corev1.ResourceRequirements{
+										Limits: corev1.ResourceList{
+											corev1.ResourceCPU: resourceQuantity("1"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedRules: []string{"backoff-limit-unbounded"},
+		},
+		"failed with backoff limit exceeded": {
+			input: batchv1.Job{
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          pointer.Ptr(int32(3)),
+					ActiveDeadlineSeconds: pointer.Ptr(int64(600)),
+				},
+				Status: batchv1.JobStatus{
+					Failed: 1,
+					Conditions: []batchv1.JobCondition{
+						{Reason: "BackoffLimitExceeded"},
+					},
+				},
+			},
+			expectedRules: []string{"resources-unbounded", "backoff-limit-exceeded"},
+		},
+		"active past deadline": {
+			input: batchv1.Job{
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          pointer.Ptr(int32(3)),
+					ActiveDeadlineSeconds: pointer.Ptr(int64(60)),
+				},
+				Status: batchv1.JobStatus{
+					Active:    1,
+					StartTime: &startTime,
+				},
+			},
+			expectedRules: []string{"resources-unbounded", "active-deadline-exceeded"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			findings := LintJob(&tc.input, DefaultLintRules())
+			var ruleIDs []string
+			for _, f := range findings {
+				ruleIDs = append(ruleIDs, f.RuleID)
+			}
+			assert.Equal(t, tc.expectedRules, ruleIDs)
+		})
+	}
+}
+
+func TestFilterLintRules(t *testing.T) {
+	rules := DefaultLintRules()
+
+	filtered := FilterLintRules(rules, []string{"resources-unbounded", "active-deadline-missing"})
+	var ids []string
+	for _, rule := range filtered {
+		ids = append(ids, rule.ID())
+	}
+	assert.Equal(t, []string{"backoff-limit-unbounded", "backoff-limit-exceeded", "active-deadline-exceeded"}, ids)
+
+	assert.Equal(t, rules, FilterLintRules(rules, nil))
+}
+
+func TestLintTags(t *testing.T) {
+	findings := []LintFinding{
+		{RuleID: "backoff-limit-unbounded"},
+		{RuleID: "active-deadline-missing"},
+	}
+	assert.Equal(t, []string{"kube_job_lint:backoff-limit-unbounded", "kube_job_lint:active-deadline-missing"}, LintTags(findings))
+	assert.Nil(t, LintTags(nil))
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024-present Datadog, Inc.
+
+package client
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+const telemetrySubsystem = "cisco_sdwan"
+
+// TelemetryStore holds the internal telemetry instruments for the Cisco
+// SD-WAN check. It is created once per check instance and shared between the
+// vManage client and the collectors so that operators can diagnose which
+// endpoint is slow or failing without turning on debug logging.
+type TelemetryStore struct {
+	requests         *telemetry.Counter
+	requestDuration  *telemetry.Histogram
+	requestsByStatus *telemetry.Counter
+	retries          *telemetry.Counter
+	collectorResult  *telemetry.Counter
+}
+
+// NewTelemetryStore creates a new TelemetryStore.
+func NewTelemetryStore() *TelemetryStore {
+	return &TelemetryStore{
+		requests: telemetry.NewCounter(
+			telemetrySubsystem,
+			"requests_total",
+			[]string{"endpoint"},
+			"Number of requests sent to the vManage API, by endpoint",
+		),
+		requestDuration: telemetry.NewHistogram(
+			telemetrySubsystem,
+			"request_duration_seconds",
+			[]string{"endpoint"},
+			"Duration of vManage API requests, by endpoint",
+			[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+		),
+		requestsByStatus: telemetry.NewCounter(
+			telemetrySubsystem,
+			"requests_by_status_total",
+			[]string{"endpoint", "status"},
+			"Number of vManage API requests, by endpoint and HTTP status",
+		),
+		retries: telemetry.NewCounter(
+			telemetrySubsystem,
+			"retries_total",
+			[]string{"endpoint"},
+			"Number of retries performed against the vManage API, by endpoint",
+		),
+		collectorResult: telemetry.NewCounter(
+			telemetrySubsystem,
+			"collector_runs_total",
+			[]string{"collector", "result"},
+			"Number of collector runs, by collector name and result (success/error)",
+		),
+	}
+}
+
+// StartRequest marks the start of a vManage API call against endpoint, and
+// returns a function to be deferred that records its duration and outcome.
+func (t *TelemetryStore) StartRequest(endpoint string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		t.requests.Inc(endpoint)
+		t.requestDuration.Observe(time.Since(start).Seconds(), endpoint)
+		t.requestsByStatus.Inc(endpoint, status)
+	}
+}
+
+// AddRetry records a retry attempt against the given endpoint.
+func (t *TelemetryStore) AddRetry(endpoint string) {
+	t.retries.Inc(endpoint)
+}
+
+// AddCollectorResult records the success or failure of a single collector run
+// (e.g. CollectHardwareMetrics, CollectInterfaceMetrics).
+func (t *TelemetryStore) AddCollectorResult(collector string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	t.collectorResult.Inc(collector, result)
+}
+
+// WithTelemetryStore attaches a TelemetryStore to the client so that every
+// request, retry and page fetch is recorded against it.
+func WithTelemetryStore(store *TelemetryStore) ClientOptions {
+	return func(c *Client) {
+		c.telemetryStore = store
+	}
+}
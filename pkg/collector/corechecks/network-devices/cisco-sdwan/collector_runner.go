@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024-present Datadog, Inc.
+
+package ciscosdwan
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxConcurrentCollectors bounds how many vManage endpoints are polled at
+// once, so that one slow endpoint can't serialize the whole check interval
+// behind the others.
+const maxConcurrentCollectors = 4
+
+const defaultCircuitBreakerCooldownIntervals = 3
+const circuitBreakerFailureThreshold = 3
+const defaultCollectorTimeout = 30 * time.Second
+
+// collectorTimeout returns the configured per-collector timeout, falling
+// back to defaultCollectorTimeout when unset.
+func (c *CiscoSdwanCheck) collectorTimeout() time.Duration {
+	if c.config.CollectorTimeoutSeconds <= 0 {
+		return defaultCollectorTimeout
+	}
+	return time.Duration(c.config.CollectorTimeoutSeconds) * time.Second
+}
+
+// collectorJob is one optional collector dispatched by runCollectors: a named
+// vManage call retried with exponential backoff until its per-job timeout
+// elapses, and tracked by the circuit breaker. The timeout ends the retries;
+// it does not interrupt a call that ignores ctx.
+type collectorJob struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// retryPolicy implements a simple exponential-backoff retry, configurable via
+// the check's retry_initial_interval/retry_max_interval/retry_max_elapsed
+// config keys. A zero-value retryPolicy runs fn exactly once.
+type retryPolicy struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	maxElapsed      time.Duration
+}
+
+// run calls fn, retrying with exponential backoff on error. onRetry, if set,
+// is called once before each retry attempt (not before the first call), so
+// callers can track how many retries a given operation needed.
+func (p retryPolicy) run(ctx context.Context, onRetry func(), fn func(context.Context) error) error {
+	if p.initialInterval <= 0 {
+		return fn(ctx)
+	}
+
+	deadline := time.Now().Add(p.maxElapsed)
+	backoff := p.initialInterval
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if p.maxElapsed > 0 && time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		if onRetry != nil {
+			onRetry()
+		}
+		if backoff *= 2; backoff > p.maxInterval && p.maxInterval > 0 {
+			backoff = p.maxInterval
+		}
+	}
+}
+
+// circuitBreaker trips a collector for cooldownIntervals check runs after
+// circuitBreakerFailureThreshold consecutive failures, so a wedged endpoint
+// stops being retried into every interval once it's clearly down.
+type circuitBreaker struct {
+	mu                sync.Mutex
+	interval          int
+	cooldownIntervals int
+	consecutiveFails  map[string]int
+	tripUntil         map[string]int
+}
+
+func newCircuitBreaker(cooldownIntervals int) *circuitBreaker {
+	if cooldownIntervals <= 0 {
+		cooldownIntervals = defaultCircuitBreakerCooldownIntervals
+	}
+	return &circuitBreaker{
+		cooldownIntervals: cooldownIntervals,
+		consecutiveFails:  map[string]int{},
+		tripUntil:         map[string]int{},
+	}
+}
+
+func (b *circuitBreaker) tripped(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.interval < b.tripUntil[name]
+}
+
+func (b *circuitBreaker) recordResult(name string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.consecutiveFails[name] = 0
+		return
+	}
+	b.consecutiveFails[name]++
+	if b.consecutiveFails[name] >= circuitBreakerFailureThreshold {
+		b.tripUntil[name] = b.interval + b.cooldownIntervals
+		b.consecutiveFails[name] = 0
+	}
+}
+
+func (b *circuitBreaker) tick() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.interval++
+}
+
+// runCollectors dispatches jobs across a bounded worker pool. Each job is
+// retried per c.retryPolicy, its outcome is recorded in the telemetry store
+// and the circuit breaker, and a service check is emitted so operators can
+// see which subsystem is degraded rather than guessing from log noise.
+func (c *CiscoSdwanCheck) runCollectors(jobs []collectorJob) {
+	sem := make(chan struct{}, maxConcurrentCollectors)
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		if c.circuitBreaker.tripped(job.name) {
+			log.Debugf("Cisco SD-WAN collector %s is circuit-broken, skipping this interval", job.name)
+			c.sendCollectorServiceCheck(job.name, servicecheck.ServiceCheckCritical, "circuit breaker open after repeated failures")
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job collectorJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), job.timeout)
+			defer cancel()
+
+			err := c.retryPolicy.run(ctx, func() { c.telemetry.AddRetry(job.name) }, job.run)
+			c.circuitBreaker.recordResult(job.name, err)
+			c.telemetry.AddCollectorResult(job.name, err)
+			if err != nil {
+				log.Warnf("Cisco SD-WAN collector %s failed: %s", job.name, err)
+				c.sendCollectorServiceCheck(job.name, servicecheck.ServiceCheckCritical, err.Error())
+				return
+			}
+			c.sendCollectorServiceCheck(job.name, servicecheck.ServiceCheckOK, "")
+		}(job)
+	}
+
+	wg.Wait()
+	c.circuitBreaker.tick()
+}
+
+func (c *CiscoSdwanCheck) sendCollectorServiceCheck(name string, status servicecheck.ServiceCheckStatus, message string) {
+	sender, err := c.GetSender()
+	if err != nil {
+		return
+	}
+	sender.ServiceCheck("cisco_sdwan.collector."+name, status, "", nil, message)
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024-present Datadog, Inc.
+
+package ciscosdwan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRunNoRetryWhenIntervalUnset(t *testing.T) {
+	p := retryPolicy{}
+	attempts := 0
+	err := p.run(context.Background(), nil, func(context.Context) error {
+		attempts++
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected the error from fn to be returned")
+	}
+	if attempts != 1 {
+		t.Errorf("expected exactly 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyRunRetriesUntilSuccess(t *testing.T) {
+	p := retryPolicy{initialInterval: time.Millisecond, maxInterval: 5 * time.Millisecond, maxElapsed: time.Second}
+	attempts := 0
+	var retries int
+	err := p.run(context.Background(), func() { retries++ }, func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if retries != 2 {
+		t.Errorf("expected 2 onRetry calls, got %d", retries)
+	}
+}
+
+func TestRetryPolicyRunStopsAtMaxElapsed(t *testing.T) {
+	p := retryPolicy{initialInterval: 5 * time.Millisecond, maxInterval: 5 * time.Millisecond, maxElapsed: 10 * time.Millisecond}
+	err := p.run(context.Background(), nil, func(context.Context) error {
+		return errors.New("always fails")
+	})
+	if err == nil {
+		t.Fatal("expected the last error to be returned once maxElapsed is exceeded")
+	}
+}
+
+func TestRetryPolicyRunStopsOnContextDone(t *testing.T) {
+	p := retryPolicy{initialInterval: time.Second, maxInterval: time.Second, maxElapsed: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	err := p.run(ctx, nil, func(context.Context) error {
+		attempts++
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected an error once the context is done")
+	}
+	if attempts != 1 {
+		t.Errorf("expected exactly 1 attempt before the context cancellation is observed, got %d", attempts)
+	}
+}
+
+func TestCircuitBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	b := newCircuitBreaker(2)
+
+	for i := 0; i < circuitBreakerFailureThreshold-1; i++ {
+		b.recordResult("job", errors.New("fail"))
+		if b.tripped("job") {
+			t.Fatalf("job should not be tripped after %d failures", i+1)
+		}
+	}
+
+	b.recordResult("job", errors.New("fail"))
+	if !b.tripped("job") {
+		t.Fatal("expected job to be tripped after reaching the failure threshold")
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	b := newCircuitBreaker(2)
+
+	b.recordResult("job", errors.New("fail"))
+	b.recordResult("job", nil)
+	b.recordResult("job", errors.New("fail"))
+	if b.tripped("job") {
+		t.Fatal("a success should reset the consecutive failure count")
+	}
+}
+
+func TestCircuitBreakerCooldownExpiresAfterIntervals(t *testing.T) {
+	b := newCircuitBreaker(2)
+
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		b.recordResult("job", errors.New("fail"))
+	}
+	if !b.tripped("job") {
+		t.Fatal("expected job to be tripped")
+	}
+
+	b.tick()
+	if !b.tripped("job") {
+		t.Fatal("expected job to still be tripped during the cooldown")
+	}
+
+	b.tick()
+	b.tick()
+	if b.tripped("job") {
+		t.Fatal("expected job to no longer be tripped once the cooldown has elapsed")
+	}
+}
+
+func TestDefaultCircuitBreakerCooldown(t *testing.T) {
+	b := newCircuitBreaker(0)
+	if b.cooldownIntervals != defaultCircuitBreakerCooldownIntervals {
+		t.Errorf("expected a cooldownIntervals <= 0 to fall back to the default, got %d", b.cooldownIntervals)
+	}
+}
+
+func TestCollectorTimeoutDefault(t *testing.T) {
+	c := &CiscoSdwanCheck{}
+	if got := c.collectorTimeout(); got != defaultCollectorTimeout {
+		t.Errorf("expected the default collector timeout, got %v", got)
+	}
+}
+
+func TestCollectorTimeoutConfigured(t *testing.T) {
+	c := &CiscoSdwanCheck{config: checkCfg{CollectorTimeoutSeconds: 5}}
+	if got := c.collectorTimeout(); got != 5*time.Second {
+		t.Errorf("expected a 5s collector timeout, got %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024-present Datadog, Inc.
+
+package ciscosdwan
+
+import "path/filepath"
+
+// egressFilter applies the user-configured egress_rules allow/deny lists to
+// sites, VPNs and hostnames. It is built once from the check configuration
+// and reused across every Run(), so that large deployments with thousands of
+// edges can scope a check instance to a subset of the fabric.
+type egressFilter struct {
+	cfg egressRulesConfig
+}
+
+func newEgressFilter(cfg egressRulesConfig) *egressFilter {
+	return &egressFilter{cfg: cfg}
+}
+
+// AllowSite reports whether siteID passes the configured sites/sites_exclude lists.
+func (f *egressFilter) AllowSite(siteID int) bool {
+	return allowInt(siteID, f.cfg.Sites, f.cfg.SitesExclude)
+}
+
+// AllowVPN reports whether vpnID passes the configured vpns/vpns_exclude lists.
+func (f *egressFilter) AllowVPN(vpnID int) bool {
+	return allowInt(vpnID, f.cfg.VPNs, f.cfg.VPNsExclude)
+}
+
+// AllowHost reports whether hostname passes the configured hosts/hosts_exclude
+// lists. Entries may use glob patterns (e.g. "*.corp.example").
+func (f *egressFilter) AllowHost(hostname string) bool {
+	return allowGlob(hostname, f.cfg.Hosts, f.cfg.HostsExclude)
+}
+
+func allowInt(v int, allow, deny []int) bool {
+	for _, d := range deny {
+		if d == v {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, a := range allow {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+func allowGlob(v string, allow, deny []string) bool {
+	for _, d := range deny {
+		if matchGlob(d, v) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, a := range allow {
+		if matchGlob(a, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, v string) bool {
+	matched, err := filepath.Match(pattern, v)
+	if err != nil {
+		return pattern == v
+	}
+	return matched
+}
+
+// filterableDevice is implemented by the vManage device/interface types that
+// carry a hostname and site ID, enough identity to be scoped by egress_rules'
+// sites/hosts lists. Types that don't implement it pass that part of the
+// filter unchanged.
+type filterableDevice interface {
+	GetHostname() string
+	GetSiteID() int
+}
+
+// vpnScopedDevice is implemented by the vManage tunnel/BGP-peer types that
+// carry a VPN ID, letting them be scoped by egress_rules' vpns/vpns_exclude
+// lists. Types that don't implement it pass that part of the filter
+// unchanged.
+type vpnScopedDevice interface {
+	GetVPNID() int
+}
+
+// allows reports whether item passes every egress rule it carries enough
+// identity to be checked against: hostname/site via filterableDevice and/or
+// VPN via vpnScopedDevice. An item implementing neither interface passes
+// unfiltered.
+func (f *egressFilter) allows(item any) bool {
+	if fd, ok := item.(filterableDevice); ok && !(f.AllowHost(fd.GetHostname()) && f.AllowSite(fd.GetSiteID())) {
+		return false
+	}
+	if vs, ok := item.(vpnScopedDevice); ok && !f.AllowVPN(vs.GetVPNID()) {
+		return false
+	}
+	return true
+}
+
+// filterDevices drops entries that don't satisfy the configured egress
+// rules. It is generic so that the same filtering logic can be reused across
+// every collector's result type (devices, interfaces, tunnels, BGP peers, ...).
+func filterDevices[T any](f *egressFilter, items []T) []T {
+	if f == nil {
+		return items
+	}
+
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if f.allows(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
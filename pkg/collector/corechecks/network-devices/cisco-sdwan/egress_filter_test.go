@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2024-present Datadog, Inc.
+
+package ciscosdwan
+
+import "testing"
+
+type fakeDevice struct {
+	hostname string
+	siteID   int
+}
+
+func (d fakeDevice) GetHostname() string { return d.hostname }
+func (d fakeDevice) GetSiteID() int      { return d.siteID }
+
+type fakeTunnel struct {
+	vpnID int
+}
+
+func (t fakeTunnel) GetVPNID() int { return t.vpnID }
+
+func TestEgressFilter(t *testing.T) {
+	devices := []fakeDevice{
+		{hostname: "edge-1.corp.example", siteID: 100},
+		{hostname: "edge-2.corp.example", siteID: 200},
+		{hostname: "edge-3.other.example", siteID: 300},
+	}
+
+	t.Run("no rules allows everything", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{})
+		result := filterDevices(f, devices)
+		if len(result) != len(devices) {
+			t.Errorf("expected all %d devices, got %d", len(devices), len(result))
+		}
+	})
+
+	t.Run("sites allow-list", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{Sites: []int{100}})
+		result := filterDevices(f, devices)
+		if len(result) != 1 || result[0].siteID != 100 {
+			t.Errorf("expected only site 100, got %+v", result)
+		}
+	})
+
+	t.Run("sites_exclude", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{SitesExclude: []int{300}})
+		result := filterDevices(f, devices)
+		if len(result) != 2 {
+			t.Errorf("expected 2 devices, got %d", len(result))
+		}
+	})
+
+	t.Run("hosts glob allow-list", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{Hosts: []string{"*.corp.example"}})
+		result := filterDevices(f, devices)
+		if len(result) != 2 {
+			t.Errorf("expected 2 devices matching *.corp.example, got %d", len(result))
+		}
+	})
+
+	t.Run("nil filter is a no-op", func(t *testing.T) {
+		result := filterDevices[fakeDevice](nil, devices)
+		if len(result) != len(devices) {
+			t.Errorf("expected all devices with a nil filter, got %d", len(result))
+		}
+	})
+}
+
+func TestEgressFilterVPN(t *testing.T) {
+	tunnels := []fakeTunnel{
+		{vpnID: 0},
+		{vpnID: 1},
+		{vpnID: 512},
+	}
+
+	t.Run("no rules allows everything", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{})
+		result := filterDevices(f, tunnels)
+		if len(result) != len(tunnels) {
+			t.Errorf("expected all %d tunnels, got %d", len(tunnels), len(result))
+		}
+	})
+
+	t.Run("vpns allow-list", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{VPNs: []int{1}})
+		result := filterDevices(f, tunnels)
+		if len(result) != 1 || result[0].vpnID != 1 {
+			t.Errorf("expected only VPN 1, got %+v", result)
+		}
+	})
+
+	t.Run("vpns_exclude", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{VPNsExclude: []int{512}})
+		result := filterDevices(f, tunnels)
+		if len(result) != 2 {
+			t.Errorf("expected 2 tunnels, got %d", len(result))
+		}
+	})
+
+	t.Run("types without hostname/site are unaffected by sites/hosts rules", func(t *testing.T) {
+		f := newEgressFilter(egressRulesConfig{Sites: []int{100}, Hosts: []string{"*.corp.example"}})
+		result := filterDevices(f, tunnels)
+		if len(result) != len(tunnels) {
+			t.Errorf("expected sites/hosts rules to be a no-op for VPN-only types, got %d", len(result))
+		}
+	})
+}
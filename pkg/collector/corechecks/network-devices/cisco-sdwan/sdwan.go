@@ -7,6 +7,7 @@
 package ciscosdwan
 
 import (
+	"context"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -31,37 +32,69 @@ const (
 
 // Configuration for the Cisco SD-WAN check
 type checkCfg struct {
-	VManageEndpoint                 string `yaml:"vmanage_endpoint"`
-	Username                        string `yaml:"username"`
-	Password                        string `yaml:"password"`
-	Namespace                       string `yaml:"namespace"`
-	MaxAttempts                     int    `yaml:"max_attempts"`
-	MaxPages                        int    `yaml:"max_pages"`
-	MaxCount                        int    `yaml:"max_count"`
-	LookbackTimeWindowMinutes       int    `yaml:"lookback_time_window_minutes"`
-	UseHTTP                         bool   `yaml:"use_http"`
-	Insecure                        bool   `yaml:"insecure"`
-	CAFile                          string `yaml:"ca_file"`
-	SendNDMMetadata                 *bool  `yaml:"send_ndm_metadata"`
-	MinCollectionInterval           int    `yaml:"min_collection_interval"`
-	CollectHardwareMetrics          *bool  `yaml:"collect_hardware_metrics"`
-	CollectInterfaceMetrics         *bool  `yaml:"collect_interface_metrics"`
-	CollectTunnelMetrics            *bool  `yaml:"collect_tunnel_metrics"`
-	CollectControlConnectionMetrics *bool  `yaml:"collect_control_connection_metrics"`
-	CollectOMPPeerMetrics           *bool  `yaml:"collect_omp_peer_metrics"`
-	CollectDeviceCountersMetrics    *bool  `yaml:"collect_device_counters_metrics"`
-	CollectBFDSessionStatus         *bool  `yaml:"collect_bfd_session_status"`
-	CollectHardwareStatus           *bool  `yaml:"collect_hardware_status"`
-	CollectCloudApplicationsMetrics *bool  `yaml:"collect_cloud_applications_metrics"`
-	CollectBGPNeighborStates        *bool  `yaml:"collect_bgp_neighbor_states"`
+	VManageEndpoint                 string            `yaml:"vmanage_endpoint"`
+	Username                        string            `yaml:"username"`
+	Password                        string            `yaml:"password"`
+	Namespace                       string            `yaml:"namespace"`
+	MaxAttempts                     int               `yaml:"max_attempts"`
+	MaxPages                        int               `yaml:"max_pages"`
+	MaxCount                        int               `yaml:"max_count"`
+	LookbackTimeWindowMinutes       int               `yaml:"lookback_time_window_minutes"`
+	UseHTTP                         bool              `yaml:"use_http"`
+	Insecure                        bool              `yaml:"insecure"`
+	CAFile                          string            `yaml:"ca_file"`
+	SendNDMMetadata                 *bool             `yaml:"send_ndm_metadata"`
+	MinCollectionInterval           int               `yaml:"min_collection_interval"`
+	CollectHardwareMetrics          *bool             `yaml:"collect_hardware_metrics"`
+	CollectInterfaceMetrics         *bool             `yaml:"collect_interface_metrics"`
+	CollectTunnelMetrics            *bool             `yaml:"collect_tunnel_metrics"`
+	CollectControlConnectionMetrics *bool             `yaml:"collect_control_connection_metrics"`
+	CollectOMPPeerMetrics           *bool             `yaml:"collect_omp_peer_metrics"`
+	CollectDeviceCountersMetrics    *bool             `yaml:"collect_device_counters_metrics"`
+	CollectBFDSessionStatus         *bool             `yaml:"collect_bfd_session_status"`
+	CollectHardwareStatus           *bool             `yaml:"collect_hardware_status"`
+	CollectCloudApplicationsMetrics *bool             `yaml:"collect_cloud_applications_metrics"`
+	CollectBGPNeighborStates        *bool             `yaml:"collect_bgp_neighbor_states"`
+	EgressRules                     egressRulesConfig `yaml:"egress_rules"`
+
+	// RetryInitialIntervalSeconds is the first backoff delay applied after a
+	// collector fails. A value of 0 disables retries entirely.
+	RetryInitialIntervalSeconds int `yaml:"retry_initial_interval"`
+	// RetryMaxIntervalSeconds caps the exponential backoff delay between retries.
+	RetryMaxIntervalSeconds int `yaml:"retry_max_interval"`
+	// RetryMaxElapsedSeconds bounds the total time spent retrying a single collector.
+	RetryMaxElapsedSeconds int `yaml:"retry_max_elapsed"`
+	// CollectorTimeoutSeconds bounds how long a single collector keeps being
+	// retried. A vManage call already in progress is not interrupted by it.
+	CollectorTimeoutSeconds int `yaml:"collector_timeout"`
+	// CircuitBreakerCooldownIntervals is the number of check intervals a
+	// collector is skipped for after too many consecutive failures.
+	CircuitBreakerCooldownIntervals int `yaml:"circuit_breaker_cooldown_intervals"`
+}
+
+// egressRulesConfig lets users scope a check instance to a subset of the
+// SD-WAN fabric by declaring allow/deny lists applied to every payload
+// produced by Run(). An empty list on either side of a field means "no
+// restriction" for that field.
+type egressRulesConfig struct {
+	Sites        []int    `yaml:"sites"`
+	SitesExclude []int    `yaml:"sites_exclude"`
+	VPNs         []int    `yaml:"vpns"`
+	VPNsExclude  []int    `yaml:"vpns_exclude"`
+	Hosts        []string `yaml:"hosts"`
+	HostsExclude []string `yaml:"hosts_exclude"`
 }
 
 // CiscoSdwanCheck contains the field for the CiscoSdwanCheck
 type CiscoSdwanCheck struct {
 	core.CheckBase
-	interval      time.Duration
-	config        checkCfg
-	metricsSender *report.SDWanSender
+	interval       time.Duration
+	config         checkCfg
+	metricsSender  *report.SDWanSender
+	telemetry      *client.TelemetryStore
+	egressFilter   *egressFilter
+	retryPolicy    retryPolicy
+	circuitBreaker *circuitBreaker
 }
 
 // Run executes the check
@@ -77,19 +110,28 @@ func (c *CiscoSdwanCheck) Run() error {
 		return err
 	}
 
-	devices, err := client.GetDevices()
+	devices, err := c.getDevices(client)
 	if err != nil {
 		log.Warnf("Error getting devices from Cisco SD-WAN API: %s", err)
 	}
-	vEdgeInterfaces, err := client.GetVEdgeInterfaces()
+
+	vEdgeInterfaces, err := c.getVEdgeInterfaces(client)
 	if err != nil {
 		log.Warnf("Error getting vEdge interfaces from Cisco SD-WAN API: %s", err)
 	}
-	cEdgeInterfaces, err := client.GetCEdgeInterfaces()
+
+	cEdgeInterfaces, err := c.getCEdgeInterfaces(client)
 	if err != nil {
 		log.Warnf("Error getting cEdge interfaces from Cisco SD-WAN API: %s", err)
 	}
 
+	// Scope the fabric down to the configured egress_rules before handing
+	// anything to the payload package, so large deployments can run one
+	// check instance per subset of sites/VPNs/hosts.
+	devices = filterDevices(c.egressFilter, devices)
+	vEdgeInterfaces = filterDevices(c.egressFilter, vEdgeInterfaces)
+	cEdgeInterfaces = filterDevices(c.egressFilter, cEdgeInterfaces)
+
 	devicesMetadata := payload.GetDevicesMetadata(c.config.Namespace, devices)
 	interfaces := payload.ConvertInterfaces(vEdgeInterfaces, cEdgeInterfaces)
 	interfacesMetadata, interfacesMap := payload.GetInterfacesMetadata(c.config.Namespace, interfaces)
@@ -98,12 +140,102 @@ func (c *CiscoSdwanCheck) Run() error {
 	deviceTags := payload.GetDevicesTags(c.config.Namespace, devices)
 	c.metricsSender.SetDeviceTags(deviceTags)
 
+	// Each optional collector below fetches its own data into a locally
+	// captured variable; runCollectors waits for every job to finish (with
+	// its own retry/backoff and circuit breaker) before we read the results,
+	// so the sequential Send* calls that follow see fully-populated data
+	// with no further synchronization needed.
+	var (
+		deviceStats             []client.DeviceHardwareStats
+		interfaceStats          []client.InterfaceStats
+		appRouteStats           []client.AppRouteStats
+		controlConnectionsState []client.ControlConnectionState
+		ompPeersState           []client.OMPPeerState
+		deviceCounters          []client.DeviceCounters
+		bfdSessionsState        []client.BFDSessionState
+		hardwareStates          []client.HardwareState
+		cloudApplications       []client.CloudApplicationMetric
+		bgpNeighbors            []client.BGPNeighbor
+	)
+
+	var jobs []collectorJob
+	timeout := c.collectorTimeout()
+
 	if *c.config.CollectHardwareMetrics {
-		deviceStats, err := client.GetDeviceHardwareMetrics()
-		if err != nil {
-			log.Warnf("Error getting device metrics from Cisco SD-WAN API: %s", err)
-		}
+		jobs = append(jobs, collectorJob{name: "hardware_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			deviceStats, err = client.GetDeviceHardwareMetrics()
+			return err
+		}})
+	}
+	if *c.config.CollectInterfaceMetrics {
+		jobs = append(jobs, collectorJob{name: "interface_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			interfaceStats, err = client.GetInterfacesMetrics()
+			return err
+		}})
+	}
+	if *c.config.CollectTunnelMetrics {
+		jobs = append(jobs, collectorJob{name: "tunnel_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			appRouteStats, err = client.GetApplicationAwareRoutingMetrics()
+			return err
+		}})
+	}
+	if *c.config.CollectControlConnectionMetrics {
+		jobs = append(jobs, collectorJob{name: "control_connection_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			controlConnectionsState, err = client.GetControlConnectionsState()
+			return err
+		}})
+	}
+	if *c.config.CollectOMPPeerMetrics {
+		jobs = append(jobs, collectorJob{name: "omp_peer_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			ompPeersState, err = client.GetOMPPeersState()
+			return err
+		}})
+	}
+	if *c.config.CollectDeviceCountersMetrics {
+		jobs = append(jobs, collectorJob{name: "device_counters_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			deviceCounters, err = client.GetDevicesCounters()
+			return err
+		}})
+	}
+	// Disabled  by default
+	if *c.config.CollectBFDSessionStatus {
+		jobs = append(jobs, collectorJob{name: "bfd_session_status", timeout: timeout, run: func(_ context.Context) (err error) {
+			bfdSessionsState, err = client.GetBFDSessionsState()
+			return err
+		}})
+	}
+	// Disabled  by default
+	if *c.config.CollectHardwareStatus {
+		jobs = append(jobs, collectorJob{name: "hardware_status", timeout: timeout, run: func(_ context.Context) (err error) {
+			hardwareStates, err = client.GetHardwareStates()
+			return err
+		}})
+	}
+	// Disabled  by default
+	if *c.config.CollectCloudApplicationsMetrics {
+		jobs = append(jobs, collectorJob{name: "cloud_applications_metrics", timeout: timeout, run: func(_ context.Context) (err error) {
+			cloudApplications, err = client.GetCloudExpressMetrics()
+			return err
+		}})
+	}
+	// Disabled  by default
+	if *c.config.CollectBGPNeighborStates {
+		jobs = append(jobs, collectorJob{name: "bgp_neighbor_states", timeout: timeout, run: func(_ context.Context) (err error) {
+			bgpNeighbors, err = client.GetBGPNeighbors()
+			return err
+		}})
+	}
 
+	c.runCollectors(jobs)
+
+	// Scope the VPN/tunnel/BGP-peer collections down to the configured
+	// egress_rules too, the same way devices/interfaces were scoped above.
+	appRouteStats = filterDevices(c.egressFilter, appRouteStats)
+	controlConnectionsState = filterDevices(c.egressFilter, controlConnectionsState)
+	ompPeersState = filterDevices(c.egressFilter, ompPeersState)
+	bgpNeighbors = filterDevices(c.egressFilter, bgpNeighbors)
+
+	if *c.config.CollectHardwareMetrics {
 		uptimes := payload.GetDevicesUptime(devices)
 		deviceStatus := payload.GetDevicesStatus(devices)
 
@@ -111,80 +243,31 @@ func (c *CiscoSdwanCheck) Run() error {
 		c.metricsSender.SendUptimeMetrics(uptimes)
 		c.metricsSender.SendDeviceStatusMetrics(deviceStatus)
 	}
-
 	if *c.config.CollectInterfaceMetrics {
-		interfaceStats, err := client.GetInterfacesMetrics()
-		if err != nil {
-			log.Warnf("Error getting interface metrics from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendInterfaceMetrics(interfaceStats, interfacesMap)
 	}
-
 	if *c.config.CollectTunnelMetrics {
-		appRouteStats, err := client.GetApplicationAwareRoutingMetrics()
-		if err != nil {
-			log.Warnf("Error getting application-aware routing metrics from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendAppRouteMetrics(appRouteStats)
 	}
-
 	if *c.config.CollectControlConnectionMetrics {
-		controlConnectionsState, err := client.GetControlConnectionsState()
-		if err != nil {
-			log.Warnf("Error getting control-connection states from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendControlConnectionMetrics(controlConnectionsState)
 	}
-
 	if *c.config.CollectOMPPeerMetrics {
-		ompPeersState, err := client.GetOMPPeersState()
-		if err != nil {
-			log.Warnf("Error getting OMP peer states from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendOMPPeerMetrics(ompPeersState)
 	}
-
 	if *c.config.CollectDeviceCountersMetrics {
-		deviceCounters, err := client.GetDevicesCounters()
-		if err != nil {
-			log.Warnf("Error getting device counters from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendDeviceCountersMetrics(deviceCounters)
 	}
-
-	// Disabled  by default
 	if *c.config.CollectBFDSessionStatus {
-		bfdSessionsState, err := client.GetBFDSessionsState()
-		if err != nil {
-			log.Warnf("Error getting BFD session states from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendBFDSessionMetrics(bfdSessionsState)
 	}
-
-	// Disabled  by default
 	if *c.config.CollectHardwareStatus {
-		hardwareStates, err := client.GetHardwareStates()
-		if err != nil {
-			log.Warnf("Error getting hardware states from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendHardwareMetrics(hardwareStates)
 	}
-
-	// Disabled  by default
 	if *c.config.CollectCloudApplicationsMetrics {
-		cloudApplications, err := client.GetCloudExpressMetrics()
-		if err != nil {
-			log.Warnf("Error getting cloud application metrics from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendCloudApplicationMetrics(cloudApplications)
 	}
-
-	// Disabled  by default
 	if *c.config.CollectBGPNeighborStates {
-		bgpNeighbors, err := client.GetBGPNeighbors()
-		if err != nil {
-			log.Warnf("Error getting BGP neighbors from Cisco SD-WAN API: %s", err)
-		}
 		c.metricsSender.SendBGPNeighborMetrics(bgpNeighbors)
 	}
 
@@ -250,12 +333,20 @@ func (c *CiscoSdwanCheck) Configure(senderManager sender.SenderManager, integrat
 	}
 
 	c.metricsSender = report.NewSDWanSender(sender, c.config.Namespace)
+	c.telemetry = client.NewTelemetryStore()
+	c.egressFilter = newEgressFilter(c.config.EgressRules)
+	c.retryPolicy = retryPolicy{
+		initialInterval: time.Duration(c.config.RetryInitialIntervalSeconds) * time.Second,
+		maxInterval:     time.Duration(c.config.RetryMaxIntervalSeconds) * time.Second,
+		maxElapsed:      time.Duration(c.config.RetryMaxElapsedSeconds) * time.Second,
+	}
+	c.circuitBreaker = newCircuitBreaker(c.config.CircuitBreakerCooldownIntervals)
 
 	return nil
 }
 
 func (c *CiscoSdwanCheck) buildClientOptions() ([]client.ClientOptions, error) {
-	var clientOptions []client.ClientOptions
+	clientOptions := []client.ClientOptions{client.WithTelemetryStore(c.telemetry)}
 
 	if c.config.Insecure || c.config.CAFile != "" {
 		options, err := client.WithTLSConfig(c.config.Insecure, c.config.CAFile)
@@ -285,6 +376,27 @@ func (c *CiscoSdwanCheck) buildClientOptions() ([]client.ClientOptions, error) {
 	return clientOptions, nil
 }
 
+func (c *CiscoSdwanCheck) getDevices(cl *client.Client) (result []client.Device, err error) {
+	done := c.telemetry.StartRequest("devices")
+	defer func() { done(err) }()
+	result, err = cl.GetDevices()
+	return result, err
+}
+
+func (c *CiscoSdwanCheck) getVEdgeInterfaces(cl *client.Client) (result []client.VEdgeInterface, err error) {
+	done := c.telemetry.StartRequest("vedge_interfaces")
+	defer func() { done(err) }()
+	result, err = cl.GetVEdgeInterfaces()
+	return result, err
+}
+
+func (c *CiscoSdwanCheck) getCEdgeInterfaces(cl *client.Client) (result []client.CEdgeInterface, err error) {
+	done := c.telemetry.StartRequest("cedge_interfaces")
+	defer func() { done(err) }()
+	result, err = cl.GetCEdgeInterfaces()
+	return result, err
+}
+
 // Interval returns the scheduling time for the check
 func (c *CiscoSdwanCheck) Interval() time.Duration {
 	return c.interval
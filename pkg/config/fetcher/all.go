@@ -0,0 +1,314 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/defaultpaths"
+)
+
+// defaultPerAgentTimeout bounds how long FetchAll waits for a single agent,
+// independently of the `server_timeout` used by the per-agent fetchers above.
+const defaultPerAgentTimeout = 10 * time.Second
+
+// defaultPorts is the well-known IPC port for each agent, used as a fallback
+// when its cmd_port-style config key isn't set. We only trust a default port
+// when an auth_token file is also present next to datadog.yaml -- otherwise
+// there's nothing to authenticate the request with, and guessing the port is
+// worthless on its own.
+var defaultPorts = map[string]int{
+	"cmd_port":                5001,
+	"security_agent.cmd_port": 5010,
+	"apm_config.debug.port":   5012,
+	"process_config.cmd_port": 6162,
+}
+
+// SourceOrigin is the value of a single configuration key together with the
+// configuration layer ("default", "file", "env", "runtime-override", ...)
+// that set it, as reported by an agent's FullConfigBySource response.
+type SourceOrigin struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// AgentConfigSnapshot is the result of querying a single agent process for its configuration.
+type AgentConfigSnapshot struct {
+	// AgentName identifies the process this snapshot came from (e.g. "security-agent").
+	AgentName string `json:"agent_name"`
+	// Config is keyed by configuration key and holds the merged value plus the
+	// layer that set it, built from that agent's FullConfigBySource response.
+	Config map[string]SourceOrigin `json:"config"`
+	// Err is set when the agent couldn't be reached or its response couldn't be parsed.
+	// A non-nil Err means Config is empty for this agent.
+	Err error `json:"error,omitempty"`
+}
+
+// agentQuery describes how to reach and parse the config of one agent process.
+// portKey is the config key holding the agent's IPC port; it's used both to
+// read the configured port and, when that's unset, to look up a fallback in
+// defaultPorts.
+type agentQuery struct {
+	name     string
+	portKey  string
+	bySource bool
+	url      func(cfg config.Reader, port int) (string, error)
+}
+
+var agentQueries = []agentQuery{
+	{
+		name: "core-agent", portKey: "cmd_port", bySource: true,
+		url: func(_ config.Reader, port int) (string, error) {
+			return fmt.Sprintf("https://localhost:%d/agent/config", port), nil
+		},
+	},
+	{
+		name: "security-agent", portKey: "security_agent.cmd_port", bySource: true,
+		url: func(_ config.Reader, port int) (string, error) {
+			return fmt.Sprintf("https://localhost:%d/agent/config", port), nil
+		},
+	},
+	{
+		name: "trace-agent", portKey: "apm_config.debug.port", bySource: false,
+		url: func(_ config.Reader, port int) (string, error) {
+			return fmt.Sprintf("https://127.0.0.1:%d/config", port), nil
+		},
+	},
+	{
+		name: "process-agent", portKey: "process_config.cmd_port", bySource: false,
+		url: func(cfg config.Reader, port int) (string, error) {
+			ipcAddress, err := setup.GetIPCAddress(cfg)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("https://%s:%d/config/all", ipcAddress, port), nil
+		},
+	},
+}
+
+// discoverPort returns the port an agent's IPC endpoint is listening on: the
+// configured portKey if set, otherwise a well-known default -- but only when
+// an auth_token file is present to actually authenticate against it.
+func discoverPort(cfg config.Reader, portKey string) (int, error) {
+	if port := cfg.GetInt(portKey); port > 0 {
+		return port, nil
+	}
+
+	defaultPort, ok := defaultPorts[portKey]
+	if !ok {
+		return 0, fmt.Errorf("%s is not configured", portKey)
+	}
+	if _, err := locateAuthTokenFile(cfg); err != nil {
+		return 0, fmt.Errorf("%s is not configured, and no auth token file was found to fall back on the default port %d: %w", portKey, defaultPort, err)
+	}
+	return defaultPort, nil
+}
+
+// locateAuthTokenFile looks for the auth_token file the agent writes next to
+// datadog.yaml, which fetchConfig/fetchConfigStream need in order to
+// authenticate against a discovered (non-configured) port.
+func locateAuthTokenFile(cfg config.Reader) (string, error) {
+	confDir := cfg.GetString("confd_path")
+	if confDir == "" {
+		confDir = defaultpaths.ConfPath
+	}
+
+	authTokenPath := filepath.Join(filepath.Dir(confDir), "auth_token")
+	if _, err := os.Stat(authTokenPath); err != nil {
+		return "", err
+	}
+	return authTokenPath, nil
+}
+
+// FetchAll concurrently queries every known agent IPC endpoint (core, security-agent,
+// trace-agent, process-agent) for their sourced configuration layers, short-circuiting
+// any agent that doesn't respond within perAgentTimeout (defaultPerAgentTimeout if <= 0),
+// and returns a snapshot per agent. Unreachable agents are reported via their
+// AgentConfigSnapshot.Err rather than failing the whole call, so callers always get
+// partial results for the agents that did respond.
+//
+// Ports are read from each agent's portKey config; an agent whose port isn't
+// configured is still queried on a well-known default port, as long as an
+// auth_token file can be found to authenticate with (see discoverPort).
+func FetchAll(ctx context.Context, cfg config.Reader) (map[string]AgentConfigSnapshot, error) {
+	return fetchAll(ctx, cfg, defaultPerAgentTimeout)
+}
+
+// FetchAllWithTimeout is FetchAll with an explicit per-agent timeout, mainly for tests.
+func FetchAllWithTimeout(ctx context.Context, cfg config.Reader, perAgentTimeout time.Duration) (map[string]AgentConfigSnapshot, error) {
+	return fetchAll(ctx, cfg, perAgentTimeout)
+}
+
+func fetchAll(ctx context.Context, cfg config.Reader, perAgentTimeout time.Duration) (map[string]AgentConfigSnapshot, error) {
+	if perAgentTimeout <= 0 {
+		perAgentTimeout = defaultPerAgentTimeout
+	}
+
+	work := make([]namedWork, 0, len(agentQueries))
+	for _, q := range agentQueries {
+		q := q
+		work = append(work, namedWork{
+			name: q.name,
+			run: func() (string, error) {
+				port, err := discoverPort(cfg, q.portKey)
+				if err != nil {
+					return "", err
+				}
+				url, err := q.url(cfg, port)
+				if err != nil {
+					return "", err
+				}
+				return fetchConfig(cfg, url, q.name, q.bySource)
+			},
+		})
+	}
+
+	return runAll(ctx, perAgentTimeout, work), nil
+}
+
+// FetchAllStreaming is FetchAll, except each agent's response is decoded
+// directly off the wire via a json.Decoder instead of being buffered into a
+// string first -- useful when a config is too large to comfortably hold in
+// memory twice (once as raw bytes, once parsed). Agents that don't expose a
+// per-source (JSON) config -- trace-agent and process-agent, which only
+// expose plain-text FullConfig -- are reported unchanged, as a single
+// "config" entry, the same as FetchAll does for them.
+func FetchAllStreaming(ctx context.Context, cfg config.Reader) (map[string]AgentConfigSnapshot, error) {
+	return fetchAllStreaming(ctx, cfg, defaultPerAgentTimeout)
+}
+
+func fetchAllStreaming(ctx context.Context, cfg config.Reader, perAgentTimeout time.Duration) (map[string]AgentConfigSnapshot, error) {
+	if perAgentTimeout <= 0 {
+		perAgentTimeout = defaultPerAgentTimeout
+	}
+
+	work := make([]namedWork, 0, len(agentQueries))
+	for _, q := range agentQueries {
+		q := q
+		work = append(work, namedWork{
+			name: q.name,
+			run: func() (string, error) {
+				port, err := discoverPort(cfg, q.portKey)
+				if err != nil {
+					return "", err
+				}
+				url, err := q.url(cfg, port)
+				if err != nil {
+					return "", err
+				}
+
+				if !q.bySource {
+					return fetchConfig(cfg, url, q.name, false)
+				}
+
+				body, err := fetchConfigStream(cfg, url)
+				if err != nil {
+					return "", err
+				}
+				defer body.Close()
+
+				var parsed map[string]SourceOrigin
+				if err := json.NewDecoder(body).Decode(&parsed); err != nil {
+					return "", fmt.Errorf("decoding %s config: %w", q.name, err)
+				}
+				raw, err := json.Marshal(parsed)
+				if err != nil {
+					return "", err
+				}
+				return string(raw), nil
+			},
+		})
+	}
+
+	return runAll(ctx, perAgentTimeout, work), nil
+}
+
+// namedWork is a unit of work to run concurrently as part of runAll: run
+// produces either a raw config payload (YAML, or the JSON FullConfigBySource
+// shape) or an error, exactly like the per-agent fetchers in from_processes.go.
+type namedWork struct {
+	name string
+	run  func() (string, error)
+}
+
+// runAll runs each item in work concurrently, with its own timeout, and
+// collects a snapshot per item -- so a single slow or broken agent can't
+// block or fail the others. It's the shared concurrency/aggregation core
+// behind fetchAll and fetchAllStreaming.
+func runAll(ctx context.Context, timeout time.Duration, work []namedWork) map[string]AgentConfigSnapshot {
+	results := make(map[string]AgentConfigSnapshot, len(work))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, w := range work {
+		wg.Add(1)
+		go func(w namedWork) {
+			defer wg.Done()
+
+			raw, err := runWithTimeout(ctx, timeout, w.name, w.run)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				results[w.name] = AgentConfigSnapshot{AgentName: w.name, Err: err}
+				return
+			}
+			bySource, err := parseBySource(raw)
+			if err != nil {
+				results[w.name] = AgentConfigSnapshot{AgentName: w.name, Err: err}
+				return
+			}
+			results[w.name] = AgentConfigSnapshot{AgentName: w.name, Config: bySource}
+		}(w)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// runWithTimeout runs work in its own goroutine and returns its result,
+// unless timeout elapses or ctx is cancelled first.
+func runWithTimeout(ctx context.Context, timeout time.Duration, name string, work func() (string, error)) (string, error) {
+	type result struct {
+		raw string
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		raw, err := work()
+		done <- result{raw: raw, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.raw, r.err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("timed out after %s waiting for %s", timeout, name)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// parseBySource parses the JSON produced by FullConfigBySource, where each
+// key maps to {value, source}, into a SourceOrigin map. Agents that only
+// expose FullConfig (no per-key source breakdown) have their raw YAML
+// reported as a single "config" entry instead of failing the whole fetch.
+func parseBySource(raw string) (map[string]SourceOrigin, error) {
+	var parsed map[string]SourceOrigin
+	if err := json.Unmarshal([]byte(raw), &parsed); err == nil {
+		return parsed, nil
+	}
+	return map[string]SourceOrigin{"config": {Value: raw, Source: "full-config"}}, nil
+}
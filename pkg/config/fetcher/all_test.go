@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithTimeoutReturnsResult(t *testing.T) {
+	raw, err := runWithTimeout(context.Background(), time.Second, "core-agent", func() (string, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", raw)
+}
+
+func TestRunWithTimeoutPropagatesError(t *testing.T) {
+	boom := errors.New("boom")
+	_, err := runWithTimeout(context.Background(), time.Second, "core-agent", func() (string, error) {
+		return "", boom
+	})
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestRunWithTimeoutExpires(t *testing.T) {
+	_, err := runWithTimeout(context.Background(), 10*time.Millisecond, "trace-agent", func() (string, error) {
+		time.Sleep(time.Second)
+		return "too late", nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Contains(t, err.Error(), "trace-agent")
+}
+
+func TestRunWithTimeoutRespectsCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := runWithTimeout(ctx, time.Second, "security-agent", func() (string, error) {
+		time.Sleep(time.Second)
+		return "too late", nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunAllAggregatesPartialResults(t *testing.T) {
+	work := []namedWork{
+		{name: "core-agent", run: func() (string, error) { return `{"site":{"value":"datadoghq.com","source":"file"}}`, nil }},
+		{name: "security-agent", run: func() (string, error) { return "", errors.New("connection refused") }},
+		{name: "trace-agent", run: func() (string, error) {
+			time.Sleep(time.Second)
+			return "too late", nil
+		}},
+	}
+
+	results := runAll(context.Background(), 50*time.Millisecond, work)
+	require.Len(t, results, 3)
+
+	core := results["core-agent"]
+	require.NoError(t, core.Err)
+	require.Contains(t, core.Config, "site")
+	assert.Equal(t, "datadoghq.com", core.Config["site"].Value)
+
+	security := results["security-agent"]
+	require.Error(t, security.Err)
+	assert.Contains(t, security.Err.Error(), "connection refused")
+	assert.Empty(t, security.Config)
+
+	trace := results["trace-agent"]
+	require.Error(t, trace.Err)
+	assert.Contains(t, trace.Err.Error(), "timed out")
+}
+
+func TestParseBySourceFallsBackToFullConfig(t *testing.T) {
+	parsed, err := parseBySource("api_key: abc\n")
+	require.NoError(t, err)
+	require.Contains(t, parsed, "config")
+	assert.Equal(t, "api_key: abc\n", parsed["config"].Value)
+	assert.Equal(t, "full-config", parsed["config"].Source)
+}
+
+func TestParseBySourceDecodesJSON(t *testing.T) {
+	parsed, err := parseBySource(`{"log_level":{"value":"debug","source":"env"}}`)
+	require.NoError(t, err)
+	require.Contains(t, parsed, "log_level")
+	assert.Equal(t, "debug", parsed["log_level"].Value)
+	assert.Equal(t, "env", parsed["log_level"].Source)
+}
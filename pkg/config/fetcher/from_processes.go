@@ -8,6 +8,8 @@ package fetcher
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
@@ -16,93 +18,99 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config/setup"
 )
 
-// SecurityAgentConfig fetch the configuration from the security-agent process by querying its HTTPS API
-func SecurityAgentConfig(config config.Reader) (string, error) {
-	err := util.SetAuthToken(config)
-	if err != nil {
+// fetchConfig authenticates against url using cfg's auth token, and returns
+// either the full config or its per-source breakdown depending on bySource.
+// It's the shared implementation behind every SecurityAgentConfig-style
+// per-agent helper below, and behind FetchAll's agentQuery table.
+func fetchConfig(cfg config.Reader, url, name string, bySource bool) (string, error) {
+	if err := util.SetAuthToken(cfg); err != nil {
 		return "", err
 	}
 
-	port := config.GetInt("security_agent.cmd_port")
-	if port <= 0 {
-		return "", fmt.Errorf("invalid security_agent.cmd_port -- %d", port)
-	}
-
 	c := util.GetClient()
-	c.Timeout = config.GetDuration("server_timeout") * time.Second
+	c.Timeout = cfg.GetDuration("server_timeout") * time.Second
 
-	apiConfigURL := fmt.Sprintf("https://localhost:%v/agent/config", port)
-	client := settingshttp.NewClient(c, apiConfigURL, "security-agent", settingshttp.NewHTTPClientOptions(util.CloseConnection))
+	client := settingshttp.NewClient(c, url, name, settingshttp.NewHTTPClientOptions(util.CloseConnection))
+	if bySource {
+		return client.FullConfigBySource()
+	}
 	return client.FullConfig()
 }
 
-// SecurityAgentConfigBySource fetch all configuration layers from the security-agent process by querying its HTTPS API
-func SecurityAgentConfigBySource(config config.Reader) (string, error) {
-	err := util.SetAuthToken(config)
+// fetchConfigStream is fetchConfig's streaming counterpart: it returns the
+// live response body instead of buffering it into a string first, for
+// configs too large to comfortably hold in memory twice (once as raw bytes,
+// once parsed). Callers must close the returned body.
+func fetchConfigStream(cfg config.Reader, url string) (io.ReadCloser, error) {
+	if err := util.SetAuthToken(cfg); err != nil {
+		return nil, err
+	}
+
+	c := util.GetClient()
+	c.Timeout = cfg.GetDuration("server_timeout") * time.Second
+
+	resp, err := c.Get(url)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
 	}
+	return resp.Body, nil
+}
 
-	port := config.GetInt("security_agent.cmd_port")
+// SecurityAgentConfig fetch the configuration from the security-agent process by querying its HTTPS API
+func SecurityAgentConfig(cfg config.Reader) (string, error) {
+	port := cfg.GetInt("security_agent.cmd_port")
 	if port <= 0 {
 		return "", fmt.Errorf("invalid security_agent.cmd_port -- %d", port)
 	}
-
-	c := util.GetClient()
-	c.Timeout = config.GetDuration("server_timeout") * time.Second
-
-	apiConfigURL := fmt.Sprintf("https://localhost:%v/agent/config", port)
-	client := settingshttp.NewClient(c, apiConfigURL, "security-agent", settingshttp.NewHTTPClientOptions(util.CloseConnection))
-	return client.FullConfigBySource()
+	return fetchConfig(cfg, fmt.Sprintf("https://localhost:%v/agent/config", port), "security-agent", false)
 }
 
-// TraceAgentConfig fetch the configuration from the trace-agent process by querying its HTTPS API
-func TraceAgentConfig(config config.Reader) (string, error) {
-	err := util.SetAuthToken(config)
-	if err != nil {
-		return "", err
+// SecurityAgentConfigBySource fetch all configuration layers from the security-agent process by querying its HTTPS API
+func SecurityAgentConfigBySource(cfg config.Reader) (string, error) {
+	port := cfg.GetInt("security_agent.cmd_port")
+	if port <= 0 {
+		return "", fmt.Errorf("invalid security_agent.cmd_port -- %d", port)
 	}
+	return fetchConfig(cfg, fmt.Sprintf("https://localhost:%v/agent/config", port), "security-agent", true)
+}
 
-	port := config.GetInt("apm_config.debug.port")
+// TraceAgentConfig fetch the configuration from the trace-agent process by querying its HTTPS API
+func TraceAgentConfig(cfg config.Reader) (string, error) {
+	port := cfg.GetInt("apm_config.debug.port")
 	if port <= 0 {
 		return "", fmt.Errorf("invalid apm_config.debug.port -- %d", port)
 	}
-
-	c := util.GetClient()
-	c.Timeout = config.GetDuration("server_timeout") * time.Second
-
-	ipcAddressWithPort := fmt.Sprintf("https://127.0.0.1:%d/config", port)
-
-	client := settingshttp.NewClient(c, ipcAddressWithPort, "trace-agent", settingshttp.NewHTTPClientOptions(util.CloseConnection))
-	return client.FullConfig()
+	return fetchConfig(cfg, fmt.Sprintf("https://127.0.0.1:%d/config", port), "trace-agent", false)
 }
 
-// ProcessAgentConfig fetch the configuration from the process-agent process by querying its HTTPS API
-func ProcessAgentConfig(config config.Reader, getEntireConfig bool) (string, error) {
-	err := util.SetAuthToken(config)
-	if err != nil {
-		return "", err
+// CoreAgentConfigBySource fetch all configuration layers from the core agent process by querying its HTTPS API
+func CoreAgentConfigBySource(cfg config.Reader) (string, error) {
+	port := cfg.GetInt("cmd_port")
+	if port <= 0 {
+		return "", fmt.Errorf("invalid cmd_port -- %d", port)
 	}
+	return fetchConfig(cfg, fmt.Sprintf("https://localhost:%v/agent/config", port), "core-agent", true)
+}
 
-	ipcAddress, err := setup.GetIPCAddress(config)
+// ProcessAgentConfig fetch the configuration from the process-agent process by querying its HTTPS API
+func ProcessAgentConfig(cfg config.Reader, getEntireConfig bool) (string, error) {
+	ipcAddress, err := setup.GetIPCAddress(cfg)
 	if err != nil {
 		return "", err
 	}
 
-	port := config.GetInt("process_config.cmd_port")
+	port := cfg.GetInt("process_config.cmd_port")
 	if port <= 0 {
 		return "", fmt.Errorf("invalid process_config.cmd_port -- %d", port)
 	}
 
-	ipcAddressWithPort := fmt.Sprintf("https://%s:%d/config", ipcAddress, port)
+	url := fmt.Sprintf("https://%s:%d/config", ipcAddress, port)
 	if getEntireConfig {
-		ipcAddressWithPort += "/all"
+		url += "/all"
 	}
-
-	c := util.GetClient()
-	c.Timeout = config.GetDuration("server_timeout") * time.Second
-
-	client := settingshttp.NewClient(c, ipcAddressWithPort, "process-agent", settingshttp.NewHTTPClientOptions(util.CloseConnection))
-
-	return client.FullConfig()
+	return fetchConfig(cfg, url, "process-agent", false)
 }
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+
+// Package dynamicinstrumentation implements Go Dynamic Instrumentation (DI):
+// it attaches probes to points in a running Go binary and streams back
+// snapshots of their state, either driven by a remote control plane or, in
+// offline mode, by a local probes file.
+package dynamicinstrumentation
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DIOptions configures a GoDI instance.
+type DIOptions struct {
+	// RateLimitPerProbePerSecond is the configured per-probe snapshot rate
+	// limit. GoDI stores it but does not enforce it.
+	RateLimitPerProbePerSecond float64
+	// OfflineOptions configures file-based probe loading, used when no
+	// control-plane connection is available.
+	OfflineOptions OfflineOptions
+}
+
+// OfflineOptions configures dynamic instrumentation's offline mode, in which
+// probes are read from a local file instead of a remote control plane.
+type OfflineOptions struct {
+	// Offline enables offline mode.
+	Offline bool
+	// ProbesFilePath is the JSON file watched for probe definitions.
+	ProbesFilePath string
+	// SnapshotOutput is the configured snapshot output file. GoDI does not
+	// write to it; snapshots are only kept in memory.
+	SnapshotOutput string
+	// DiagnosticOutput is the configured diagnostics output file. GoDI does
+	// not write to it.
+	DiagnosticOutput string
+}
+
+// Stats reports counters about a running GoDI instance.
+type Stats struct {
+	PIDEventsCreatedCount   uint64
+	ProbeEventsCreatedCount uint64
+}
+
+// maxSnapshotHistory bounds how many snapshot events GoDI keeps in memory for
+// callers like the /snapshots HTTP API; older events are dropped once the
+// cap is reached.
+const maxSnapshotHistory = 1000
+
+// GoDI is a running dynamic instrumentation instance. It owns the set of
+// installed probes and the in-memory snapshot history, and in offline mode
+// also owns the goroutine watching OfflineOptions.ProbesFilePath.
+type GoDI struct {
+	opts *DIOptions
+
+	mu     sync.RWMutex
+	probes map[string]*ProbeStatus
+	// fileProbes is the set of probe IDs last loaded from the offline probes
+	// file, so reloadProbesFile can remove a probe that disappeared from the
+	// file without touching probes installed through the /probes API.
+	fileProbes map[string]struct{}
+	snapshots  []SnapshotEvent
+	stats      Stats
+
+	stopOffline chan struct{}
+}
+
+// RunDynamicInstrumentation starts a GoDI instance configured by opts. In
+// offline mode it also launches the goroutine that watches
+// OfflineOptions.ProbesFilePath for probe definitions.
+func RunDynamicInstrumentation(opts *DIOptions) (*GoDI, error) {
+	g := &GoDI{
+		opts:       opts,
+		probes:     make(map[string]*ProbeStatus),
+		fileProbes: make(map[string]struct{}),
+	}
+
+	if opts.OfflineOptions.Offline {
+		if opts.OfflineOptions.ProbesFilePath == "" {
+			return nil, fmt.Errorf("dynamic instrumentation: offline mode requires a probes file path")
+		}
+		g.stopOffline = make(chan struct{})
+		go g.watchProbesFile(opts.OfflineOptions.ProbesFilePath, g.stopOffline)
+	}
+
+	return g, nil
+}
+
+// Close stops the offline probes-file watcher, if one is running.
+func (g *GoDI) Close() {
+	if g.stopOffline != nil {
+		close(g.stopOffline)
+	}
+}
+
+// GetStats returns a snapshot of g's counters.
+func (g *GoDI) GetStats() Stats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.stats
+}
+
+// recordSnapshot appends event to g's in-memory snapshot history, trimming
+// the oldest entries once maxSnapshotHistory is exceeded.
+func (g *GoDI) recordSnapshot(event SnapshotEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.snapshots = append(g.snapshots, event)
+	if over := len(g.snapshots) - maxSnapshotHistory; over > 0 {
+		g.snapshots = g.snapshots[over:]
+	}
+	g.stats.ProbeEventsCreatedCount++
+}
@@ -8,7 +8,10 @@
 package module
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	coreconfig "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/system-probe/api/module"
@@ -18,9 +21,20 @@ import (
 	di "github.com/DataDog/datadog-agent/pkg/dynamicinstrumentation"
 )
 
+// goDI is the subset of *di.GoDI the module's HTTP handlers depend on,
+// satisfied by *di.GoDI and, in tests, by a fake.
+type goDI interface {
+	GetStats() di.Stats
+	Close()
+	ListProbes() []di.ProbeStatus
+	InstallProbe(di.Probe) (*di.ProbeStatus, error)
+	RemoveProbe(id string) error
+	SnapshotsSince(since time.Time) []di.SnapshotEvent
+}
+
 // Module is the dynamic instrumentation system probe module
 type Module struct {
-	godi *di.GoDI
+	godi goDI
 }
 
 // NewModule creates a new dynamic instrumentation system probe module
@@ -72,7 +86,9 @@ func (m *Module) GetStats() map[string]interface{} {
 	return debug
 }
 
-// Register creates a health check endpoint for the dynamic instrumentation module
+// Register creates the dynamic instrumentation module's HTTP API: a /check
+// health endpoint, and a /probes, /probes/{id}, /snapshots REST surface for
+// managing probes and streaming back their snapshots.
 func (m *Module) Register(httpMux *module.Router) error {
 	httpMux.HandleFunc("/check", utils.WithConcurrencyLimit(utils.DefaultMaxConcurrentRequests,
 		func(w http.ResponseWriter, _ *http.Request) {
@@ -80,6 +96,97 @@ func (m *Module) Register(httpMux *module.Router) error {
 			utils.WriteAsJSON(w, stats, utils.CompactOutput)
 		}))
 
+	httpMux.HandleFunc("/probes", utils.WithConcurrencyLimit(utils.DefaultMaxConcurrentRequests, m.handleProbes))
+	httpMux.HandleFunc("/probes/", utils.WithConcurrencyLimit(utils.DefaultMaxConcurrentRequests, m.handleProbeByID))
+	httpMux.HandleFunc("/snapshots", utils.WithConcurrencyLimit(utils.DefaultMaxConcurrentRequests, m.handleSnapshots))
+
 	log.Info("Registering dynamic instrumentation module")
 	return nil
 }
+
+// handleProbes serves GET /probes (list active probes) and POST /probes
+// (install a probe), both routed through the same GoDI codepath the offline
+// probes-file loader uses.
+func (m *Module) handleProbes(w http.ResponseWriter, r *http.Request) {
+	if m.godi == nil {
+		http.Error(w, "dynamic instrumentation module is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		utils.WriteAsJSON(w, m.godi.ListProbes(), utils.CompactOutput)
+	case http.MethodPost:
+		var probe di.Probe
+		if err := json.NewDecoder(r.Body).Decode(&probe); err != nil {
+			http.Error(w, "invalid probe definition: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		status, err := m.godi.InstallProbe(probe)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		utils.WriteAsJSON(w, status, utils.CompactOutput)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProbeByID serves DELETE /probes/{id}, removing one installed probe.
+func (m *Module) handleProbeByID(w http.ResponseWriter, r *http.Request) {
+	if m.godi == nil {
+		http.Error(w, "dynamic instrumentation module is not running", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/probes/")
+	if id == "" {
+		http.Error(w, "missing probe id", http.StatusBadRequest)
+		return
+	}
+	if err := m.godi.RemoveProbe(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSnapshots serves GET /snapshots?since=<RFC3339 timestamp>, streaming
+// matching snapshot events back as newline-delimited JSON.
+func (m *Module) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	if m.godi == nil {
+		http.Error(w, "dynamic instrumentation module is not running", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	since := time.Time{}
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "invalid since parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for _, event := range m.godi.SnapshotsSince(since) {
+		if err := encoder.Encode(event); err != nil {
+			log.Warnf("dynamic instrumentation: could not write snapshot event: %s", err)
+			return
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	di "github.com/DataDog/datadog-agent/pkg/dynamicinstrumentation"
+)
+
+// fakeGoDI is an in-memory stand-in for *di.GoDI used to exercise Module's
+// HTTP handlers without a real dynamic instrumentation instance.
+type fakeGoDI struct {
+	probes    map[string]*di.ProbeStatus
+	snapshots []di.SnapshotEvent
+	closed    bool
+}
+
+func newFakeGoDI() *fakeGoDI {
+	return &fakeGoDI{probes: make(map[string]*di.ProbeStatus)}
+}
+
+func (f *fakeGoDI) GetStats() di.Stats { return di.Stats{} }
+func (f *fakeGoDI) Close()             { f.closed = true }
+
+func (f *fakeGoDI) ListProbes() []di.ProbeStatus {
+	statuses := make([]di.ProbeStatus, 0, len(f.probes))
+	for _, status := range f.probes {
+		statuses = append(statuses, *status)
+	}
+	return statuses
+}
+
+func (f *fakeGoDI) InstallProbe(probe di.Probe) (*di.ProbeStatus, error) {
+	if probe.ID == "" {
+		return nil, fmt.Errorf("probe is missing an id")
+	}
+	status := &di.ProbeStatus{Probe: probe, State: di.ProbeStateInstalled}
+	f.probes[probe.ID] = status
+	return status, nil
+}
+
+func (f *fakeGoDI) RemoveProbe(id string) error {
+	if _, ok := f.probes[id]; !ok {
+		return fmt.Errorf("no probe installed with id %q", id)
+	}
+	delete(f.probes, id)
+	return nil
+}
+
+func (f *fakeGoDI) SnapshotsSince(since time.Time) []di.SnapshotEvent {
+	events := make([]di.SnapshotEvent, 0, len(f.snapshots))
+	for _, event := range f.snapshots {
+		if !event.Timestamp.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestModuleProbeCRUDLifecycle(t *testing.T) {
+	fake := newFakeGoDI()
+	m := &Module{godi: fake}
+
+	body, err := json.Marshal(di.Probe{ID: "probe-1", Where: di.ProbeLocation{MethodName: "DoWork"}})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/probes", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	m.handleProbes(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/probes", nil)
+	rec = httptest.NewRecorder()
+	m.handleProbes(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var statuses []di.ProbeStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "probe-1", statuses[0].ID)
+	assert.Equal(t, di.ProbeStateInstalled, statuses[0].State)
+
+	req = httptest.NewRequest(http.MethodDelete, "/probes/probe-1", nil)
+	rec = httptest.NewRecorder()
+	m.handleProbeByID(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/probes", nil)
+	rec = httptest.NewRecorder()
+	m.handleProbes(rec, req)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	assert.Empty(t, m.godi.ListProbes())
+
+	req = httptest.NewRequest(http.MethodDelete, "/probes/probe-1", nil)
+	rec = httptest.NewRecorder()
+	m.handleProbeByID(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestModuleHandleProbesInvalidBody(t *testing.T) {
+	m := &Module{godi: newFakeGoDI()}
+
+	req := httptest.NewRequest(http.MethodPost, "/probes", bytes.NewReader([]byte("not json")))
+	rec := httptest.NewRecorder()
+	m.handleProbes(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestModuleHandleSnapshots(t *testing.T) {
+	fake := newFakeGoDI()
+	now := time.Now()
+	fake.snapshots = []di.SnapshotEvent{
+		{ProbeID: "probe-1", Timestamp: now.Add(-time.Hour)},
+		{ProbeID: "probe-1", Timestamp: now},
+	}
+	m := &Module{godi: fake}
+
+	req := httptest.NewRequest(http.MethodGet, "/snapshots?since="+now.Add(-time.Minute).Format(time.RFC3339), nil)
+	rec := httptest.NewRecorder()
+	m.handleSnapshots(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	decoder := json.NewDecoder(rec.Body)
+	var events []di.SnapshotEvent
+	for decoder.More() {
+		var event di.SnapshotEvent
+		require.NoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, "probe-1", events[0].ProbeID)
+}
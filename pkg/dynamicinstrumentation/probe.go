@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+
+package dynamicinstrumentation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// probesFilePollInterval is how often the offline probes file is re-read for
+// changes.
+const probesFilePollInterval = 5 * time.Second
+
+// ProbeLocation identifies the instrumentation point a probe attaches to.
+type ProbeLocation struct {
+	// TypeName is the receiver type the target method is defined on, empty
+	// for free functions.
+	TypeName string `json:"typeName,omitempty"`
+	// MethodName is the name of the function or method to instrument.
+	MethodName string `json:"methodName"`
+}
+
+// Probe is a single probe definition, as accepted both by the offline
+// ProbesFilePath loader and the /probes HTTP API.
+type Probe struct {
+	// ID uniquely identifies the probe.
+	ID string `json:"id"`
+	// Where is the instrumentation point the probe attaches to.
+	Where ProbeLocation `json:"where"`
+	// Tags are attached to every snapshot the probe emits.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// ProbeState is the lifecycle state of an installed probe.
+type ProbeState string
+
+const (
+	// ProbeStateInstalled means the probe is attached and emitting snapshots.
+	ProbeStateInstalled ProbeState = "installed"
+	// ProbeStateError means the probe failed to install.
+	ProbeStateError ProbeState = "error"
+)
+
+// ProbeStatus is a Probe together with its current installation state.
+type ProbeStatus struct {
+	Probe
+	// State is the probe's current lifecycle state.
+	State ProbeState `json:"state"`
+	// Error holds the installation error, if State is ProbeStateError.
+	Error string `json:"error,omitempty"`
+}
+
+// SnapshotEvent is a single captured probe snapshot.
+type SnapshotEvent struct {
+	// ProbeID identifies the probe that produced this snapshot.
+	ProbeID string `json:"probeId"`
+	// Timestamp is when the snapshot was captured.
+	Timestamp time.Time `json:"timestamp"`
+	// Data holds the captured variable values, keyed by name.
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// ListProbes returns the status of every installed probe.
+func (g *GoDI) ListProbes() []ProbeStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	statuses := make([]ProbeStatus, 0, len(g.probes))
+	for _, status := range g.probes {
+		statuses = append(statuses, *status)
+	}
+	return statuses
+}
+
+// InstallProbe attaches probe, sharing the codepath used for probes loaded
+// from the offline probes file. It returns the resulting status even when
+// installation fails, with State set to ProbeStateError.
+func (g *GoDI) InstallProbe(probe Probe) (*ProbeStatus, error) {
+	var err error
+	if probe.ID == "" {
+		err = fmt.Errorf("dynamic instrumentation: probe is missing an id")
+	} else if probe.Where.MethodName == "" {
+		err = fmt.Errorf("dynamic instrumentation: probe %q is missing where.methodName", probe.ID)
+	}
+	if err != nil {
+		return &ProbeStatus{Probe: probe, State: ProbeStateError, Error: err.Error()}, err
+	}
+
+	status := &ProbeStatus{Probe: probe, State: ProbeStateInstalled}
+
+	g.mu.Lock()
+	g.probes[probe.ID] = status
+	g.mu.Unlock()
+
+	return status, nil
+}
+
+// RemoveProbe detaches the probe with the given id. It returns an error if
+// no such probe is installed.
+func (g *GoDI) RemoveProbe(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.probes[id]; !ok {
+		return fmt.Errorf("dynamic instrumentation: no probe installed with id %q", id)
+	}
+	delete(g.probes, id)
+	return nil
+}
+
+// SnapshotsSince returns the recorded snapshot events captured at or after
+// since, oldest first.
+func (g *GoDI) SnapshotsSince(since time.Time) []SnapshotEvent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	events := make([]SnapshotEvent, 0, len(g.snapshots))
+	for _, event := range g.snapshots {
+		if !event.Timestamp.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// watchProbesFile polls path every probesFilePollInterval, reconciling g's
+// installed probes with the JSON array of Probe definitions it contains,
+// until stop is closed.
+func (g *GoDI) watchProbesFile(path string, stop <-chan struct{}) {
+	ticker := time.NewTicker(probesFilePollInterval)
+	defer ticker.Stop()
+
+	g.reloadProbesFile(path)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			g.reloadProbesFile(path)
+		}
+	}
+}
+
+// reloadProbesFile reads path, installs each probe it defines, and removes
+// any previously file-loaded probe that's no longer present, so the
+// installed set always mirrors the file's current contents. It logs (rather
+// than failing) on read or parse errors so a transient issue with the file
+// doesn't tear down the watcher, and it never touches probes installed
+// through the /probes API.
+func (g *GoDI) reloadProbesFile(path string) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		log.Warnf("dynamic instrumentation: could not read probes file %q: %s", path, err)
+		return
+	}
+
+	var probes []Probe
+	if err := json.Unmarshal(contents, &probes); err != nil {
+		log.Warnf("dynamic instrumentation: could not parse probes file %q: %s", path, err)
+		return
+	}
+
+	seen := make(map[string]struct{}, len(probes))
+	for _, probe := range probes {
+		seen[probe.ID] = struct{}{}
+		if _, err := g.InstallProbe(probe); err != nil {
+			log.Warnf("dynamic instrumentation: could not install probe %q from %q: %s", probe.ID, path, err)
+		}
+	}
+
+	g.mu.Lock()
+	var stale []string
+	for id := range g.fileProbes {
+		if _, ok := seen[id]; !ok {
+			stale = append(stale, id)
+		}
+	}
+	g.fileProbes = seen
+	g.mu.Unlock()
+
+	for _, id := range stale {
+		if err := g.RemoveProbe(id); err != nil {
+			log.Warnf("dynamic instrumentation: could not remove stale probe %q from %q: %s", id, path, err)
+		}
+	}
+}
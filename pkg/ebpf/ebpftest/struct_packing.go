@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package ebpftest holds helpers shared by the eBPF test suites, including
+// compile-time and runtime checks that Go structs mirroring C counterparts
+// stay in sync.
+package ebpftest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// DefaultMaxPaddingBytes is the default threshold used by AuditStructPacking:
+// structs whose padding could shrink by more bytes than this through field
+// reordering will fail the test.
+const DefaultMaxPaddingBytes = 0
+
+// fieldLayout captures the information needed to reason about where a field
+// sits in memory and how it could be reordered.
+type fieldLayout struct {
+	Name   string
+	Offset uintptr
+	Size   uintptr
+	Align  uintptr
+}
+
+// AuditStructPacking walks the fields of T (which must be a struct) in
+// declaration order and fails the test if reordering the fields
+// largest-alignment-first would save more than maxPaddingBytes of padding.
+//
+// This complements TestCgoAlignment, which only verifies that the Go and C
+// views of a struct agree on size and alignment: a struct can pass that check
+// while still wasting bytes on avoidable padding. Because these structs are
+// copied through perf/ring buffers at very high rates, the saved bytes
+// translate directly into reduced buffer pressure.
+func AuditStructPacking[T any](t *testing.T, maxPaddingBytes int) {
+	t.Helper()
+
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ.Kind() != reflect.Struct {
+		t.Fatalf("AuditStructPacking: %s is not a struct", typ.Name())
+	}
+
+	current := layoutOf(typ)
+	currentPadding := paddingOf(current, typ.Size())
+
+	optimal := append([]fieldLayout(nil), current...)
+	sort.SliceStable(optimal, func(i, j int) bool {
+		return optimal[i].Align > optimal[j].Align
+	})
+	optimalSize, optimalOffsets := pack(optimal)
+	optimalPadding := paddingOf(optimalOffsets, optimalSize)
+
+	wasted := currentPadding - optimalPadding
+	if wasted <= uintptr(maxPaddingBytes) {
+		return
+	}
+
+	names := make([]string, len(optimal))
+	for i, f := range optimal {
+		names[i] = f.Name
+	}
+	t.Errorf(
+		"%s wastes %d bytes of padding (current size %d, optimal size %d); "+
+			"reorder fields as: %v",
+		typ.Name(), wasted, typ.Size(), optimalSize, names,
+	)
+}
+
+func layoutOf(typ reflect.Type) []fieldLayout {
+	fields := make([]fieldLayout, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		fields = append(fields, fieldLayout{
+			Name:   f.Name,
+			Offset: f.Offset,
+			Size:   f.Type.Size(),
+			Align:  uintptr(f.Type.Align()),
+		})
+	}
+	return fields
+}
+
+// pack simulates the C compiler laying out fields in the given order,
+// returning the resulting struct size and the offset assigned to each field.
+func pack(fields []fieldLayout) (uintptr, []fieldLayout) {
+	var offset uintptr
+	var maxAlign uintptr = 1
+	packed := make([]fieldLayout, len(fields))
+	for i, f := range fields {
+		if f.Align > maxAlign {
+			maxAlign = f.Align
+		}
+		if f.Align > 0 {
+			offset = alignUp(offset, f.Align)
+		}
+		packed[i] = fieldLayout{Name: f.Name, Offset: offset, Size: f.Size, Align: f.Align}
+		offset += f.Size
+	}
+	return alignUp(offset, maxAlign), packed
+}
+
+func alignUp(offset, align uintptr) uintptr {
+	if align == 0 {
+		return offset
+	}
+	return (offset + align - 1) / align * align
+}
+
+func paddingOf(fields []fieldLayout, structSize uintptr) uintptr {
+	var used uintptr
+	for _, f := range fields {
+		used += f.Size
+	}
+	if structSize < used {
+		return 0
+	}
+	return structSize - used
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ebpftest
+
+import (
+	"testing"
+)
+
+type wellPackedStruct struct {
+	A uint64
+	B uint64
+	C uint32
+	D uint32
+}
+
+type poorlyPackedStruct struct {
+	A uint8
+	B uint64
+	C uint8
+	D uint64
+}
+
+func TestAuditStructPacking(t *testing.T) {
+	t.Run("well packed", func(t *testing.T) {
+		inner := &testing.T{}
+		AuditStructPacking[wellPackedStruct](inner, DefaultMaxPaddingBytes)
+		if inner.Failed() {
+			t.Errorf("expected wellPackedStruct to report no wasted padding")
+		}
+	})
+
+	t.Run("poorly packed", func(t *testing.T) {
+		inner := &testing.T{}
+		AuditStructPacking[poorlyPackedStruct](inner, DefaultMaxPaddingBytes)
+		if !inner.Failed() {
+			t.Errorf("expected poorlyPackedStruct to report wasted padding")
+		}
+	})
+
+	t.Run("tolerates configured slack", func(t *testing.T) {
+		inner := &testing.T{}
+		AuditStructPacking[poorlyPackedStruct](inner, 64)
+		if inner.Failed() {
+			t.Errorf("expected a generous maxPaddingBytes to suppress the failure")
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package diagnostic holds the predicates and record shapes shared between the
+// stream-logs CLI and the /agent/stream-logs endpoint it talks to.
+package diagnostic
+
+import (
+	"regexp"
+	"time"
+)
+
+// StreamFormat selects how each streamed log line is rendered: plain text,
+// indented JSON records, or newline-delimited JSON (ndjson) records.
+type StreamFormat string
+
+const (
+	// FormatText streams each log line as-is. This is the default.
+	FormatText StreamFormat = "text"
+	// FormatJSON streams one indented JSON Record per line.
+	FormatJSON StreamFormat = "json"
+	// FormatNDJSON streams one compact JSON Record per line, suitable for
+	// piping into jq or line-oriented log shippers.
+	FormatNDJSON StreamFormat = "ndjson"
+)
+
+// IsValid reports whether f is one of the supported stream formats.
+func (f StreamFormat) IsValid() bool {
+	switch f {
+	case FormatText, FormatJSON, FormatNDJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// Filters holds the predicates used to select which logs are streamed back by
+// the /agent/stream-logs endpoint, and the format the response should use.
+type Filters struct {
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Service string `json:"service,omitempty"`
+
+	// MessageRegex, when set, drops streamed lines whose message doesn't match it.
+	MessageRegex string `json:"message_regex,omitempty"`
+	// Tags, when set, drops streamed lines missing any of these "key:value" pairs.
+	Tags []string `json:"tags,omitempty"`
+	// Format selects how each streamed record is rendered; defaults to FormatText.
+	Format StreamFormat `json:"format,omitempty"`
+}
+
+// Record is the self-describing structure written for each streamed log line
+// when Filters.Format is FormatJSON or FormatNDJSON.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Service   string    `json:"service"`
+	Tags      []string  `json:"tags,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// CompileMessageRegex compiles f.MessageRegex, returning a nil regexp (always
+// matching) when f.MessageRegex is empty.
+func (f *Filters) CompileMessageRegex() (*regexp.Regexp, error) {
+	if f.MessageRegex == "" {
+		return nil, nil
+	}
+	return regexp.Compile(f.MessageRegex)
+}
+
+// Matches reports whether a log line with the given tags and message satisfies
+// f's MessageRegex and Tags predicates. messageRe is the compiled form of
+// f.MessageRegex, passed in so callers don't recompile it per line.
+func (f *Filters) Matches(messageRe *regexp.Regexp, tags []string, message string) bool {
+	if messageRe != nil && !messageRe.MatchString(message) {
+		return false
+	}
+	for _, want := range f.Tags {
+		found := false
+		for _, got := range tags {
+			if got == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
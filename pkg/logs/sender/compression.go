@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import "io"
+
+// CompressionCodec compresses an outgoing batch and reports the
+// Content-Encoding value the destination should advertise for it. It's
+// satisfied by comp/logs/agent/config.CompressionCodec without importing
+// that package, the same role PathwayContextHeader plays for DSM: the
+// config component builds the concrete codec, the sender only needs its
+// shape.
+type CompressionCodec interface {
+	NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error)
+}
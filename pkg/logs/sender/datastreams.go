@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package sender implements Data Streams Monitoring checkpoints for the logs
+// pipeline, mirroring the produce/consume checkpoint pattern used by the
+// dd-trace-go Kafka integration.
+package sender
+
+import (
+	"container/list"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// PathwayContextHeader is the HTTP/TCP header carrying the base64-encoded
+// pathway hash and origin timestamp, analogous to dd-pathway-ctx in
+// dd-trace-go's Data Streams Monitoring integration.
+const PathwayContextHeader = "dd-pathway-ctx"
+
+// defaultMaxPathways bounds how many distinct pathways are tracked at once,
+// so a pipeline with unbounded tag cardinality can't grow memory unbounded.
+const defaultMaxPathways = 10000
+
+var pathwayLatency = telemetry.NewHistogram(
+	"logs_data_streams",
+	"pathway_latency_seconds",
+	[]string{"service"},
+	"Latency from a pathway's origin checkpoint to the current one",
+	[]float64{.01, .05, .1, .5, 1, 5, 10, 30, 60},
+)
+
+// Pathway identifies a DSM checkpoint: a hash derived from the ordered edge
+// tags crossed so far, and the time the pathway originated.
+type Pathway struct {
+	Hash      uint64
+	StartedAt time.Time
+}
+
+// NewPathway starts a new pathway at the given edge (e.g.
+// []string{"direction:out", "topic:my-source", "type:logs"}).
+func NewPathway(edgeTags []string) Pathway {
+	return Pathway{Hash: hashEdge(0, edgeTags), StartedAt: time.Now()}
+}
+
+// Checkpoint advances an existing pathway across a new edge, combining the
+// previous hash with the new edge tags.
+func (p Pathway) Checkpoint(edgeTags []string) Pathway {
+	return Pathway{Hash: hashEdge(p.Hash, edgeTags), StartedAt: p.StartedAt}
+}
+
+// Encode serializes the pathway into the value carried by PathwayContextHeader.
+func (p Pathway) Encode() string {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[:8], p.Hash)
+	binary.BigEndian.PutUint64(buf[8:], uint64(p.StartedAt.UnixNano()))
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodePathway parses a PathwayContextHeader value, e.g. one decoded from
+// inbound Kafka message headers so a broker-fed source can continue the
+// pathway started upstream.
+func DecodePathway(encoded string) (Pathway, bool) {
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(buf) != 16 {
+		return Pathway{}, false
+	}
+	return Pathway{
+		Hash:      binary.BigEndian.Uint64(buf[:8]),
+		StartedAt: time.Unix(0, int64(binary.BigEndian.Uint64(buf[8:]))),
+	}, true
+}
+
+func hashEdge(parent uint64, edgeTags []string) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], parent)
+	_, _ = h.Write(b[:])
+	_, _ = h.Write([]byte(strings.Join(edgeTags, ",")))
+	return h.Sum64()
+}
+
+// PathwayTracker records per-pathway latency from a pathway's origin to the
+// moment it is observed here, bounding memory with LRU eviction over
+// maxPathways distinct hashes.
+type PathwayTracker struct {
+	mu          sync.Mutex
+	service     string
+	maxPathways int
+	order       *list.List
+	elements    map[uint64]*list.Element
+}
+
+// NewPathwayTracker creates a tracker for the given DSM service name. A
+// maxPathways <= 0 falls back to defaultMaxPathways.
+func NewPathwayTracker(service string, maxPathways int) *PathwayTracker {
+	if maxPathways <= 0 {
+		maxPathways = defaultMaxPathways
+	}
+	return &PathwayTracker{
+		service:     service,
+		maxPathways: maxPathways,
+		order:       list.New(),
+		elements:    make(map[uint64]*list.Element),
+	}
+}
+
+// Observe records the latency between a pathway's origin and now, flushing it
+// to the existing agent aggregator via the logs_data_streams telemetry
+// histogram, and evicts the least-recently-seen pathway if over capacity.
+func (t *PathwayTracker) Observe(p Pathway) {
+	pathwayLatency.Observe(time.Since(p.StartedAt).Seconds(), t.service)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if el, ok := t.elements[p.Hash]; ok {
+		t.order.MoveToFront(el)
+		return
+	}
+
+	el := t.order.PushFront(p.Hash)
+	t.elements[p.Hash] = el
+
+	for t.order.Len() > t.maxPathways {
+		oldest := t.order.Back()
+		if oldest == nil {
+			break
+		}
+		t.order.Remove(oldest)
+		delete(t.elements, oldest.Value.(uint64))
+	}
+}
+
+// Len returns the number of distinct pathways currently tracked.
+func (t *PathwayTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
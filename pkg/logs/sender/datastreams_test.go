@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import "testing"
+
+func TestPathwayEncodeDecode(t *testing.T) {
+	p := NewPathway([]string{"direction:out", "topic:my-source", "type:logs"})
+
+	decoded, ok := DecodePathway(p.Encode())
+	if !ok {
+		t.Fatalf("expected DecodePathway to succeed")
+	}
+	if decoded.Hash != p.Hash {
+		t.Errorf("expected hash %d, got %d", p.Hash, decoded.Hash)
+	}
+	if !decoded.StartedAt.Equal(p.StartedAt) {
+		t.Errorf("expected StartedAt %v, got %v", p.StartedAt, decoded.StartedAt)
+	}
+}
+
+func TestDecodePathwayInvalid(t *testing.T) {
+	if _, ok := DecodePathway("not-base64!!"); ok {
+		t.Errorf("expected invalid input to fail decoding")
+	}
+}
+
+func TestPathwayCheckpointChangesHash(t *testing.T) {
+	origin := NewPathway([]string{"direction:out", "topic:a", "type:logs"})
+	next := origin.Checkpoint([]string{"direction:in", "topic:a", "type:logs"})
+
+	if next.Hash == origin.Hash {
+		t.Errorf("expected checkpoint to change the pathway hash")
+	}
+	if !next.StartedAt.Equal(origin.StartedAt) {
+		t.Errorf("expected checkpoint to preserve the origin timestamp")
+	}
+}
+
+func TestPathwayTrackerEvictsOldest(t *testing.T) {
+	tracker := NewPathwayTracker("test-service", 2)
+
+	p1 := NewPathway([]string{"a"})
+	p2 := NewPathway([]string{"b"})
+	p3 := NewPathway([]string{"c"})
+
+	tracker.Observe(p1)
+	tracker.Observe(p2)
+	tracker.Observe(p3)
+
+	if got := tracker.Len(); got != 2 {
+		t.Errorf("expected 2 tracked pathways after eviction, got %d", got)
+	}
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Batch is a group of log messages sent together to a single destination,
+// carrying the DSM pathway checkpoint for the "out" edge this send
+// represents.
+type Batch struct {
+	Payload []byte
+	Pathway Pathway
+}
+
+// Destination sends a Batch to a single logs intake endpoint, stamping it
+// with its DSM pathway checkpoint along the way.
+type Destination interface {
+	Send(batch Batch) error
+}
+
+// HTTPDestination sends batches to an HTTP(S) logs intake endpoint,
+// propagating the DSM pathway via PathwayContextHeader the same way
+// dd-trace-go's Kafka integration propagates dd-pathway-ctx.
+type HTTPDestination struct {
+	client           *http.Client
+	endpoint         string
+	edgeTags         []string
+	tracker          *PathwayTracker
+	codec            CompressionCodec
+	compressionLevel int
+}
+
+// NewHTTPDestination returns an HTTPDestination posting batches to endpoint.
+// tracker may be nil, in which case checkpointed pathways aren't observed
+// for telemetry.
+func NewHTTPDestination(client *http.Client, endpoint string, edgeTags []string, tracker *PathwayTracker) *HTTPDestination {
+	return &HTTPDestination{client: client, endpoint: endpoint, edgeTags: edgeTags, tracker: tracker}
+}
+
+// WithCompression makes d compress every batch's payload through codec at
+// level before sending it, advertising the codec's Content-Encoding on the
+// request. codec may be nil to send payloads uncompressed, the default.
+func (d *HTTPDestination) WithCompression(codec CompressionCodec, level int) *HTTPDestination {
+	d.codec = codec
+	d.compressionLevel = level
+	return d
+}
+
+// Send POSTs batch.Payload to the destination's endpoint, checkpointing
+// batch.Pathway across this send's edge and carrying it in
+// PathwayContextHeader. If a CompressionCodec was set via WithCompression,
+// the payload is compressed and the negotiated Content-Encoding is set on
+// the request.
+func (d *HTTPDestination) Send(batch Batch) error {
+	payload := batch.Payload
+	var contentEncoding string
+	if d.codec != nil {
+		var buf bytes.Buffer
+		enc, encoding, err := d.codec.NewEncoder(&buf, d.compressionLevel)
+		if err != nil {
+			return fmt.Errorf("could not create compression encoder: %w", err)
+		}
+		if _, err := enc.Write(batch.Payload); err != nil {
+			return fmt.Errorf("could not compress batch: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("could not finalize compressed batch: %w", err)
+		}
+		payload = buf.Bytes()
+		contentEncoding = encoding
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	checkpointed := batch.Pathway.Checkpoint(d.edgeTags)
+	req.Header.Set(PathwayContextHeader, checkpointed.Encode())
+	if d.tracker != nil {
+		d.tracker.Observe(checkpointed)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send batch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, d.endpoint)
+	}
+	return nil
+}
+
+// TCPDestination sends batches over a raw TCP connection. Since a TCP
+// stream has no header section, the DSM pathway is carried as a
+// "key: value\n" line prepended to the payload, the closest analogue to an
+// HTTP header this transport supports.
+type TCPDestination struct {
+	conn     net.Conn
+	edgeTags []string
+	tracker  *PathwayTracker
+}
+
+// NewTCPDestination returns a TCPDestination writing batches to conn.
+// tracker may be nil, in which case checkpointed pathways aren't observed
+// for telemetry.
+func NewTCPDestination(conn net.Conn, edgeTags []string, tracker *PathwayTracker) *TCPDestination {
+	return &TCPDestination{conn: conn, edgeTags: edgeTags, tracker: tracker}
+}
+
+// Send writes batch.Payload to the connection, checkpointing batch.Pathway
+// across this send's edge and prepending it as a PathwayContextHeader line.
+func (d *TCPDestination) Send(batch Batch) error {
+	checkpointed := batch.Pathway.Checkpoint(d.edgeTags)
+	if d.tracker != nil {
+		d.tracker.Observe(checkpointed)
+	}
+
+	frame := append([]byte(fmt.Sprintf("%s: %s\n", PathwayContextHeader, checkpointed.Encode())), batch.Payload...)
+	if _, err := d.conn.Write(frame); err != nil {
+		return fmt.Errorf("could not write batch: %w", err)
+	}
+	return nil
+}
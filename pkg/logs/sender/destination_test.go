@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gzipTestCodec is a minimal CompressionCodec used to exercise
+// HTTPDestination.WithCompression without depending on the real codecs in
+// comp/logs/agent/config.
+type gzipTestCodec struct{}
+
+func (gzipTestCodec) NewEncoder(w io.Writer, level int) (io.WriteCloser, string, error) {
+	enc, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, "", err
+	}
+	return enc, "gzip", nil
+}
+
+func TestHTTPDestinationSetsPathwayHeader(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(PathwayContextHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tracker := NewPathwayTracker("test-service", 10)
+	dest := NewHTTPDestination(server.Client(), server.URL, []string{"direction:out", "type:logs"}, tracker)
+
+	origin := NewPathway([]string{"direction:in", "type:logs"})
+	if err := dest.Send(Batch{Payload: []byte("hello"), Pathway: origin}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotHeader == "" {
+		t.Fatalf("expected %s to be set on the outgoing request", PathwayContextHeader)
+	}
+	decoded, ok := DecodePathway(gotHeader)
+	if !ok {
+		t.Fatalf("expected header value to decode as a pathway")
+	}
+	if decoded.Hash == origin.Hash {
+		t.Errorf("expected the header pathway to be checkpointed past the origin")
+	}
+	if tracker.Len() != 1 {
+		t.Errorf("expected the checkpointed pathway to be observed by the tracker")
+	}
+}
+
+func TestHTTPDestinationCompressesPayloadWhenCodecSet(t *testing.T) {
+	var gotEncoding string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dest := NewHTTPDestination(server.Client(), server.URL, []string{"direction:out", "type:logs"}, nil).
+		WithCompression(gzipTestCodec{}, gzip.BestSpeed)
+
+	payload := []byte("hello world")
+	if err := dest.Send(Batch{Payload: payload, Pathway: NewPathway(nil)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotEncoding != "gzip" {
+		t.Fatalf("expected Content-Encoding to be gzip, got %q", gotEncoding)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gotBody))
+	if err != nil {
+		t.Fatalf("expected a valid gzip body: %v", err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error decoding body: %v", err)
+	}
+	if string(decoded) != string(payload) {
+		t.Errorf("expected decoded payload %q, got %q", payload, decoded)
+	}
+}
+
+func TestHTTPDestinationSendsUncompressedWithoutCodec(t *testing.T) {
+	var gotEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dest := NewHTTPDestination(server.Client(), server.URL, []string{"direction:out", "type:logs"}, nil)
+	if err := dest.Send(Batch{Payload: []byte("hello"), Pathway: NewPathway(nil)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotEncoding != "" {
+		t.Errorf("expected no Content-Encoding header, got %q", gotEncoding)
+	}
+}
+
+func TestTCPDestinationPrependsPathwayFrame(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	tracker := NewPathwayTracker("test-service", 10)
+	dest := NewTCPDestination(client, []string{"direction:out", "type:logs"}, tracker)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dest.Send(Batch{Payload: []byte("payload")})
+	}()
+
+	buf := make([]byte, 256)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	got := string(buf[:n])
+	if want := PathwayContextHeader + ": "; len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("expected frame to start with %q, got %q", want, got)
+	}
+	if tracker.Len() != 1 {
+		t.Errorf("expected the checkpointed pathway to be observed by the tracker")
+	}
+}
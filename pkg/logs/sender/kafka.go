@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+// KafkaHeader is a single record header, shaped to match the header types
+// exposed by the common Kafka client libraries (e.g. sarama.RecordHeader,
+// kafka-go's Header), so callers can adapt either without copying.
+type KafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+// PathwayFromKafkaHeaders looks for PathwayContextHeader among headers and
+// decodes it, mirroring how dd-trace-go's Kafka consumer integration reads
+// dd-pathway-ctx off an inbound message to resume its DSM pathway.
+func PathwayFromKafkaHeaders(headers []KafkaHeader) (Pathway, bool) {
+	for _, h := range headers {
+		if h.Key != PathwayContextHeader {
+			continue
+		}
+		return DecodePathway(string(h.Value))
+	}
+	return Pathway{}, false
+}
+
+// BatchFromKafkaMessage builds the Batch for a message consumed off Kafka,
+// resuming its pathway from headers if present, or starting a fresh one
+// otherwise, then checkpointing it across the "in" edge described by
+// edgeTags.
+func BatchFromKafkaMessage(headers []KafkaHeader, payload []byte, edgeTags []string) Batch {
+	pathway, ok := PathwayFromKafkaHeaders(headers)
+	if !ok {
+		pathway = NewPathway(edgeTags)
+	}
+	return Batch{
+		Payload: payload,
+		Pathway: pathway.Checkpoint(edgeTags),
+	}
+}
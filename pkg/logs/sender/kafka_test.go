@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import "testing"
+
+func TestPathwayFromKafkaHeadersDecodesExistingHeader(t *testing.T) {
+	origin := NewPathway([]string{"direction:out", "topic:my-topic", "type:logs"})
+	headers := []KafkaHeader{
+		{Key: "other-header", Value: []byte("ignored")},
+		{Key: PathwayContextHeader, Value: []byte(origin.Encode())},
+	}
+
+	decoded, ok := PathwayFromKafkaHeaders(headers)
+	if !ok {
+		t.Fatalf("expected a pathway to be decoded")
+	}
+	if decoded.Hash != origin.Hash {
+		t.Errorf("expected hash %d, got %d", origin.Hash, decoded.Hash)
+	}
+}
+
+func TestPathwayFromKafkaHeadersMissingHeader(t *testing.T) {
+	if _, ok := PathwayFromKafkaHeaders([]KafkaHeader{{Key: "other-header", Value: []byte("x")}}); ok {
+		t.Errorf("expected no pathway to be decoded without %s", PathwayContextHeader)
+	}
+}
+
+func TestBatchFromKafkaMessageResumesPathway(t *testing.T) {
+	origin := NewPathway([]string{"direction:out", "topic:my-topic", "type:logs"})
+	headers := []KafkaHeader{{Key: PathwayContextHeader, Value: []byte(origin.Encode())}}
+
+	batch := BatchFromKafkaMessage(headers, []byte("payload"), []string{"direction:in", "topic:my-topic", "type:logs"})
+
+	if !batch.Pathway.StartedAt.Equal(origin.StartedAt) {
+		t.Errorf("expected the batch pathway to preserve the origin's StartedAt")
+	}
+	if batch.Pathway.Hash == origin.Hash {
+		t.Errorf("expected the batch pathway to be checkpointed past the origin")
+	}
+}
+
+func TestBatchFromKafkaMessageStartsNewPathwayWithoutHeader(t *testing.T) {
+	batch := BatchFromKafkaMessage(nil, []byte("payload"), []string{"direction:in", "topic:my-topic", "type:logs"})
+
+	if batch.Payload == nil {
+		t.Errorf("expected payload to be preserved")
+	}
+	if batch.Pathway.Hash == 0 {
+		t.Errorf("expected a non-zero pathway hash")
+	}
+}
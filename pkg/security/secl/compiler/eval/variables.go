@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package eval holds the SECL expression evaluator
+package eval
+
+// Context is the per-event state a scoped variable is resolved against.
+// Event holds the concrete event being evaluated; scoped variable getters
+// type-assert it to whatever sub-context they need.
+type Context struct {
+	Event interface{}
+}
+
+// VariableOpts holds the options that can be passed when declaring a SECL
+// variable. It's currently unused by scoped variables but kept so their
+// constructors share a signature with the rest of the eval package's
+// variable declarations.
+type VariableOpts struct{}
+
+// SECLVariable describes a variable that can be referenced from a SECL
+// expression.
+type SECLVariable interface {
+	// GetEvaluator returns the function resolving this variable's value
+	// against a given Context.
+	GetEvaluator() interface{}
+}
+
+// ScopedIntVariable is a SECLVariable whose value is an int computed from
+// the event in the evaluation Context, such as process.pid.
+type ScopedIntVariable struct {
+	getter func(ctx *Context) (int, bool)
+	opts   *VariableOpts
+}
+
+// NewScopedIntVariable returns a ScopedIntVariable resolved by getter.
+func NewScopedIntVariable(getter func(ctx *Context) (int, bool), opts *VariableOpts) *ScopedIntVariable {
+	return &ScopedIntVariable{getter: getter, opts: opts}
+}
+
+// GetEvaluator returns the getter resolving this variable's value.
+func (v *ScopedIntVariable) GetEvaluator() interface{} {
+	return v.getter
+}
+
+// ScopedStringVariable is a SECLVariable whose value is a string computed
+// from the event in the evaluation Context, such as container.id.
+type ScopedStringVariable struct {
+	getter func(ctx *Context) (string, bool)
+	opts   *VariableOpts
+}
+
+// NewScopedStringVariable returns a ScopedStringVariable resolved by getter.
+func NewScopedStringVariable(getter func(ctx *Context) (string, bool), opts *VariableOpts) *ScopedStringVariable {
+	return &ScopedStringVariable{getter: getter, opts: opts}
+}
+
+// GetEvaluator returns the getter resolving this variable's value.
+func (v *ScopedStringVariable) GetEvaluator() interface{} {
+	return v.getter
+}
+
+// ScopedIntArrayVariable is a SECLVariable whose value is a []int computed
+// from the event in the evaluation Context, such as process.ancestors.pid.
+type ScopedIntArrayVariable struct {
+	getter func(ctx *Context) ([]int, bool)
+	opts   *VariableOpts
+}
+
+// NewScopedIntArrayVariable returns a ScopedIntArrayVariable resolved by getter.
+func NewScopedIntArrayVariable(getter func(ctx *Context) ([]int, bool), opts *VariableOpts) *ScopedIntArrayVariable {
+	return &ScopedIntArrayVariable{getter: getter, opts: opts}
+}
+
+// GetEvaluator returns the getter resolving this variable's value.
+func (v *ScopedIntArrayVariable) GetEvaluator() interface{} {
+	return v.getter
+}
+
+// ScopedStringArrayVariable is a SECLVariable whose value is a []string
+// computed from the event in the evaluation Context, such as process.envs.
+type ScopedStringArrayVariable struct {
+	getter func(ctx *Context) ([]string, bool)
+	opts   *VariableOpts
+}
+
+// NewScopedStringArrayVariable returns a ScopedStringArrayVariable resolved by getter.
+func NewScopedStringArrayVariable(getter func(ctx *Context) ([]string, bool), opts *VariableOpts) *ScopedStringArrayVariable {
+	return &ScopedStringArrayVariable{getter: getter, opts: opts}
+}
+
+// GetEvaluator returns the getter resolving this variable's value.
+func (v *ScopedStringArrayVariable) GetEvaluator() interface{} {
+	return v.getter
+}
@@ -20,5 +20,74 @@ var (
 			}
 			return int(pc.Process.Pid), true
 		}, nil),
+
+		"container.id": eval.NewScopedStringVariable(func(ctx *eval.Context) (string, bool) {
+			cc := ctx.Event.(*Event).ContainerContext
+			if cc == nil {
+				return "", false
+			}
+			return cc.ID, true
+		}, nil),
+
+		"container.created_at": eval.NewScopedIntVariable(func(ctx *eval.Context) (int, bool) {
+			cc := ctx.Event.(*Event).ContainerContext
+			if cc == nil {
+				return 0, false
+			}
+			return int(cc.CreatedAt), true
+		}, nil),
+
+		"cgroup.id": eval.NewScopedStringVariable(func(ctx *eval.Context) (string, bool) {
+			cgc := ctx.Event.(*Event).CGroupContext
+			if cgc == nil {
+				return "", false
+			}
+			return string(cgc.CGroupID), true
+		}, nil),
+
+		"cgroup.path": eval.NewScopedStringVariable(func(ctx *eval.Context) (string, bool) {
+			cgc := ctx.Event.(*Event).CGroupContext
+			if cgc == nil {
+				return "", false
+			}
+			return cgc.CGroupPath, true
+		}, nil),
+
+		"user.uid": eval.NewScopedIntVariable(func(ctx *eval.Context) (int, bool) {
+			pc := ctx.Event.(*Event).ProcessContext
+			if pc == nil {
+				return 0, false
+			}
+			return int(pc.Process.Credentials.UID), true
+		}, nil),
+
+		"user.name": eval.NewScopedStringVariable(func(ctx *eval.Context) (string, bool) {
+			pc := ctx.Event.(*Event).ProcessContext
+			if pc == nil {
+				return "", false
+			}
+			return pc.Process.Credentials.User, true
+		}, nil),
+
+		"process.ancestors.pid": eval.NewScopedIntArrayVariable(func(ctx *eval.Context) ([]int, bool) {
+			pc := ctx.Event.(*Event).ProcessContext
+			if pc == nil || pc.Ancestor == nil {
+				return nil, false
+			}
+
+			var pids []int
+			for ancestor := pc.Ancestor; ancestor != nil; ancestor = ancestor.Ancestor {
+				pids = append(pids, int(ancestor.Process.Pid))
+			}
+			return pids, true
+		}, nil),
+
+		"process.envs": eval.NewScopedStringArrayVariable(func(ctx *eval.Context) ([]string, bool) {
+			pc := ctx.Event.(*Event).ProcessContext
+			if pc == nil || pc.Process.EnvsEntry == nil {
+				return nil, false
+			}
+			return pc.Process.EnvsEntry.Values, true
+		}, nil),
 	}
 )
@@ -9,10 +9,13 @@
 package activitytree
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/DataDog/datadog-agent/pkg/security/secl/containerutils"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
 )
@@ -54,10 +57,109 @@ func (cc *callbackContext) replaceBy(start, end int, replaceBy string) {
 	cc.path = b.String()
 }
 
-// NewPathsReducer returns a new PathsReducer
-func NewPathsReducer() *PathsReducer {
+// UserPatternReducerConfig is the YAML-serializable description of an
+// operator-supplied path reducer, as loaded from the activity tree config
+// (e.g. to cover systemd "machine-*.scope" cgroups, snap revision
+// directories, docker overlay2 UUIDs, or Nomad alloc IDs not covered by the
+// built-in patterns).
+type UserPatternReducerConfig struct {
+	// Pattern is the regular expression evaluated against the path.
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Hint is an optional literal substring used as a fast-path filter: the
+	// pattern is only evaluated on paths containing Hint.
+	Hint string `yaml:"hint,omitempty" json:"hint,omitempty"`
+	// Filesystem, when set, restricts the pattern to file events on this
+	// filesystem (e.g. "sysfs"), mirroring the built-in reducers' PreCheck.
+	Filesystem string `yaml:"filesystem,omitempty" json:"filesystem,omitempty"`
+	// ReplaceGroup selects the capture group replaced in the path, written
+	// as "${N}" (e.g. "${1}"). Defaults to "${0}", the whole match.
+	ReplaceGroup string `yaml:"replace_group,omitempty" json:"replace_group,omitempty"`
+	// ReplaceWith is the literal string substituted in place of
+	// ReplaceGroup, e.g. "*".
+	ReplaceWith string `yaml:"replace_with" json:"replace_with"`
+}
+
+// replaceGroupPattern matches a ReplaceGroup reference like "${1}".
+var replaceGroupPattern = regexp.MustCompile(`^\$\{(\d+)\}$`)
+
+// newUserPatternReducer compiles a single UserPatternReducerConfig into a
+// PatternReducer, ready to be appended to PathsReducer.patterns.
+func newUserPatternReducer(cfg UserPatternReducerConfig) (PatternReducer, error) {
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return PatternReducer{}, fmt.Errorf("invalid user path reducer pattern %q: %w", cfg.Pattern, err)
+	}
+
+	group := 0
+	if cfg.ReplaceGroup != "" {
+		m := replaceGroupPattern.FindStringSubmatch(cfg.ReplaceGroup)
+		if m == nil {
+			return PatternReducer{}, fmt.Errorf("invalid replace_group %q, expected a \"${N}\" reference", cfg.ReplaceGroup)
+		}
+		group, err = strconv.Atoi(m[1])
+		if err != nil {
+			return PatternReducer{}, fmt.Errorf("invalid replace_group %q: %w", cfg.ReplaceGroup, err)
+		}
+		if group > pattern.NumSubexp() {
+			return PatternReducer{}, fmt.Errorf("replace_group %q refers to a capture group pattern %q doesn't have", cfg.ReplaceGroup, cfg.Pattern)
+		}
+	}
+
+	var preCheck func(path string, fileEvent *model.FileEvent) bool
+	if cfg.Filesystem != "" {
+		preCheck = func(_ string, fileEvent *model.FileEvent) bool {
+			return fileEvent != nil && fileEvent.Filesystem == cfg.Filesystem
+		}
+	}
+
+	replaceWith := cfg.ReplaceWith
+	return PatternReducer{
+		Pattern:  pattern,
+		Hint:     cfg.Hint,
+		PreCheck: preCheck,
+		Callback: func(ctx *callbackContext) {
+			start, end := ctx.getGroup(group)
+			ctx.replaceBy(start, end, replaceWith)
+		},
+	}, nil
+}
+
+// LoadUserPatternReducers compiles a list of operator-supplied
+// UserPatternReducerConfig entries into PatternReducers, for use with
+// NewPathsReducer.
+func LoadUserPatternReducers(configs []UserPatternReducerConfig) ([]PatternReducer, error) {
+	patterns := make([]PatternReducer, 0, len(configs))
+	for _, cfg := range configs {
+		pattern, err := newUserPatternReducer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// LoadUserPatternReducersFromYAML parses a YAML document holding a list of
+// UserPatternReducerConfig entries (the shape of the activity_tree
+// path_reducers config key) and compiles them into PatternReducers, ready to
+// be passed to NewPathsReducer. It's the entry point operators' config goes
+// through; LoadUserPatternReducers is for callers that already have the
+// config unmarshaled (e.g. tests).
+func LoadUserPatternReducersFromYAML(data []byte) ([]PatternReducer, error) {
+	var configs []UserPatternReducerConfig
+	if err := yaml.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing user path reducers: %w", err)
+	}
+	return LoadUserPatternReducers(configs)
+}
+
+// NewPathsReducer returns a new PathsReducer. userPatterns, if any, are
+// evaluated after the built-in patterns, in the order given.
+func NewPathsReducer(userPatterns ...PatternReducer) *PathsReducer {
+	patterns := getPathsReducerPatterns()
+	patterns = append(patterns, userPatterns...)
 	return &PathsReducer{
-		patterns: getPathsReducerPatterns(),
+		patterns: patterns,
 	}
 }
 
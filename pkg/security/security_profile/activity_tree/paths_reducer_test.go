@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+
+package activitytree
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+func TestLoadUserPatternReducers(t *testing.T) {
+	configs := []UserPatternReducerConfig{
+		{
+			Pattern:      `machine-([^/]*)\.scope`,
+			Hint:         "machine-",
+			ReplaceGroup: "${1}",
+			ReplaceWith:  "*",
+		},
+		{
+			Pattern:     `/snap/[^/]+/(\d+)/`,
+			Hint:        "/snap/",
+			ReplaceWith: "*",
+		},
+	}
+
+	userPatterns, err := LoadUserPatternReducers(configs)
+	require.NoError(t, err)
+	require.Len(t, userPatterns, 2)
+
+	reducer := NewPathsReducer(userPatterns...)
+	assert.Equal(t, "/sys/fs/cgroup/machine-*.scope/", reducer.ReducePath("/sys/fs/cgroup/machine-qemu-1-foo.scope/", nil, nil))
+}
+
+func TestLoadUserPatternReducersFromYAML(t *testing.T) {
+	doc := `
+- pattern: 'machine-([^/]*)\.scope'
+  hint: 'machine-'
+  replace_group: "${1}"
+  replace_with: '*'
+`
+	userPatterns, err := LoadUserPatternReducersFromYAML([]byte(doc))
+	require.NoError(t, err)
+	require.Len(t, userPatterns, 1)
+
+	reducer := NewPathsReducer(userPatterns...)
+	assert.Equal(t, "/sys/fs/cgroup/machine-*.scope/", reducer.ReducePath("/sys/fs/cgroup/machine-qemu-1-foo.scope/", nil, nil))
+}
+
+func TestLoadUserPatternReducersFromYAMLInvalidYAML(t *testing.T) {
+	_, err := LoadUserPatternReducersFromYAML([]byte("not: [valid"))
+	assert.Error(t, err)
+}
+
+func TestLoadUserPatternReducersInvalidPattern(t *testing.T) {
+	_, err := LoadUserPatternReducers([]UserPatternReducerConfig{
+		{Pattern: `(unterminated`, ReplaceWith: "*"},
+	})
+	assert.Error(t, err)
+}
+
+func TestLoadUserPatternReducersInvalidReplaceGroup(t *testing.T) {
+	_, err := LoadUserPatternReducers([]UserPatternReducerConfig{
+		{Pattern: `foo`, ReplaceGroup: "${1}", ReplaceWith: "*"},
+	})
+	assert.Error(t, err)
+}
+
+func BenchmarkReducePath(b *testing.B) {
+	reducer := NewPathsReducer()
+	fileEvent := &model.FileEvent{Filesystem: "sysfs"}
+	path := "/sys/fs/cgroup/kubepods-burstable-pod123.slice/cri-containerd-abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789.scope/"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reducer.ReducePath(path, fileEvent, nil)
+	}
+}
+
+func BenchmarkReducePathWithUserPatterns(b *testing.B) {
+	for _, n := range []int{1, 10, 50} {
+		b.Run(fmt.Sprintf("user-patterns-%d", n), func(b *testing.B) {
+			configs := make([]UserPatternReducerConfig, 0, n)
+			for i := 0; i < n; i++ {
+				configs = append(configs, UserPatternReducerConfig{
+					Pattern:     `/nomad/alloc/` + strconv.Itoa(i) + `/([^/]+)/`,
+					Hint:        "/nomad/alloc/",
+					ReplaceWith: "*",
+				})
+			}
+			userPatterns, err := LoadUserPatternReducers(configs)
+			require.NoError(b, err)
+			reducer := NewPathsReducer(userPatterns...)
+			path := "/nomad/alloc/0/deadbeef-dead-beef-dead-beefdeadbeef/"
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reducer.ReducePath(path, nil, nil)
+			}
+		})
+	}
+}
@@ -115,6 +115,12 @@ func sqsMessageCarrier(event events.SQSMessage) (tracer.TextMapReader, error) {
 		return sqsMessageAttrCarrier(attr)
 	}
 
+	// Check if this is an SQS message carrying W3C Trace Context attributes
+	// directly, rather than Datadog's _datadog envelope
+	if attr, ok := event.MessageAttributes[w3cTraceparentHeader]; ok {
+		return sqsMessageW3CAttrCarrier(attr, event.MessageAttributes[w3cTracestateHeader])
+	}
+
 	// Check if this is an EventBridge event sent through SQS
 	var eventBridgeEvent events.EventBridgeEvent
 	if err := json.Unmarshal([]byte(event.Body), &eventBridgeEvent); err == nil {
@@ -153,6 +159,39 @@ func sqsMessageAttrCarrier(attr events.SQSMessageAttribute) (tracer.TextMapReade
 	return carrier, nil
 }
 
+// sqsMessageAttrStringValue returns the plain string value of an SQS message
+// attribute, decoding it first if it was delivered as Binary (as happens
+// when an SNS message with raw message delivery enabled is fanned out to
+// SQS).
+func sqsMessageAttrStringValue(attr events.SQSMessageAttribute) (string, error) {
+	switch attr.DataType {
+	case "String":
+		if attr.StringValue == nil {
+			return "", errorStringNotFound
+		}
+		return *attr.StringValue, nil
+	case "Binary":
+		return string(attr.BinaryValue), nil
+	default:
+		return "", errorUnsupportedDataType
+	}
+}
+
+// sqsMessageW3CAttrCarrier returns the tracer.TextMapReader used to extract
+// W3C Trace Context from an SQS message's traceparent (and, if present,
+// tracestate) message attributes.
+func sqsMessageW3CAttrCarrier(traceparentAttr, tracestateAttr events.SQSMessageAttribute) (tracer.TextMapReader, error) {
+	traceparent, err := sqsMessageAttrStringValue(traceparentAttr)
+	if err != nil {
+		return nil, err
+	}
+	carrier := tracer.TextMapCarrier{w3cTraceparentHeader: traceparent}
+	if tracestate, err := sqsMessageAttrStringValue(tracestateAttr); err == nil {
+		carrier[w3cTracestateHeader] = tracestate
+	}
+	return carrier, nil
+}
+
 // snsBody is used to  unmarshal only required fields on events.SNSEntity
 // types.
 type snsBody struct {
@@ -184,47 +223,81 @@ func snsEntityCarrier(event events.SNSEntity) (tracer.TextMapReader, error) {
 	}
 
 	// If not, check if this is a regular SNS message with Datadog trace information
-	msgAttrs, ok := event.MessageAttributes[datadogTraceHeader]
+	if _, ok := event.MessageAttributes[datadogTraceHeader]; ok {
+		payload, err := snsAttrStringValue(event.MessageAttributes, datadogTraceHeader)
+		if err != nil {
+			return nil, err
+		}
+		var carrier tracer.TextMapCarrier
+		if err := json.Unmarshal([]byte(payload), &carrier); err != nil {
+			return nil, fmt.Errorf("Error unmarshaling the decoded binary: %w", err)
+		}
+		return carrier, nil
+	}
+
+	// Or a regular SNS message carrying W3C Trace Context attributes directly
+	if _, ok := event.MessageAttributes[w3cTraceparentHeader]; ok {
+		return snsW3CAttrCarrier(event.MessageAttributes)
+	}
+
+	return nil, errorNoDDContextFound
+}
+
+// snsAttrStringValue decodes the named SNS message attribute's value, as
+// delivered in the {"Type": ..., "Value": ...} shape SNS uses for both
+// String and Binary attribute types.
+func snsAttrStringValue(msgAttrs map[string]interface{}, name string) (string, error) {
+	attr, ok := msgAttrs[name]
 	if !ok {
-		return nil, errorNoDDContextFound
+		return "", errorNoDDContextFound
 	}
-	mapAttrs, ok := msgAttrs.(map[string]interface{})
+	mapAttr, ok := attr.(map[string]interface{})
 	if !ok {
-		return nil, errorUnsupportedPayloadType
+		return "", errorUnsupportedPayloadType
 	}
 
-	typ, ok := mapAttrs["Type"].(string)
+	typ, ok := mapAttr["Type"].(string)
 	if !ok {
-		return nil, errorUnsupportedTypeType
+		return "", errorUnsupportedTypeType
 	}
-	val, ok := mapAttrs["Value"].(string)
+	val, ok := mapAttr["Value"].(string)
 	if !ok {
-		return nil, errorUnsupportedValueType
+		return "", errorUnsupportedValueType
 	}
 
-	var bytes []byte
-	var err error
 	switch typ {
 	case "Binary":
-		bytes, err = base64.StdEncoding.DecodeString(val)
+		decoded, err := base64.StdEncoding.DecodeString(val)
 		if err != nil {
-			return nil, fmt.Errorf("Error decoding binary: %w", err)
+			return "", fmt.Errorf("Error decoding binary: %w", err)
 		}
+		return string(decoded), nil
 	case "String":
-		bytes = []byte(val)
+		return val, nil
 	default:
-		return nil, errorUnsupportedTypeValue
+		return "", errorUnsupportedTypeValue
 	}
+}
 
-	var carrier tracer.TextMapCarrier
-	if err = json.Unmarshal(bytes, &carrier); err != nil {
-		return nil, fmt.Errorf("Error unmarshaling the decoded binary: %w", err)
+// snsW3CAttrCarrier returns the tracer.TextMapReader used to extract W3C
+// Trace Context from an SNS message's traceparent (and, if present,
+// tracestate) message attributes.
+func snsW3CAttrCarrier(msgAttrs map[string]interface{}) (tracer.TextMapReader, error) {
+	traceparent, err := snsAttrStringValue(msgAttrs, w3cTraceparentHeader)
+	if err != nil {
+		return nil, err
+	}
+	carrier := tracer.TextMapCarrier{w3cTraceparentHeader: traceparent}
+	if tracestate, err := snsAttrStringValue(msgAttrs, w3cTracestateHeader); err == nil {
+		carrier[w3cTracestateHeader] = tracestate
 	}
 	return carrier, nil
 }
 
 // eventBridgeCarrier returns the tracer.TextMapReader used to extract trace
-// context from the Detail field of an events.EventBridgeEvent
+// context from the Detail field of an events.EventBridgeEvent. TraceContext
+// is carried through verbatim, so it works for Datadog's headers as well as
+// W3C's traceparent/tracestate.
 func eventBridgeCarrier(event events.EventBridgeEvent) (tracer.TextMapReader, error) {
 	traceContext := event.Detail.TraceContext
 	if len(traceContext) > 0 {
@@ -234,7 +307,9 @@ func eventBridgeCarrier(event events.EventBridgeEvent) (tracer.TextMapReader, er
 }
 
 type invocationPayload struct {
-	Headers tracer.TextMapCarrier `json:"headers"`
+	Headers     tracer.TextMapCarrier `json:"headers"`
+	Traceparent string                `json:"traceparent"`
+	Tracestate  string                `json:"tracestate"`
 }
 
 // rawPayloadCarrier returns the tracer.TextMapReader used to extract trace
@@ -244,11 +319,27 @@ func rawPayloadCarrier(rawPayload []byte) (tracer.TextMapReader, error) {
 	if err := json.Unmarshal(rawPayload, &payload); err != nil {
 		return nil, errorCouldNotUnmarshal
 	}
+	if len(payload.Headers) > 0 {
+		return payload.Headers, nil
+	}
+
+	// Some invocation payloads carry a W3C Trace Context header at the top
+	// level instead of nesting it under "headers".
+	if payload.Traceparent != "" {
+		carrier := tracer.TextMapCarrier{w3cTraceparentHeader: payload.Traceparent}
+		if payload.Tracestate != "" {
+			carrier[w3cTracestateHeader] = payload.Tracestate
+		}
+		return carrier, nil
+	}
+
 	return payload.Headers, nil
 }
 
 // headersCarrier returns the tracer.TextMapReader used to extract trace
-// context from a Headers field of form map[string]string.
+// context from a Headers field of form map[string]string. Both Datadog's
+// headers and W3C's traceparent/tracestate are carried through as ordinary
+// entries, so no special-casing is needed here.
 func headersCarrier(hdrs map[string]string) (tracer.TextMapReader, error) {
 	return tracer.TextMapCarrier(hdrs), nil
 }
@@ -263,6 +354,59 @@ func headersOrMultiheadersCarrier(hdrs map[string]string, multiHdrs map[string][
 	return tracer.HTTPHeadersCarrier(multiHdrs), nil
 }
 
+// kinesisRecordCarrier returns the tracer.TextMapReader used to extract
+// Datadog trace context from the base64-decoded Data payload of a Kinesis
+// stream record, matching the "_datadog" envelope the tracer libraries
+// inject when they publish to a stream.
+func kinesisRecordCarrier(record events.KinesisEventRecord) (tracer.TextMapReader, error) {
+	var body struct {
+		Datadog tracer.TextMapCarrier `json:"_datadog"`
+	}
+	if err := json.Unmarshal(record.Kinesis.Data, &body); err != nil {
+		return nil, errorCouldNotUnmarshal
+	}
+	if len(body.Datadog) == 0 {
+		return nil, errorNoDDContextFound
+	}
+	return body.Datadog, nil
+}
+
+// dynamoDBStreamCarrier returns the tracer.TextMapReader used to extract
+// Datadog trace context from the "_datadog" attribute the tracer libraries
+// inject into a DynamoDB item before writing it, as surfaced in a DynamoDB
+// Streams record's NewImage.
+func dynamoDBStreamCarrier(record events.DynamoDBEventRecord) (tracer.TextMapReader, error) {
+	attr, ok := record.Change.NewImage[datadogTraceHeader]
+	if !ok {
+		return nil, errorNoDDContextFound
+	}
+
+	fields := attr.Map()
+	carrier := make(tracer.TextMapCarrier, len(fields))
+	for key, val := range fields {
+		carrier[key] = val.String()
+	}
+	if len(carrier) == 0 {
+		return nil, errorNoDDContextFound
+	}
+	return carrier, nil
+}
+
+// kafkaRecordCarrier returns the tracer.TextMapReader used to extract
+// Datadog trace context from an MSK (Kafka) record's headers.
+func kafkaRecordCarrier(record events.KafkaRecord) (tracer.TextMapReader, error) {
+	carrier := make(tracer.TextMapCarrier, len(record.Headers))
+	for _, header := range record.Headers {
+		for key, val := range header {
+			carrier[key] = string(val)
+		}
+	}
+	if len(carrier) == 0 {
+		return nil, errorNoDDContextFound
+	}
+	return carrier, nil
+}
+
 // extractTraceContextFromStepFunctionContext extracts the execution ARN, execution redrive count, state name, state
 // entered time, and state retry count and uses them to generate Trace ID and Parent ID. The logic is based on the trace
 // context conversion in Logs To Traces, dd-trace-py, dd-trace-js, etc.
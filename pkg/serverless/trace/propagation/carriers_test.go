@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"testing"
+
+	json "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/DataDog/datadog-agent/pkg/serverless/trigger/events"
+)
+
+func TestKinesisRecordCarrier(t *testing.T) {
+	record := events.KinesisEventRecord{
+		Kinesis: events.KinesisRecord{
+			Data: []byte(`{"_datadog":{"x-datadog-trace-id":"1234","x-datadog-parent-id":"5678"}}`),
+		},
+	}
+
+	carrier, err := kinesisRecordCarrier(record)
+	require.NoError(t, err)
+
+	values := map[string]string{}
+	require.NoError(t, carrier.ForeachKey(func(key, val string) error {
+		values[key] = val
+		return nil
+	}))
+	assert.Equal(t, "1234", values["x-datadog-trace-id"])
+	assert.Equal(t, "5678", values["x-datadog-parent-id"])
+}
+
+func TestKinesisRecordCarrierNoDatadogContext(t *testing.T) {
+	record := events.KinesisEventRecord{
+		Kinesis: events.KinesisRecord{
+			Data: []byte(`{"some":"data"}`),
+		},
+	}
+
+	_, err := kinesisRecordCarrier(record)
+	assert.Equal(t, errorNoDDContextFound, err)
+}
+
+func TestDynamoDBStreamCarrier(t *testing.T) {
+	record := events.DynamoDBEventRecord{
+		Change: events.DynamoDBStreamRecord{
+			NewImage: map[string]events.DynamoDBAttributeValue{
+				"_datadog": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+					"x-datadog-trace-id":  events.NewStringAttribute("1234"),
+					"x-datadog-parent-id": events.NewStringAttribute("5678"),
+				}),
+			},
+		},
+	}
+
+	carrier, err := dynamoDBStreamCarrier(record)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", carrier.(tracer.TextMapCarrier)["x-datadog-trace-id"])
+	assert.Equal(t, "5678", carrier.(tracer.TextMapCarrier)["x-datadog-parent-id"])
+}
+
+func TestDynamoDBStreamCarrierNoDatadogContext(t *testing.T) {
+	record := events.DynamoDBEventRecord{
+		Change: events.DynamoDBStreamRecord{
+			NewImage: map[string]events.DynamoDBAttributeValue{
+				"some-attr": events.NewStringAttribute("value"),
+			},
+		},
+	}
+
+	_, err := dynamoDBStreamCarrier(record)
+	assert.Equal(t, errorNoDDContextFound, err)
+}
+
+func TestKafkaRecordCarrier(t *testing.T) {
+	record := events.KafkaRecord{
+		Headers: []map[string][]byte{
+			{"x-datadog-trace-id": []byte("1234")},
+			{"x-datadog-parent-id": []byte("5678")},
+		},
+	}
+
+	carrier, err := kafkaRecordCarrier(record)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", carrier.(tracer.TextMapCarrier)["x-datadog-trace-id"])
+	assert.Equal(t, "5678", carrier.(tracer.TextMapCarrier)["x-datadog-parent-id"])
+}
+
+func TestKafkaRecordCarrierNoHeaders(t *testing.T) {
+	record := events.KafkaRecord{}
+
+	_, err := kafkaRecordCarrier(record)
+	assert.Equal(t, errorNoDDContextFound, err)
+}
+
+func stringPtr(s string) *string { return &s }
+
+func TestSQSMessageCarrierW3C(t *testing.T) {
+	event := events.SQSMessage{
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			"traceparent": {DataType: "String", StringValue: stringPtr("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+			"tracestate":  {DataType: "String", StringValue: stringPtr("dd=s:2")},
+		},
+	}
+
+	carrier, err := sqsMessageCarrier(event)
+	require.NoError(t, err)
+
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestSQSMessageW3CAttrCarrierMissingTracestate(t *testing.T) {
+	carrier, err := sqsMessageW3CAttrCarrier(
+		events.SQSMessageAttribute{DataType: "String", StringValue: stringPtr("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+		events.SQSMessageAttribute{},
+	)
+	require.NoError(t, err)
+
+	values := map[string]string{}
+	require.NoError(t, carrier.ForeachKey(func(key, val string) error {
+		values[key] = val
+		return nil
+	}))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", values["traceparent"])
+	_, hasTracestate := values["tracestate"]
+	assert.False(t, hasTracestate)
+}
+
+func TestSNSEntityCarrierW3C(t *testing.T) {
+	event := events.SNSEntity{
+		MessageAttributes: map[string]interface{}{
+			"traceparent": map[string]interface{}{"Type": "String", "Value": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+	}
+
+	carrier, err := snsEntityCarrier(event)
+	require.NoError(t, err)
+
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestEventBridgeCarrierW3C(t *testing.T) {
+	var event events.EventBridgeEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"detail":{"TraceContext":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}}`), &event))
+
+	carrier, err := eventBridgeCarrier(event)
+	require.NoError(t, err)
+
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestRawPayloadCarrierW3CTopLevel(t *testing.T) {
+	carrier, err := rawPayloadCarrier([]byte(`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01","tracestate":"dd=s:2"}`))
+	require.NoError(t, err)
+
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestRawPayloadCarrierPrefersNestedHeaders(t *testing.T) {
+	carrier, err := rawPayloadCarrier([]byte(`{"headers":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},"traceparent":"00-00000000000000000000000000000001-00f067aa0ba902b7-01"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", carrier.(tracer.TextMapCarrier)["traceparent"])
+}
+
+func TestHeadersCarrierW3C(t *testing.T) {
+	carrier, err := headersCarrier(map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	require.NoError(t, err)
+
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
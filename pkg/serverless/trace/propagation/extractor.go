@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// TraceContext holds the trace and span identifiers extracted from an
+// incoming Lambda event, regardless of which wire format (Datadog, AWS
+// X-Ray, Step Functions, W3C Trace Context) they were carried in.
+type TraceContext struct {
+	TraceID uint64
+	// TraceIDUpper64Hex holds the upper 64 bits of a 128-bit trace ID, hex
+	// encoded. It's left empty by formats that only carry a 64-bit trace ID.
+	TraceIDUpper64Hex string
+	ParentID          uint64
+	SamplingPriority  sampler.SamplingPriority
+}
+
+// Style identifies a propagation format an Extractor can read a TraceContext
+// from.
+type Style string
+
+const (
+	// StyleDatadog covers the formats this package already understood before
+	// W3C support was added: the _datadog envelope, x-datadog-* headers, the
+	// AWSTraceHeader convention, and Step Functions context objects.
+	StyleDatadog Style = "datadog"
+	// StyleW3C is the W3C Trace Context format (traceparent/tracestate).
+	StyleW3C Style = "tracecontext"
+)
+
+// defaultPrecedence mirrors dd-trace-go's default DD_TRACE_PROPAGATION_STYLE
+// ordering: Datadog's own format wins when a carrier holds both.
+var defaultPrecedence = []Style{StyleDatadog, StyleW3C}
+
+// Extractor pulls a TraceContext out of a carrier, trying each of its
+// configured Styles in order and returning the first one that successfully
+// extracts a context.
+type Extractor struct {
+	precedence []Style
+}
+
+// ExtractorOption configures an Extractor returned by NewExtractor.
+type ExtractorOption func(*Extractor)
+
+// WithPrecedence sets the order in which an Extractor tries propagation
+// styles. A style left out of styles is never attempted.
+func WithPrecedence(styles ...Style) ExtractorOption {
+	return func(e *Extractor) {
+		e.precedence = styles
+	}
+}
+
+// NewExtractor builds an Extractor that tries Datadog's format before W3C's,
+// matching dd-trace-go's default multi-style propagation order. Pass
+// WithPrecedence to change it, e.g. to prefer W3C or to only accept one
+// style.
+func NewExtractor(opts ...ExtractorOption) *Extractor {
+	e := &Extractor{precedence: defaultPrecedence}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Extract tries carrier against each of the Extractor's configured styles in
+// order, returning the first TraceContext successfully extracted.
+func (e *Extractor) Extract(carrier tracer.TextMapReader) (*TraceContext, error) {
+	lastErr := errorNoDDContextFound
+	for _, style := range e.precedence {
+		var (
+			tc  *TraceContext
+			err error
+		)
+		switch style {
+		case StyleDatadog:
+			tc, err = extractTraceContextFromDatadogHeaders(carrier)
+		case StyleW3C:
+			tc, err = extractTraceContextFromW3CHeaders(carrier)
+		default:
+			continue
+		}
+		if err == nil {
+			return tc, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// ddSamplingPriority is implemented by the ddtrace.SpanContext the tracer
+// produces; it's how a sampling priority set by tracer.Extract's Datadog
+// propagator makes it into a TraceContext, since ddtrace.SpanContext itself
+// doesn't expose one publicly.
+type ddSamplingPriority interface {
+	SamplingPriority() (int, bool)
+}
+
+// extractTraceContextFromDatadogHeaders extracts a TraceContext from
+// carrier's Datadog-format trace headers (x-datadog-trace-id,
+// x-datadog-parent-id, x-datadog-sampling-priority, ...), as already
+// unpacked into carrier by the carriers in this package.
+func extractTraceContextFromDatadogHeaders(carrier tracer.TextMapReader) (*TraceContext, error) {
+	spanContext, err := tracer.Extract(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &TraceContext{
+		TraceID:          spanContext.TraceID(),
+		ParentID:         spanContext.SpanID(),
+		SamplingPriority: sampler.PriorityAutoKeep,
+	}
+	if withPriority, ok := spanContext.(ddSamplingPriority); ok {
+		if priority, ok := withPriority.SamplingPriority(); ok {
+			tc.SamplingPriority = sampler.SamplingPriority(priority)
+		}
+	}
+	return tc, nil
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func bothStylesCarrier() tracer.TextMapCarrier {
+	return tracer.TextMapCarrier{
+		"x-datadog-trace-id":          "1234",
+		"x-datadog-parent-id":         "5678",
+		"x-datadog-sampling-priority": "2",
+		"traceparent":                 "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+}
+
+func TestExtractorDefaultPrecedencePrefersDatadog(t *testing.T) {
+	tc, err := NewExtractor().Extract(bothStylesCarrier())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1234, tc.TraceID)
+	assert.Equal(t, "", tc.TraceIDUpper64Hex)
+}
+
+func TestExtractorWithPrecedencePrefersW3C(t *testing.T) {
+	tc, err := NewExtractor(WithPrecedence(StyleW3C, StyleDatadog)).Extract(bothStylesCarrier())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+	assert.Equal(t, "4bf92f3577b34da6", tc.TraceIDUpper64Hex)
+}
+
+func TestExtractorWithPrecedenceOnlyW3C(t *testing.T) {
+	e := NewExtractor(WithPrecedence(StyleW3C))
+
+	_, err := e.Extract(tracer.TextMapCarrier{
+		"x-datadog-trace-id":  "1234",
+		"x-datadog-parent-id": "5678",
+	})
+	assert.Error(t, err, "a Datadog-only carrier should not be accepted when W3C is the only configured style")
+
+	tc, err := e.Extract(bothStylesCarrier())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestExtractorFallsThroughToNextStyle(t *testing.T) {
+	carrier := tracer.TextMapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	tc, err := NewExtractor().Extract(carrier)
+	require.NoError(t, err, "Datadog extraction should fail on this carrier and fall through to W3C")
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestExtractorNoMatchingStyle(t *testing.T) {
+	_, err := NewExtractor().Extract(tracer.TextMapCarrier{"unrelated": "header"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"fmt"
+	"sync"
+
+	json "github.com/json-iterator/go"
+
+	"github.com/DataDog/datadog-agent/pkg/serverless/trigger/events"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// EventType names the shape of an incoming Lambda event payload, used to
+// select which carrier extracts its trace context.
+type EventType string
+
+const (
+	EventTypeSQS            EventType = "sqs"
+	EventTypeSNS            EventType = "sns"
+	EventTypeEventBridge    EventType = "eventbridge"
+	EventTypeKinesis        EventType = "kinesis"
+	EventTypeDynamoDBStream EventType = "dynamodb"
+	EventTypeKafka          EventType = "kafka"
+	EventTypeStepFunction   EventType = "stepfunction"
+	EventTypeRawPayload     EventType = "raw"
+)
+
+// CarrierFunc builds a tracer.TextMapReader from a raw Lambda event payload.
+type CarrierFunc func(rawPayload []byte) (tracer.TextMapReader, error)
+
+// PostProcessorFunc adjusts a TraceContext after it's been extracted for an
+// event of the given type, e.g. to synthesize trace/span IDs the way Step
+// Functions contexts do rather than parsing them off the wire.
+type PostProcessorFunc func(eventType string, tc *TraceContext) *TraceContext
+
+var (
+	registryMu      sync.RWMutex
+	carrierRegistry = map[string]CarrierFunc{}
+	postProcessors  = map[string]PostProcessorFunc{}
+)
+
+// RegisterCarrier registers fn as the carrier used for events of the given
+// type name, e.g. for a custom trigger built on API Gateway custom
+// authorizers, AppSync resolvers, or IoT Rules. ExtractFromEvent tries
+// registered carriers before falling through to the built-in ones, so this
+// can also be used to override a built-in event type. Registering under an
+// existing name replaces it.
+func RegisterCarrier(eventTypeName string, fn CarrierFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	carrierRegistry[eventTypeName] = fn
+}
+
+// RegisterPostProcessor registers fn to run on every TraceContext
+// ExtractFromEvent extracts for events of the given type name, after the
+// carrier (registered or built-in) has produced one.
+func RegisterPostProcessor(eventTypeName string, fn PostProcessorFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	postProcessors[eventTypeName] = fn
+}
+
+func registeredCarrier(eventTypeName string) (CarrierFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	fn, ok := carrierRegistry[eventTypeName]
+	return fn, ok
+}
+
+func registeredPostProcessor(eventTypeName string) (PostProcessorFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	fn, ok := postProcessors[eventTypeName]
+	return fn, ok
+}
+
+// builtinCarrierFunc adapts one of this package's built-in, typed carrier
+// functions to the raw-payload CarrierFunc shape, unmarshaling rawPayload
+// into the event type it expects. It returns nil for an eventType with no
+// built-in carrier.
+func builtinCarrierFunc(eventType EventType) CarrierFunc {
+	switch eventType {
+	case EventTypeSQS:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var event events.SQSMessage
+			if err := json.Unmarshal(rawPayload, &event); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return sqsMessageCarrier(event)
+		}
+	case EventTypeSNS:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var event events.SNSEntity
+			if err := json.Unmarshal(rawPayload, &event); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return snsEntityCarrier(event)
+		}
+	case EventTypeEventBridge:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var event events.EventBridgeEvent
+			if err := json.Unmarshal(rawPayload, &event); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return eventBridgeCarrier(event)
+		}
+	case EventTypeKinesis:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var record events.KinesisEventRecord
+			if err := json.Unmarshal(rawPayload, &record); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return kinesisRecordCarrier(record)
+		}
+	case EventTypeDynamoDBStream:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var record events.DynamoDBEventRecord
+			if err := json.Unmarshal(rawPayload, &record); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return dynamoDBStreamCarrier(record)
+		}
+	case EventTypeKafka:
+		return func(rawPayload []byte) (tracer.TextMapReader, error) {
+			var record events.KafkaRecord
+			if err := json.Unmarshal(rawPayload, &record); err != nil {
+				return nil, errorCouldNotUnmarshal
+			}
+			return kafkaRecordCarrier(record)
+		}
+	case EventTypeRawPayload:
+		return rawPayloadCarrier
+	default:
+		return nil
+	}
+}
+
+// ExtractFromEvent extracts a TraceContext from a raw Lambda event payload of
+// the given type: a carrier registered for eventType via RegisterCarrier is
+// tried first, then the package's built-in carrier for that type, if any.
+// Step Functions is handled specially, since its trace context is
+// synthesized from the execution context rather than parsed from a carrier.
+// A post-processor registered for eventType via RegisterPostProcessor, if
+// any, gets the last word on the result.
+func (e *Extractor) ExtractFromEvent(eventType EventType, rawPayload []byte) (*TraceContext, error) {
+	if carrierFn, ok := registeredCarrier(string(eventType)); ok {
+		carrier, err := carrierFn(rawPayload)
+		if err != nil {
+			return nil, err
+		}
+		tc, err := e.Extract(carrier)
+		return e.applyPostProcessor(eventType, tc, err)
+	}
+
+	if eventType == EventTypeStepFunction {
+		var event events.StepFunctionPayload
+		if err := json.Unmarshal(rawPayload, &event); err != nil {
+			return nil, errorCouldNotUnmarshal
+		}
+		tc, err := extractTraceContextFromStepFunctionContext(event)
+		return e.applyPostProcessor(eventType, tc, err)
+	}
+
+	carrierFn := builtinCarrierFunc(eventType)
+	if carrierFn == nil {
+		return nil, fmt.Errorf("propagation: no carrier registered for event type %q", eventType)
+	}
+	carrier, err := carrierFn(rawPayload)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := e.Extract(carrier)
+	return e.applyPostProcessor(eventType, tc, err)
+}
+
+func (e *Extractor) applyPostProcessor(eventType EventType, tc *TraceContext, err error) (*TraceContext, error) {
+	if err != nil {
+		return nil, err
+	}
+	if post, ok := registeredPostProcessor(string(eventType)); ok {
+		tc = post(string(eventType), tc)
+	}
+	return tc, nil
+}
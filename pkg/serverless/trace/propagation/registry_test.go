@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestRegisterCarrierIsTriedBeforeBuiltin(t *testing.T) {
+	const customEventType EventType = "custom-authorizer"
+
+	RegisterCarrier(string(customEventType), func(rawPayload []byte) (tracer.TextMapReader, error) {
+		return tracer.TextMapCarrier{"x-datadog-trace-id": "42", "x-datadog-parent-id": "7"}, nil
+	})
+	defer delete(carrierRegistry, string(customEventType))
+
+	e := NewExtractor()
+	tc, err := e.ExtractFromEvent(customEventType, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), tc.TraceID)
+	assert.Equal(t, uint64(7), tc.ParentID)
+}
+
+func TestRegisterPostProcessorAdjustsResult(t *testing.T) {
+	const customEventType EventType = "custom-iot-rule"
+
+	RegisterCarrier(string(customEventType), func(rawPayload []byte) (tracer.TextMapReader, error) {
+		return tracer.TextMapCarrier{"x-datadog-trace-id": "42", "x-datadog-parent-id": "7"}, nil
+	})
+	defer delete(carrierRegistry, string(customEventType))
+
+	RegisterPostProcessor(string(customEventType), func(eventType string, tc *TraceContext) *TraceContext {
+		tc.ParentID = 99
+		return tc
+	})
+	defer delete(postProcessors, string(customEventType))
+
+	e := NewExtractor()
+	tc, err := e.ExtractFromEvent(customEventType, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(99), tc.ParentID)
+}
+
+func TestExtractFromEventUnknownType(t *testing.T) {
+	e := NewExtractor()
+	_, err := e.ExtractFromEvent(EventType("does-not-exist"), []byte(`{}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/serverless/trigger/events"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	stepFunctionSpanService = "states"
+	stepFunctionSpanName    = "step_function"
+)
+
+// SynthesizeUpstreamSpan builds the synthetic span representing the Step
+// Function state execution that produced tc, via
+// extractTraceContextFromStepFunctionContext. The Lambda span invoked from
+// that state sets this span's ID (tc.ParentID) as its own parent ID, giving
+// it a visible parent in the flame graph even though no real upstream
+// service emitted a span of its own.
+//
+// It returns nil if event doesn't carry the execution/state fields
+// extractTraceContextFromStepFunctionContext needs, or if stateEnteredTime
+// isn't a parseable timestamp.
+func SynthesizeUpstreamSpan(tc *TraceContext, event events.StepFunctionPayload) *pb.Span {
+	execArn := event.Execution.ID
+	stateName := event.State.Name
+	stateEnteredTime := event.State.EnteredTime
+	if tc == nil || execArn == "" || stateName == "" || stateEnteredTime == "" {
+		return nil
+	}
+
+	startTime, err := time.Parse(time.RFC3339Nano, stateEnteredTime)
+	if err != nil {
+		return nil
+	}
+
+	duration := time.Since(startTime)
+	if duration < 0 {
+		duration = 0
+	}
+
+	return &pb.Span{
+		Service:  stepFunctionSpanService,
+		Name:     stepFunctionSpanName,
+		Resource: stateName,
+		TraceID:  tc.TraceID,
+		SpanID:   tc.ParentID,
+		Start:    startTime.UnixNano(),
+		Duration: duration.Nanoseconds(),
+		Meta: map[string]string{
+			"step_function.execution_arn":           execArn,
+			"step_function.state_name":              stateName,
+			"step_function.state_entered_time":      stateEnteredTime,
+			"step_function.state_retry_count":       strconv.Itoa(int(event.State.RetryCount)),
+			"step_function.execution_redrive_count": strconv.Itoa(int(event.Execution.RedriveCount)),
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/serverless/trigger/events"
+)
+
+func stepFunctionPayload() events.StepFunctionPayload {
+	return events.StepFunctionPayload{
+		Execution: events.StepFunctionExecution{
+			ID: "arn:aws:states:sa-east-1:425362996713:execution:HelloWorld:75b3a2e1-b48d-4a4f-8f1b-9ff4a1cc1c6e",
+		},
+		State: events.StepFunctionState{
+			Name:        "say-hello",
+			EnteredTime: "2023-01-01T00:00:00.000Z",
+		},
+	}
+}
+
+func TestSynthesizeUpstreamSpan(t *testing.T) {
+	payload := stepFunctionPayload()
+	tc, err := extractTraceContextFromStepFunctionContext(payload)
+	require.NoError(t, err)
+
+	span := SynthesizeUpstreamSpan(tc, payload)
+	require.NotNil(t, span)
+	assert.Equal(t, stepFunctionSpanService, span.Service)
+	assert.Equal(t, stepFunctionSpanName, span.Name)
+	assert.Equal(t, "say-hello", span.Resource)
+	assert.Equal(t, tc.TraceID, span.TraceID)
+	assert.Equal(t, tc.ParentID, span.SpanID)
+	assert.Equal(t, payload.Execution.ID, span.Meta["step_function.execution_arn"])
+	assert.Equal(t, payload.State.Name, span.Meta["step_function.state_name"])
+
+	startTime, err := time.Parse(time.RFC3339Nano, payload.State.EnteredTime)
+	require.NoError(t, err)
+	assert.Equal(t, startTime.UnixNano(), span.Start)
+}
+
+func TestSynthesizeUpstreamSpanNestedStepFunction(t *testing.T) {
+	inner := stepFunctionPayload()
+	nested := events.NestedStepFunctionPayload{
+		Payload:         inner,
+		RootExecutionID: "arn:aws:states:sa-east-1:425362996713:execution:HelloWorld:root-execution-id",
+	}
+
+	tc, err := extractTraceContextFromNestedStepFunctionContext(nested)
+	require.NoError(t, err)
+
+	span := SynthesizeUpstreamSpan(tc, nested.Payload)
+	require.NotNil(t, span)
+	// the nested extraction overrides the trace ID with the root execution's
+	assert.Equal(t, tc.TraceID, span.TraceID)
+	assert.Equal(t, inner.Execution.ID, span.Meta["step_function.execution_arn"])
+}
+
+func TestSynthesizeUpstreamSpanLambdaRootStepFunction(t *testing.T) {
+	inner := stepFunctionPayload()
+	root := events.LambdaRootStepFunctionPayload{
+		Payload:   inner,
+		TraceID:   "1234567890123456789",
+		TraceTags: "_dd.p.tid=1111111111111111",
+	}
+
+	tc, err := extractTraceContextFromLambdaRootStepFunctionContext(root)
+	require.NoError(t, err)
+
+	span := SynthesizeUpstreamSpan(tc, root.Payload)
+	require.NotNil(t, span)
+	assert.Equal(t, tc.TraceID, span.TraceID)
+	assert.Equal(t, inner.State.Name, span.Meta["step_function.state_name"])
+}
+
+func TestSynthesizeUpstreamSpanMissingFields(t *testing.T) {
+	tc := &TraceContext{TraceID: 1, ParentID: 2}
+	span := SynthesizeUpstreamSpan(tc, events.StepFunctionPayload{})
+	assert.Nil(t, span)
+}
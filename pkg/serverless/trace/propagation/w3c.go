@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	w3cTraceparentHeader = "traceparent"
+	w3cTracestateHeader  = "tracestate"
+
+	w3cTraceFlagSampled = 0x1
+)
+
+var (
+	errorW3CTraceparentNotFound  = errors.New("no traceparent header found")
+	errorW3CTraceparentMalformed = errors.New("traceparent header does not match the expected version-traceid-parentid-flags format")
+)
+
+// extractTraceContextFromW3CHeaders extracts a TraceContext from carrier's
+// traceparent and, if present, tracestate headers, per the W3C Trace Context
+// spec (https://www.w3.org/TR/trace-context/). Unlike the Datadog carriers in
+// this package, it's self-contained: it doesn't need to be passed to
+// tracer.Extract, since dd-trace-go's own propagator only understands
+// Datadog's headers.
+func extractTraceContextFromW3CHeaders(carrier tracer.TextMapReader) (*TraceContext, error) {
+	var traceparent, tracestate string
+	err := carrier.ForeachKey(func(key, val string) error {
+		switch strings.ToLower(key) {
+		case w3cTraceparentHeader:
+			traceparent = val
+		case w3cTracestateHeader:
+			tracestate = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if traceparent == "" {
+		return nil, errorW3CTraceparentNotFound
+	}
+	return parseW3CTraceparent(traceparent, tracestate)
+}
+
+// parseW3CTraceparent parses a traceparent header value of the form
+// "version-trace_id-parent_id-trace_flags" into a TraceContext, splitting the
+// 128-bit trace ID into its lower 64 bits (TraceID) and upper 64 bits
+// (TraceIDUpper64Hex), and deriving SamplingPriority from the sampled bit of
+// trace_flags. If tracestate carries a Datadog "dd=s:<priority>" member, it
+// refines that coarse sampled/not-sampled bit into the full Datadog sampling
+// priority it was generated from, the same way dd-trace-go's own
+// tracecontext propagator does.
+func parseW3CTraceparent(traceparent, tracestate string) (*TraceContext, error) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 {
+		return nil, errorW3CTraceparentMalformed
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if version == "" || len(traceID) != 32 || len(parentID) != 16 || len(flags) != 2 {
+		return nil, errorW3CTraceparentMalformed
+	}
+
+	tc := new(TraceContext)
+	var err error
+	tc.TraceIDUpper64Hex = strings.ToLower(traceID[:16])
+	tc.TraceID, err = strconv.ParseUint(traceID[16:], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace ID from traceparent: %w", err)
+	}
+	tc.ParentID, err = strconv.ParseUint(parentID, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse parent ID from traceparent: %w", err)
+	}
+	if tc.TraceID == 0 || tc.ParentID == 0 {
+		return nil, errorW3CTraceparentMalformed
+	}
+
+	traceFlags, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace flags from traceparent: %w", err)
+	}
+	if traceFlags&w3cTraceFlagSampled != 0 {
+		tc.SamplingPriority = sampler.PriorityAutoKeep
+	} else {
+		tc.SamplingPriority = sampler.PriorityAutoDrop
+	}
+
+	if priority, ok := ddSamplingPriorityFromTracestate(tracestate); ok {
+		tc.SamplingPriority = priority
+	}
+
+	return tc, nil
+}
+
+// ddSamplingPriorityFromTracestate looks for a Datadog ("dd=") member in a
+// W3C tracestate header and, if it carries an "s:<priority>" field, returns
+// the sampling priority it encodes.
+func ddSamplingPriorityFromTracestate(tracestate string) (sampler.SamplingPriority, bool) {
+	for _, member := range strings.Split(tracestate, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || key != "dd" {
+			continue
+		}
+		for _, field := range strings.Split(val, ";") {
+			k, v, ok := strings.Cut(field, ":")
+			if !ok || k != "s" {
+				continue
+			}
+			priority, err := strconv.Atoi(v)
+			if err != nil {
+				return 0, false
+			}
+			return sampler.SamplingPriority(priority), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package propagation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+func TestParseW3CTraceparentSampled(t *testing.T) {
+	tc, err := parseW3CTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "")
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6", tc.TraceIDUpper64Hex)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+	assert.Equal(t, uint64(0x00f067aa0ba902b7), tc.ParentID)
+	assert.Equal(t, sampler.PriorityAutoKeep, tc.SamplingPriority)
+}
+
+func TestParseW3CTraceparentNotSampled(t *testing.T) {
+	tc, err := parseW3CTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "")
+	require.NoError(t, err)
+	assert.Equal(t, sampler.PriorityAutoDrop, tc.SamplingPriority)
+}
+
+func TestParseW3CTraceparentDDTracestateOverridesSamplingPriority(t *testing.T) {
+	tc, err := parseW3CTraceparent(
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"dd=s:2;o:rum,other=value",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, sampler.SamplingPriority(2), tc.SamplingPriority)
+}
+
+func TestParseW3CTraceparentMalformed(t *testing.T) {
+	cases := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-short-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	}
+	for _, traceparent := range cases {
+		_, err := parseW3CTraceparent(traceparent, "")
+		assert.Error(t, err, traceparent)
+	}
+}
+
+func TestExtractTraceContextFromW3CHeaders(t *testing.T) {
+	carrier := tracer.TextMapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	tc, err := extractTraceContextFromW3CHeaders(carrier)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), tc.TraceID)
+}
+
+func TestExtractTraceContextFromW3CHeadersMissing(t *testing.T) {
+	_, err := extractTraceContextFromW3CHeaders(tracer.TextMapCarrier{})
+	assert.Equal(t, errorW3CTraceparentNotFound, err)
+}
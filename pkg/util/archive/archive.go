@@ -0,0 +1,381 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package archive packages a directory tree into a single archive file
+// (zip, tar+gzip, or tar+zstd) and extracts it back, preserving file modes
+// across the round trip.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format identifies the container format an archive is packaged in.
+type Format string
+
+const (
+	// FormatZip packages entries into a .zip archive. This is the agent's
+	// historical flare format.
+	FormatZip Format = "zip"
+	// FormatTarGzip packages entries into a gzip-compressed tarball.
+	FormatTarGzip Format = "tar.gz"
+	// FormatTarZstd packages entries into a zstd-compressed tarball.
+	FormatTarZstd Format = "tar.zst"
+)
+
+// Extension returns the conventional file extension for format.
+func Extension(format Format) string {
+	switch format {
+	case FormatTarGzip:
+		return ".tar.gz"
+	case FormatTarZstd:
+		return ".tar.zst"
+	default:
+		return ".zip"
+	}
+}
+
+// Create packages every file under srcDir into destPath using format, with
+// entry names relative to srcDir (so destPath's top-level entries are
+// srcDir's immediate children). The zero value of Format produces a zip.
+func Create(format Format, srcDir, destPath string) error {
+	switch format {
+	case FormatTarGzip:
+		return TarGzip(srcDir, destPath)
+	case FormatTarZstd:
+		return TarZstd(srcDir, destPath)
+	case FormatZip, "":
+		return Zip(srcDir, destPath)
+	default:
+		return fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// CreateTo is the io.Writer counterpart of Create: it packages srcDir
+// directly into w instead of a file at destPath, so a caller can stream the
+// archive straight into an upload (or anywhere else) without staging it on
+// disk first. It stops early if ctx is canceled.
+func CreateTo(ctx context.Context, format Format, srcDir string, w io.Writer) error {
+	switch format {
+	case FormatTarGzip:
+		return WriteTarGzip(ctx, srcDir, w)
+	case FormatTarZstd:
+		return WriteTarZstd(ctx, srcDir, w)
+	case FormatZip, "":
+		return WriteZip(ctx, srcDir, w)
+	default:
+		return fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// Extract unpacks srcPath (previously produced by Create with the matching
+// format) into destDir. The zero value of Format assumes a zip.
+func Extract(format Format, srcPath, destDir string) error {
+	switch format {
+	case FormatTarGzip:
+		return UntarGzip(srcPath, destDir)
+	case FormatTarZstd:
+		return UntarZstd(srcPath, destDir)
+	case FormatZip, "":
+		return Unzip(srcPath, destDir)
+	default:
+		return fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// Zip packages every file under srcDir into a zip archive at destPath, with
+// entry names relative to srcDir.
+func Zip(srcDir, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", destPath, err)
+	}
+	defer out.Close()
+
+	return WriteZip(context.Background(), srcDir, out)
+}
+
+// WriteZip is the io.Writer counterpart of Zip: it writes the zip archive
+// directly to w instead of a file, stopping early if ctx is canceled.
+func WriteZip(ctx context.Context, srcDir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return fmt.Errorf("building zip header for %q: %w", path, err)
+		}
+		header.Name = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zw.CreateHeader(header)
+			return err
+		}
+
+		header.Method = zip.Deflate
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("adding %q to zip: %w", rel, err)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening %q: %w", path, err)
+		}
+		defer f.Close()
+
+		_, err = io.Copy(entry, f)
+		return err
+	})
+}
+
+// Unzip extracts the zip archive at srcPath into destDir.
+func Unzip(srcPath, destDir string) error {
+	r, err := zip.OpenReader(srcPath)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", srcPath, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if err := extractZipEntry(f, destDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(f *zip.File, destDir string) error {
+	destPath, err := safeJoin(destDir, f.Name)
+	if err != nil {
+		return err
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(destPath, f.Mode())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return fmt.Errorf("creating directory for %q: %w", destPath, err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening %q in archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", destPath, err)
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// TarGzip packages every file under srcDir into a gzip-compressed tarball at
+// destPath, with entry names relative to srcDir, preserving file modes.
+func TarGzip(srcDir, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", destPath, err)
+	}
+	defer out.Close()
+
+	return WriteTarGzip(context.Background(), srcDir, out)
+}
+
+// WriteTarGzip is the io.Writer counterpart of TarGzip: it writes the
+// gzip-compressed tarball directly to w instead of a file, stopping early if
+// ctx is canceled.
+func WriteTarGzip(ctx context.Context, srcDir string, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	defer gw.Close()
+
+	return writeTar(ctx, srcDir, gw)
+}
+
+// UntarGzip extracts the gzip-compressed tarball at srcPath into destDir.
+func UntarGzip(srcPath, destDir string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", srcPath, err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("opening gzip stream in %q: %w", srcPath, err)
+	}
+	defer gr.Close()
+
+	return extractTar(gr, destDir)
+}
+
+// TarZstd packages every file under srcDir into a zstd-compressed tarball at
+// destPath, with entry names relative to srcDir, preserving file modes.
+func TarZstd(srcDir, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", destPath, err)
+	}
+	defer out.Close()
+
+	return WriteTarZstd(context.Background(), srcDir, out)
+}
+
+// WriteTarZstd is the io.Writer counterpart of TarZstd: it writes the
+// zstd-compressed tarball directly to w instead of a file, stopping early if
+// ctx is canceled.
+func WriteTarZstd(ctx context.Context, srcDir string, w io.Writer) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("opening zstd stream: %w", err)
+	}
+	defer zw.Close()
+
+	return writeTar(ctx, srcDir, zw)
+}
+
+// UntarZstd extracts the zstd-compressed tarball at srcPath into destDir.
+func UntarZstd(srcPath, destDir string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", srcPath, err)
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("opening zstd stream in %q: %w", srcPath, err)
+	}
+	defer zr.Close()
+
+	return extractTar(zr, destDir)
+}
+
+func writeTar(ctx context.Context, srcDir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("building tar header for %q: %w", path, err)
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("writing tar header for %q: %w", rel, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening %q: %w", path, err)
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+func extractTar(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar entry: %w", err)
+		}
+
+		destPath, err := safeJoin(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destPath, os.FileMode(header.Mode)); err != nil {
+				return fmt.Errorf("creating directory %q: %w", destPath, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+				return fmt.Errorf("creating directory for %q: %w", destPath, err)
+			}
+			if err := writeTarRegularFile(tr, destPath, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeTarRegularFile(r io.Reader, destPath string, mode os.FileMode) error {
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", destPath, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("writing %q: %w", destPath, err)
+	}
+	return nil
+}
+
+// safeJoin joins destDir and name (an archive entry path), rejecting entries
+// that would escape destDir (e.g. via ".." components in a maliciously
+// crafted archive).
+func safeJoin(destDir, name string) (string, error) {
+	destPath := filepath.Join(destDir, name)
+	if destPath != destDir && !strings.HasPrefix(destPath, destDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes destination directory", name)
+	}
+	return destPath, nil
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+)
+
+// AgeExtension is appended to an archive's usual extension once it's been
+// encrypted with NewEncryptWriter.
+const AgeExtension = ".age"
+
+// Recipient identifies who an encrypted flare archive can be decrypted by.
+// Age is the default and only scheme NewEncryptWriter currently supports;
+// PGP is accepted here so integration authors can start filling it in, but
+// PGP-only recipients are rejected until that path is implemented.
+type Recipient struct {
+	// Age is an age X25519 recipient string (e.g. "age1ql3z7h...", as
+	// produced by `age-keygen`).
+	Age string
+	// PGP is an ASCII-armored PGP public key. Not yet supported: a recipient
+	// that only sets PGP makes NewEncryptWriter return an error.
+	PGP string
+}
+
+// NewEncryptWriter wraps w so that everything written to the returned
+// writer is age-encrypted for recipients as it's written - one file key
+// stanza per recipient, so any one of their matching identities can decrypt
+// the whole stream. The caller must Close the returned writer to flush the
+// final frame; closing it does not close w.
+func NewEncryptWriter(w io.Writer, recipients []Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("encrypting requires at least one recipient")
+	}
+
+	ageRecipients := make([]age.Recipient, 0, len(recipients))
+	for i, r := range recipients {
+		if r.Age == "" {
+			return nil, fmt.Errorf("recipient %d: PGP recipients are not supported yet, set Age instead", i)
+		}
+		ar, err := age.ParseX25519Recipient(r.Age)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: parsing age recipient: %w", i, err)
+		}
+		ageRecipients = append(ageRecipients, ar)
+	}
+
+	enc, err := age.Encrypt(w, ageRecipients...)
+	if err != nil {
+		return nil, fmt.Errorf("opening age encryption stream: %w", err)
+	}
+	return enc, nil
+}
+
+// DecryptFlare decrypts the age-encrypted archive at path using identity (an
+// age X25519 identity string, as produced by `age-keygen`), then extracts
+// the result into destDir using format - the inverse of a builder.Save that
+// set FlareArgs.EncryptTo.
+func DecryptFlare(path, identity string, format Format, destDir string) error {
+	id, err := age.ParseX25519Identity(identity)
+	if err != nil {
+		return fmt.Errorf("parsing age identity: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", path, err)
+	}
+	defer f.Close()
+
+	r, err := age.Decrypt(f, id)
+	if err != nil {
+		return fmt.Errorf("decrypting %q: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp("", "flare-decrypted-*"+Extension(format))
+	if err != nil {
+		return fmt.Errorf("creating temporary archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("decrypting %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary archive: %w", err)
+	}
+
+	return Extract(format, tmp.Name(), destDir)
+}
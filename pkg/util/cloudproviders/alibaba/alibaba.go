@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package alibaba fetches instance metadata from Alibaba Cloud's metadata
+// service, for hosts running on ECS.
+package alibaba
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// CloudProviderName is the value returned as the detected provider name.
+const CloudProviderName = "Alibaba"
+
+const metadataItemPath = "/latest/meta-data"
+
+const timeout = 300 * time.Millisecond
+
+// metadataURL is the Alibaba Cloud metadata service's base URL; it's a var
+// so tests can point it at an httptest.Server.
+var metadataURL = "http://100.100.100.200"
+
+var httpClient = &http.Client{Timeout: timeout}
+
+// GetInstanceID queries the metadata service for the instance's ID.
+func GetInstanceID(ctx context.Context) (string, error) {
+	return getMetadataItem(ctx, "/instance-id")
+}
+
+// GetHostname queries the metadata service for the instance's hostname.
+func GetHostname(ctx context.Context) (string, error) {
+	return getMetadataItem(ctx, "/hostname")
+}
+
+// GetHostAliases returns the instance ID as a host alias, the same way the
+// Agent treats other cloud providers' instance IDs.
+func GetHostAliases(ctx context.Context) ([]string, error) {
+	instanceID, err := GetInstanceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{instanceID}, nil
+}
+
+// GetClusterName returns the name of the ACK (Alibaba Container Service for
+// Kubernetes) cluster this instance belongs to, or an error if it isn't
+// part of one.
+func GetClusterName(ctx context.Context) (string, error) {
+	return getMetadataItem(ctx, "/ack-cluster-id")
+}
+
+// GetNTPHosts returns the NTP pool recommended for hosts on Alibaba Cloud,
+// or nil if this host doesn't look like an Alibaba Cloud instance.
+func GetNTPHosts(ctx context.Context) []string {
+	if _, err := GetInstanceID(ctx); err != nil {
+		return nil
+	}
+	return []string{"ntp.aliyun.com"}
+}
+
+func detectionEnabled() bool {
+	for _, p := range config.Datadog().GetStringSlice("cloud_provider_metadata") {
+		if p == "alibaba" {
+			return true
+		}
+	}
+	return false
+}
+
+func getMetadataItem(ctx context.Context, endpoint string) (string, error) {
+	if !detectionEnabled() {
+		return "", fmt.Errorf("cloud_provider_metadata does not include alibaba")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+metadataItemPath+endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata request to %q failed with status %d", req.URL.Path, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
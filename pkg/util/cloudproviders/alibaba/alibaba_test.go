@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package alibaba
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestGetInstanceID(t *testing.T) {
+	cfg := configmock.New(t)
+	ctx := context.Background()
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"alibaba"})
+
+	expected := "i-8vb3626hzepwq3lf6bi7"
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, expected)
+		lastRequest = r
+	}))
+	defer ts.Close()
+	metadataURL = ts.URL
+
+	val, err := GetInstanceID(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, val)
+	assert.Equal(t, lastRequest.URL.Path, "/latest/meta-data/instance-id")
+}
+
+func TestGetHostname(t *testing.T) {
+	cfg := configmock.New(t)
+	ctx := context.Background()
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"alibaba"})
+
+	expected := "hostname-from-alibaba"
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, expected)
+		lastRequest = r
+	}))
+	defer ts.Close()
+	metadataURL = ts.URL
+
+	val, err := GetHostname(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, val)
+	assert.Equal(t, lastRequest.URL.Path, "/latest/meta-data/hostname")
+}
+
+func TestGetHostAliases(t *testing.T) {
+	cfg := configmock.New(t)
+	ctx := context.Background()
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"alibaba"})
+
+	expected := "i-8vb3626hzepwq3lf6bi7"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, expected)
+	}))
+	defer ts.Close()
+	metadataURL = ts.URL
+
+	aliases, err := GetHostAliases(ctx)
+	assert.NoError(t, err)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, expected, aliases[0])
+}
+
+func TestGetClusterName(t *testing.T) {
+	cfg := configmock.New(t)
+	ctx := context.Background()
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"alibaba"})
+
+	expected := "cdd9f27b6d0ad4f058f4651b1******"
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, expected)
+		lastRequest = r
+	}))
+	defer ts.Close()
+	metadataURL = ts.URL
+
+	val, err := GetClusterName(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, val)
+	assert.Equal(t, lastRequest.URL.Path, "/latest/meta-data/ack-cluster-id")
+}
+
+func TestGetNTPHosts(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"alibaba"})
+
+	ctx := context.Background()
+	expectedHosts := []string{"ntp.aliyun.com"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "test")
+	}))
+	defer ts.Close()
+
+	metadataURL = ts.URL
+	actualHosts := GetNTPHosts(ctx)
+
+	assert.Equal(t, expectedHosts, actualHosts)
+}
+
+func TestGetNTPHostsNotOnAlibaba(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{})
+
+	assert.Nil(t, GetNTPHosts(context.Background()))
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package tencent fetches instance metadata from Tencent Cloud's metadata
+// service, for hosts running on CVM.
+package tencent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// CloudProviderName is the value returned as the detected provider name.
+const CloudProviderName = "Tencent"
+
+const (
+	metadataItemPath = "/meta-data"
+	tokenPath        = "/latest/api/token"
+
+	tokenHeader    = "X-TC-Metadata-Token"
+	tokenTTLHeader = "X-TC-Metadata-Token-TTL-Seconds"
+
+	timeout = 300 * time.Millisecond
+)
+
+// tokenTTL is how long a fetched IMDSv2 token is valid for; it's a var
+// rather than a const so tests can shrink it to exercise expiry-driven
+// refresh without sleeping for hours.
+var tokenTTL = 6 * time.Hour
+
+// tokenRefreshSkew is how far ahead of its real expiry a cached token is
+// treated as stale, so a request doesn't race a token that's about to
+// expire.
+var tokenRefreshSkew = 1 * time.Minute
+
+// metadataURL is the Tencent Cloud metadata service's base URL; it's a var
+// so tests can point it at an httptest.Server.
+var metadataURL = "http://metadata.tencentyun.com"
+
+var httpClient = &http.Client{Timeout: timeout}
+
+// GetInstanceID queries the metadata service for the instance's ID.
+func GetInstanceID(ctx context.Context) (string, error) {
+	return getMetadataItem(ctx, "/instance-id")
+}
+
+// GetHostAliases returns the instance ID as a host alias, the same way the
+// Agent treats other cloud providers' instance IDs.
+func GetHostAliases(ctx context.Context) ([]string, error) {
+	instanceID, err := GetInstanceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{instanceID}, nil
+}
+
+// GetNTPHosts returns the NTP pool recommended for hosts on Tencent Cloud,
+// or nil if this host doesn't look like a Tencent Cloud instance.
+func GetNTPHosts(ctx context.Context) []string {
+	if _, err := GetInstanceID(ctx); err != nil {
+		return nil
+	}
+	return []string{"ntpupdate.tencentyun.com"}
+}
+
+func detectionEnabled() bool {
+	for _, p := range config.Datadog().GetStringSlice("cloud_provider_metadata") {
+		if p == "tencent" {
+			return true
+		}
+	}
+	return false
+}
+
+// imdsV2Enabled reports whether tencent_imds_version opts this host into the
+// token-authenticated metadata flow; the zero value ("v1", same as unset)
+// keeps the historical unauthenticated behavior.
+func imdsV2Enabled() bool {
+	return config.Datadog().GetString("tencent_imds_version") == "v2"
+}
+
+func getMetadataItem(ctx context.Context, endpoint string) (string, error) {
+	if !detectionEnabled() {
+		return "", fmt.Errorf("cloud_provider_metadata does not include tencent")
+	}
+
+	path := metadataItemPath + endpoint
+	if imdsV2Enabled() {
+		if value, err := queryMetadataV2(ctx, path); err == nil {
+			return value, nil
+		}
+		// The token-authenticated path isn't available (e.g. an older
+		// hypervisor image); fall back to v1 so detection still works.
+	}
+	return queryMetadataV1(ctx, path)
+}
+
+func queryMetadataV1(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	body, _, err := doMetadataRequest(req)
+	return body, err
+}
+
+// queryMetadataV2 attaches a cached IMDSv2 token to the request, fetching
+// one first if none is cached, and retries exactly once with a freshly
+// fetched token if the metadata service rejects it as expired.
+func queryMetadataV2(ctx context.Context, path string) (string, error) {
+	token, err := defaultTokenManager.get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching IMDSv2 token: %w", err)
+	}
+
+	body, status, err := queryMetadataWithToken(ctx, path, token)
+	if status != http.StatusUnauthorized {
+		return body, err
+	}
+
+	defaultTokenManager.invalidate()
+	token, err = defaultTokenManager.get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("refreshing IMDSv2 token: %w", err)
+	}
+	body, _, err = queryMetadataWithToken(ctx, path, token)
+	return body, err
+}
+
+func queryMetadataWithToken(ctx context.Context, path, token string) (string, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+path, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set(tokenHeader, token)
+	return doMetadataRequest(req)
+}
+
+func doMetadataRequest(req *http.Request) (string, int, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", resp.StatusCode, fmt.Errorf("metadata request to %q failed with status %d", req.URL.Path, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp.StatusCode, err
+	}
+	return string(body), resp.StatusCode, nil
+}
+
+// tokenManager caches the IMDSv2 session token in-process, refreshing it
+// shortly before it expires or whenever the metadata service reports it as
+// no longer valid.
+type tokenManager struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var defaultTokenManager = &tokenManager{}
+
+func (m *tokenManager) get(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.token != "" && time.Now().Before(m.expires.Add(-tokenRefreshSkew)) {
+		return m.token, nil
+	}
+	return m.fetchLocked(ctx)
+}
+
+func (m *tokenManager) invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = ""
+}
+
+func (m *tokenManager) fetchLocked(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, metadataURL+tokenPath, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(tokenTTLHeader, strconv.Itoa(int(tokenTTL.Seconds())))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	m.token = strings.TrimSpace(string(body))
+	m.expires = time.Now().Add(tokenTTL)
+	return m.token, nil
+}
@@ -10,7 +10,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,6 +20,18 @@ import (
 	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 )
 
+// resetTokenManager clears the package-level IMDSv2 token cache and TTL
+// settings between tests, since they're shared global state.
+func resetTokenManager(t *testing.T) {
+	t.Helper()
+	defaultTokenManager = &tokenManager{}
+	origTTL, origSkew := tokenTTL, tokenRefreshSkew
+	t.Cleanup(func() {
+		tokenTTL, tokenRefreshSkew = origTTL, origSkew
+		defaultTokenManager = &tokenManager{}
+	})
+}
+
 func TestGetInstanceID(t *testing.T) {
 	cfg := configmock.New(t)
 	ctx := context.Background()
@@ -79,3 +93,117 @@ func TestGetNTPHosts(t *testing.T) {
 
 	assert.Equal(t, expectedHosts, actualHosts)
 }
+
+// imdsV2Server answers the IMDSv2 token PUT with wantToken's current value
+// (counting how many times it's called) and only serves metadata GETs that
+// carry that value, answering 401 to any other.
+func imdsV2Server(t *testing.T, token string, wantToken *atomic.Value) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var tokenRequests atomic.Int32
+	wantToken.Store(token)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			tokenRequests.Add(1)
+			io.WriteString(w, wantToken.Load().(string))
+			return
+		}
+		if r.Header.Get(tokenHeader) != wantToken.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "ins-nad6bga0")
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &tokenRequests
+}
+
+func TestIMDSv2TokenFetchedOnce(t *testing.T) {
+	resetTokenManager(t)
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"tencent"})
+	cfg.SetWithoutSource("tencent_imds_version", "v2")
+
+	var wantToken atomic.Value
+	ts, tokenRequests := imdsV2Server(t, "test-token", &wantToken)
+	metadataURL = ts.URL
+
+	for i := 0; i < 5; i++ {
+		val, err := GetInstanceID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "ins-nad6bga0", val)
+	}
+	assert.EqualValues(t, 1, tokenRequests.Load())
+}
+
+func TestIMDSv2TokenRefreshedOnExpiry(t *testing.T) {
+	resetTokenManager(t)
+	tokenTTL = 10 * time.Millisecond
+	tokenRefreshSkew = 0
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"tencent"})
+	cfg.SetWithoutSource("tencent_imds_version", "v2")
+
+	var wantToken atomic.Value
+	ts, tokenRequests := imdsV2Server(t, "test-token", &wantToken)
+	metadataURL = ts.URL
+
+	_, err := GetInstanceID(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, tokenRequests.Load())
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err = GetInstanceID(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, tokenRequests.Load())
+}
+
+func TestIMDSv2RefreshesOn401(t *testing.T) {
+	resetTokenManager(t)
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"tencent"})
+	cfg.SetWithoutSource("tencent_imds_version", "v2")
+
+	var wantToken atomic.Value
+	ts, tokenRequests := imdsV2Server(t, "test-token", &wantToken)
+	metadataURL = ts.URL
+
+	_, err := GetInstanceID(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, tokenRequests.Load())
+
+	// Simulate the metadata service invalidating the cached token without
+	// the client knowing yet.
+	wantToken.Store("rotated-token")
+
+	val, err := GetInstanceID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ins-nad6bga0", val)
+	assert.EqualValues(t, 2, tokenRequests.Load())
+}
+
+func TestIMDSv2FallsBackToV1WhenTokenRequestFails(t *testing.T) {
+	resetTokenManager(t)
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("cloud_provider_metadata", []string{"tencent"})
+	cfg.SetWithoutSource("tencent_imds_version", "v2")
+
+	expected := "ins-nad6bga0"
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastRequest = r
+		io.WriteString(w, expected)
+	}))
+	defer ts.Close()
+	metadataURL = ts.URL
+
+	val, err := GetInstanceID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, val)
+	assert.Empty(t, lastRequest.Header.Get(tokenHeader))
+}
@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filesystem
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/klauspost/compress/zstd"
+)
+
+// RotatorCompression selects how rotated segments are compressed once they're
+// no longer the active write target.
+type RotatorCompression string
+
+const (
+	// RotatorCompressionNone leaves rotated segments uncompressed.
+	RotatorCompressionNone RotatorCompression = "none"
+	// RotatorCompressionGzip compresses rotated segments with gzip.
+	RotatorCompressionGzip RotatorCompression = "gzip"
+	// RotatorCompressionZstd compresses rotated segments with zstd.
+	RotatorCompressionZstd RotatorCompression = "zstd"
+)
+
+// defaultMaxFiles is how many rotated segments are kept when
+// RotatorOptions.MaxFiles isn't set.
+const defaultMaxFiles = 5
+
+// RotatorOptions configures a Rotator.
+type RotatorOptions struct {
+	// MaxSize is the maximum size in bytes of the active segment before it's
+	// rotated. Zero disables size-based rotation.
+	MaxSize int64
+	// MaxFiles bounds how many rotated segments (path.1, path.2, ...) are kept;
+	// the oldest is removed past this count. Defaults to defaultMaxFiles.
+	MaxFiles int
+	// Compression selects how rotated segments are compressed in the
+	// background. Defaults to RotatorCompressionNone.
+	Compression RotatorCompression
+}
+
+// Rotator is an io.WriteCloser over a single file path that rotates the
+// active segment to "<path>.1" (shifting older numbered segments up) once it
+// reaches MaxSize, optionally compressing rotated segments in the background.
+// It's safe for concurrent use.
+type Rotator struct {
+	path string
+	opts RotatorOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	written int64
+
+	compressWG sync.WaitGroup
+	// compressMu is held from the moment a segment is renamed to path.1
+	// until its background compression (if any) has removed it, so a
+	// following rotation can't rename a new segment on top of the one a
+	// compressAndRemove goroutine still has open.
+	compressMu sync.Mutex
+}
+
+// NewRotator opens (creating if needed) path for writing and returns a
+// Rotator that writes to it, rotating according to opts.
+func NewRotator(path string, opts RotatorOptions) (*Rotator, error) {
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+	if opts.Compression == "" {
+		opts.Compression = RotatorCompressionNone
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s for writing: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Rotator{path: path, opts: opts, file: f, written: info.Size()}, nil
+}
+
+// Write appends p to the active segment, rotating first if it would push the
+// segment past opts.MaxSize.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.opts.MaxSize > 0 && r.written > 0 && r.written+int64(len(p)) > r.opts.MaxSize {
+		if err := r.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.written += int64(n)
+	return n, err
+}
+
+// Close flushes and closes the active segment, then waits for any
+// in-progress background compression of rotated segments to finish.
+func (r *Rotator) Close() error {
+	r.mu.Lock()
+	err := r.file.Close()
+	r.mu.Unlock()
+
+	r.compressWG.Wait()
+	return err
+}
+
+func (r *Rotator) rotateLocked() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if err := r.shiftSegmentsLocked(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.written = 0
+	return nil
+}
+
+// shiftSegmentsLocked renames path.N to path.N+1 for every existing rotated
+// segment (dropping the oldest past opts.MaxFiles), then moves the
+// just-closed active segment to path.1 and, if configured, compresses it in
+// a goroutine. The shift, the move to path.1 and the handoff to that
+// goroutine are serialized against any still-running compression of the
+// previous path.1 via compressMu, so two rotations can never race over the
+// same segment path.
+func (r *Rotator) shiftSegmentsLocked() error {
+	ext := compressionExt(r.opts.Compression)
+
+	// Block until any previous rotation's background compression of path.1
+	// has finished and removed it. Without this, a rotation that fires
+	// before the previous one's compressAndRemove goroutine is done would
+	// skip shifting a path.1 archive that doesn't exist yet (so the new
+	// compression would then overwrite it), and would rename this segment on
+	// top of the plain path.1 that goroutine is still reading, so its
+	// trailing os.Remove would delete this segment's data instead.
+	r.compressMu.Lock()
+
+	oldest := r.segmentPath(r.opts.MaxFiles, ext)
+	if _, err := os.Stat(oldest); err == nil {
+		if err := os.Remove(oldest); err != nil {
+			r.compressMu.Unlock()
+			return fmt.Errorf("pruning oldest rotated segment: %w", err)
+		}
+	}
+
+	for i := r.opts.MaxFiles - 1; i >= 1; i-- {
+		from := r.segmentPath(i, ext)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if err := os.Rename(from, r.segmentPath(i+1, ext)); err != nil {
+			r.compressMu.Unlock()
+			return fmt.Errorf("shifting rotated segment %s: %w", from, err)
+		}
+	}
+
+	rotated := r.segmentPath(1, "")
+	if err := os.Rename(r.path, rotated); err != nil {
+		r.compressMu.Unlock()
+		return fmt.Errorf("rotating %s: %w", r.path, err)
+	}
+
+	if r.opts.Compression == RotatorCompressionNone {
+		r.compressMu.Unlock()
+		return nil
+	}
+
+	r.compressWG.Add(1)
+	go func() {
+		defer r.compressWG.Done()
+		defer r.compressMu.Unlock()
+		if err := compressAndRemove(rotated, rotated+ext, r.opts.Compression); err != nil {
+			log.Warnf("could not compress rotated log segment %s: %v", rotated, err)
+		}
+	}()
+	return nil
+}
+
+func (r *Rotator) segmentPath(n int, ext string) string {
+	return fmt.Sprintf("%s.%d%s", r.path, n, ext)
+}
+
+func compressionExt(c RotatorCompression) string {
+	switch c {
+	case RotatorCompressionGzip:
+		return ".gz"
+	case RotatorCompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// compressAndRemove compresses src into dst using the given compression kind
+// and removes src once dst has been fully written.
+func compressAndRemove(src, dst string, kind RotatorCompression) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	var enc io.WriteCloser
+	switch kind {
+	case RotatorCompressionGzip:
+		enc = gzip.NewWriter(out)
+	case RotatorCompressionZstd:
+		zw, zerr := zstd.NewWriter(out)
+		if zerr != nil {
+			out.Close()
+			return zerr
+		}
+		enc = zw
+	default:
+		out.Close()
+		return fmt.Errorf("unsupported rotator compression kind: %q", kind)
+	}
+
+	if _, err := io.Copy(enc, in); err != nil {
+		enc.Close()
+		out.Close()
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filesystem
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRotatorBackToBackRotationsDoNotLoseData forces a rotation on every
+// write, so the next rotation's segment shift always races the previous
+// rotation's background compression. If that race isn't serialized, the new
+// active segment gets renamed on top of the plain path.1 the previous
+// goroutine is still reading, and that goroutine's trailing os.Remove then
+// deletes the new segment: total bytes across every surviving segment would
+// come up short of what was written.
+func TestRotatorBackToBackRotationsDoNotLoseData(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	r, err := NewRotator(path, RotatorOptions{
+		MaxSize:     1,
+		MaxFiles:    50,
+		Compression: RotatorCompressionGzip,
+	})
+	require.NoError(t, err)
+
+	var written int
+	for i := 0; i < 50; i++ {
+		chunk := []byte(fmt.Sprintf("chunk-%03d-payload\n", i))
+		n, err := r.Write(chunk)
+		require.NoError(t, err)
+		written += n
+	}
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, written, totalSegmentBytes(t, path))
+}
+
+// totalSegmentBytes sums the decompressed size of the active segment and
+// every rotated segment (compressed or not) belonging to path.
+func totalSegmentBytes(t *testing.T, path string) int {
+	t.Helper()
+
+	matches, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+
+	var total int
+	for _, m := range matches {
+		f, err := os.Open(m)
+		require.NoError(t, err)
+
+		var r io.Reader = f
+		if filepath.Ext(m) == ".gz" {
+			gz, err := gzip.NewReader(f)
+			require.NoError(t, err)
+			defer gz.Close()
+			r = gz
+		}
+
+		n, err := io.Copy(io.Discard, r)
+		require.NoError(t, err)
+		total += int(n)
+
+		f.Close()
+	}
+	return total
+}
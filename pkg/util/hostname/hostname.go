@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package hostname exposes the agent's notion of "the current host's name".
+package hostname
+
+import (
+	"context"
+	"os"
+)
+
+// Get returns the host's hostname. It falls back to the OS-reported hostname;
+// callers that need the full provider-prioritized resolution (configuration,
+// cloud metadata, FQDN, ...) should use GetWithProvider instead.
+func Get(_ context.Context) (string, error) {
+	return os.Hostname()
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// Provider is one step of the hostname resolution chain: Detect decides
+// whether this provider applies to the current host, and, if it does,
+// Hostname returns the value it found.
+type Provider interface {
+	// Name identifies this provider in Data.Provider.
+	Name() string
+	// Detect reports whether this provider has a usable hostname for the
+	// current host, caching it for a subsequent Hostname call.
+	Detect(ctx context.Context) bool
+	// Hostname returns the hostname found by the last Detect call.
+	Hostname(ctx context.Context) (string, error)
+}
+
+// defaultProviderPriority is the provider resolution order used when
+// hostname_providers_priority is unset.
+var defaultProviderPriority = []string{
+	"configuration", "file", "fargate", "gce", "azure", "alibaba", "fqdn", "os", "aws",
+}
+
+// legacyEC2PrioritizedPriority reproduces the historical behavior of the
+// ec2_prioritize_instance_id_as_hostname setting: aws moves ahead of fqdn
+// and os instead of only being tried as a last resort.
+var legacyEC2PrioritizedPriority = []string{
+	"configuration", "file", "fargate", "gce", "azure", "alibaba", "aws", "fqdn", "os",
+}
+
+// providerConstructors builds a fresh Provider for each known provider name;
+// fresh, because a Provider caches what its Detect call found for the
+// subsequent Hostname call.
+var providerConstructors = map[string]func() Provider{
+	"configuration": func() Provider { return &configurationProvider{} },
+	"file":          func() Provider { return &fileProvider{} },
+	"fargate":       func() Provider { return &fargateProvider{} },
+	"gce":           func() Provider { return &gceProvider{} },
+	"azure":         func() Provider { return &azureProvider{} },
+	"alibaba":       func() Provider { return &alibabaProvider{} },
+	"aws":           func() Provider { return &awsProvider{} },
+	"tencent":       func() Provider { return &tencentProvider{} },
+	"fqdn":          func() Provider { return &fqdnProvider{} },
+	"os":            func() Provider { return &osProvider{} },
+}
+
+func newProvider(name string) Provider {
+	return providerConstructors[name]()
+}
+
+// providerPriority returns the provider names detectHostname tries, in
+// order: hostname_providers_priority if set, otherwise
+// defaultProviderPriority, reordered for the legacy
+// ec2_prioritize_instance_id_as_hostname setting if that's set instead.
+func providerPriority() ([]string, error) {
+	if names := config.Datadog().GetStringSlice("hostname_providers_priority"); len(names) > 0 {
+		return validateProviderPriority(names)
+	}
+	if config.Datadog().GetBool("ec2_prioritize_instance_id_as_hostname") {
+		return legacyEC2PrioritizedPriority, nil
+	}
+	return defaultProviderPriority, nil
+}
+
+// validateProviderPriority rejects a hostname_providers_priority value
+// naming an unknown provider, or naming the same provider twice, at load
+// time rather than silently ignoring or re-running it.
+func validateProviderPriority(names []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := providerConstructors[name]; !ok {
+			return nil, fmt.Errorf("hostname_providers_priority: unknown provider %q", name)
+		}
+		if _, dup := seen[name]; dup {
+			return nil, fmt.Errorf("hostname_providers_priority: provider %q listed more than once", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return names, nil
+}
+
+type configurationProvider struct{ hostname string }
+
+func (p *configurationProvider) Name() string { return configProviderName }
+func (p *configurationProvider) Detect(context.Context) bool {
+	p.hostname = config.Datadog().GetString("hostname")
+	return p.hostname != ""
+}
+func (p *configurationProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type fileProvider struct{ hostname string }
+
+func (p *fileProvider) Name() string { return "hostnameFile" }
+func (p *fileProvider) Detect(context.Context) bool {
+	h, ok := readHostnameFile()
+	p.hostname = h
+	return ok
+}
+func (p *fileProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+// fargateProvider reports no hostname: Fargate tasks don't have one of
+// their own, but a task still needs a recognized "fargate" entry in the
+// resolution chain to stop before the rest look for one.
+type fargateProvider struct{}
+
+func (p *fargateProvider) Name() string                { return "fargate" }
+func (p *fargateProvider) Detect(context.Context) bool { return isFargateInstance() }
+func (p *fargateProvider) Hostname(context.Context) (string, error) {
+	return "", nil
+}
+
+type gceProvider struct{ hostname string }
+
+func (p *gceProvider) Name() string { return "gce" }
+func (p *gceProvider) Detect(ctx context.Context) bool {
+	h, err := gceGetHostname(ctx)
+	p.hostname = h
+	return err == nil && h != ""
+}
+func (p *gceProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type azureProvider struct{ hostname string }
+
+func (p *azureProvider) Name() string { return "azure" }
+func (p *azureProvider) Detect(ctx context.Context) bool {
+	h, err := azureGetHostname(ctx)
+	p.hostname = h
+	return err == nil && h != ""
+}
+func (p *azureProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type alibabaProvider struct{ hostname string }
+
+func (p *alibabaProvider) Name() string { return "alibaba" }
+func (p *alibabaProvider) Detect(ctx context.Context) bool {
+	h, err := alibabaGetHostname(ctx)
+	p.hostname = h
+	return err == nil && h != ""
+}
+func (p *alibabaProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type awsProvider struct{ hostname string }
+
+func (p *awsProvider) Name() string { return "aws" }
+func (p *awsProvider) Detect(ctx context.Context) bool {
+	h, err := ec2GetInstanceID(ctx)
+	p.hostname = h
+	return err == nil && h != ""
+}
+func (p *awsProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+// tencentProvider isn't part of defaultProviderPriority, unlike its other
+// cloud-provider siblings - it's available to opt into via
+// hostname_providers_priority, or to inject into a Resolver with
+// WithTencent, without changing GetWithProvider's default behavior.
+type tencentProvider struct{ hostname string }
+
+func (p *tencentProvider) Name() string { return "tencent" }
+func (p *tencentProvider) Detect(ctx context.Context) bool {
+	h, err := tencentGetInstanceID(ctx)
+	p.hostname = h
+	return err == nil && h != ""
+}
+func (p *tencentProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type fqdnProvider struct{ hostname string }
+
+func (p *fqdnProvider) Name() string { return "fqdn" }
+func (p *fqdnProvider) Detect(ctx context.Context) bool {
+	if !osHostnameUsable(ctx) || !config.Datadog().GetBool("hostname_fqdn") {
+		return false
+	}
+	h, err := fqdnHostname()
+	p.hostname = h
+	return err == nil && h != "" && !isDefaultHostname(h)
+}
+func (p *fqdnProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+type osProvider struct{ hostname string }
+
+func (p *osProvider) Name() string { return "os" }
+func (p *osProvider) Detect(ctx context.Context) bool {
+	if !osHostnameUsable(ctx) {
+		return false
+	}
+	h, err := osHostname()
+	p.hostname = h
+	return err == nil && h != "" && !isDefaultHostname(h)
+}
+func (p *osProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/env"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/alibaba"
+	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/azure"
+	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/gce"
+	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/tencent"
+	"github.com/DataDog/datadog-agent/pkg/util/ec2"
+	"github.com/DataDog/datadog-agent/pkg/util/fargate"
+)
+
+// configProviderName is the Provider GetWithProvider reports when the
+// hostname came straight from the "hostname" configuration setting.
+const configProviderName = "configuration"
+
+// hostnameCacheKey is the cache.Cache key GetWithProvider's result is stored
+// under, so repeated calls don't re-run the whole detection chain.
+const hostnameCacheKey = "hostname"
+
+// these are package vars, rather than direct calls, so tests can stub out
+// each provider independently without a live cloud environment.
+var (
+	isFargateInstance    = fargate.IsFargateInstance
+	ec2GetInstanceID     = ec2.GetInstanceID
+	isContainerized      = env.IsContainerized
+	gceGetHostname       = gce.GetHostname
+	azureGetHostname     = azure.GetHostname
+	alibabaGetHostname   = alibaba.GetHostname
+	tencentGetInstanceID = tencent.GetInstanceID
+	osHostname           = os.Hostname
+	fqdnHostname         = getSystemFQDN
+	osHostnameUsable     = isOSHostnameUsable
+)
+
+// Data is a resolved hostname, together with which provider in the
+// resolution chain produced it.
+type Data struct {
+	// Hostname is the resolved hostname. It's empty for providers (like
+	// Fargate) that don't have a single stable hostname of their own.
+	Hostname string
+	// Provider names which step of the resolution chain produced
+	// Hostname: "configuration", "hostnameFile", "fargate", "gce",
+	// "azure", "alibaba", "tencent", "fqdn", "os", or "aws".
+	Provider string
+}
+
+// FromConfiguration reports whether Hostname came from the "hostname"
+// configuration setting, as opposed to being detected.
+func (d Data) FromConfiguration() bool {
+	return d.Provider == configProviderName
+}
+
+// GetWithProvider returns the Agent's resolved hostname and which provider
+// produced it, trying each provider named by hostname_providers_priority (or
+// defaultProviderPriority, if unset) in order until one succeeds. The result
+// is cached for the life of the process.
+func GetWithProvider(ctx context.Context) (Data, error) {
+	if cached, found := cache.Cache.Get(cache.BuildAgentKey(hostnameCacheKey)); found {
+		return cached.(Data), nil
+	}
+
+	data, err := detectHostname(ctx)
+	if err != nil {
+		return Data{}, err
+	}
+
+	cache.Cache.Set(cache.BuildAgentKey(hostnameCacheKey), data, cache.NoExpiration)
+	return data, nil
+}
+
+func detectHostname(ctx context.Context) (Data, error) {
+	return defaultResolver.Resolve(ctx)
+}
+
+// readHostnameFile returns the trimmed contents of the file named by the
+// "hostname_file" configuration setting, if any is set and readable.
+func readHostnameFile() (string, bool) {
+	path := config.Datadog().GetString("hostname_file")
+	if path == "" {
+		return "", false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	h := strings.TrimSpace(string(content))
+	return h, h != ""
+}
+
+// isOSHostnameUsable reports whether the OS- or FQDN-reported hostname can
+// be trusted as a stable identifier: inside a container, it's usually the
+// container ID, not the host's name.
+func isOSHostnameUsable(context.Context) bool {
+	return !isContainerized()
+}
+
+// ec2DefaultHostnamePrefixes match the auto-assigned hostnames cloud
+// providers hand out before any real name is configured (e.g. AWS's
+// "ip-10-0-0-1" or a Xen "domU" guest name); a hostname starting with one
+// of these isn't a useful identifier, so detectHostname treats it as if
+// detection had failed.
+var ec2DefaultHostnamePrefixes = []string{"ip-", "domu"}
+
+func isDefaultHostname(hostname string) bool {
+	lower := strings.ToLower(hostname)
+	for _, prefix := range ec2DefaultHostnamePrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSystemFQDN returns the fully qualified domain name the resolver
+// reports for this host's hostname, reversing its first forward-confirmed
+// address back to a name.
+func getSystemFQDN() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := net.LookupHost(hostname)
+	if err != nil {
+		return "", fmt.Errorf("resolving %q: %w", hostname, err)
+	}
+
+	for _, addr := range addrs {
+		names, err := net.LookupAddr(addr)
+		if err != nil || len(names) == 0 {
+			continue
+		}
+		return strings.TrimSuffix(names[0], "."), nil
+	}
+
+	return "", fmt.Errorf("could not determine the FQDN for %q", hostname)
+}
@@ -11,19 +11,31 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config/env"
 	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/alibaba"
 	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/azure"
 	"github.com/DataDog/datadog-agent/pkg/util/cloudproviders/gce"
 	"github.com/DataDog/datadog-agent/pkg/util/ec2"
 	"github.com/DataDog/datadog-agent/pkg/util/fargate"
 )
 
+// setupHostnameFile writes content to a temp file and points the
+// "hostname_file" configuration setting at it.
+func setupHostnameFile(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hostname")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	configmock.New(t).SetWithoutSource("hostname_file", path)
+}
+
 // testCase represents a test scenario for hostname resolution. The logic goes down a list trying different provider
 // that might or might not be coupled. Each field represents if the corresponding provider should be successful or not
 // and which one we expect at the end.
@@ -34,6 +46,7 @@ type testCase struct {
 	fargate          bool
 	GCE              bool
 	azure            bool
+	alibaba          bool
 	container        bool
 	FQDN             bool
 	FQDNEC2          bool
@@ -52,6 +65,7 @@ func setupHostnameTest(t *testing.T, tc testCase) {
 		isContainerized = env.IsContainerized
 		gceGetHostname = gce.GetHostname
 		azureGetHostname = azure.GetHostname
+		alibabaGetHostname = alibaba.GetHostname
 		osHostname = os.Hostname
 		fqdnHostname = getSystemFQDN
 		osHostnameUsable = isOSHostnameUsable
@@ -85,6 +99,12 @@ func setupHostnameTest(t *testing.T, tc testCase) {
 		azureGetHostname = func(context.Context) (string, error) { return "", fmt.Errorf("some error") }
 	}
 
+	if tc.alibaba {
+		alibabaGetHostname = func(context.Context) (string, error) { return "hostname-from-alibaba", nil }
+	} else {
+		alibabaGetHostname = func(context.Context) (string, error) { return "", fmt.Errorf("some error") }
+	}
+
 	if tc.FQDN || tc.FQDNEC2 {
 		// making isOSHostnameUsable return true
 		osHostnameUsable = func(context.Context) bool { return true }
@@ -241,6 +261,22 @@ func TestHostnamePrority(t *testing.T) {
 			expectedHostname: "hostname-from-azure",
 			expectedProvider: "azure",
 		},
+		{
+			name:             "hostname from Alibaba",
+			configHostname:   false,
+			hostnameFile:     false,
+			fargate:          false,
+			GCE:              false,
+			azure:            false,
+			alibaba:          true,
+			container:        true,
+			FQDN:             true,
+			OS:               true,
+			EC2:              true,
+			EC2Proritized:    true, // alibaba still wins, same as GCE/Azure above
+			expectedHostname: "hostname-from-alibaba",
+			expectedProvider: "alibaba",
+		},
 		{
 			name:             "hostname from FQDN",
 			configHostname:   false,
@@ -355,3 +391,43 @@ func TestHostnamePrority(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderPriorityReordering(t *testing.T) {
+	setupHostnameTest(t, testCase{GCE: true, OS: true})
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("hostname_providers_priority", []string{"os", "gce"})
+
+	data, err := GetWithProvider(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-os", data.Hostname)
+	assert.Equal(t, "os", data.Provider)
+}
+
+func TestProviderPriorityDisablesProvider(t *testing.T) {
+	setupHostnameTest(t, testCase{GCE: true, OS: true})
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("hostname_providers_priority", []string{"os"})
+
+	data, err := GetWithProvider(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-os", data.Hostname)
+	assert.Equal(t, "os", data.Provider)
+}
+
+func TestProviderPriorityRejectsUnknownName(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("hostname_providers_priority", []string{"os", "nope"})
+
+	_, err := GetWithProvider(context.TODO())
+	assert.ErrorContains(t, err, `unknown provider "nope"`)
+}
+
+func TestProviderPriorityRejectsDuplicateName(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("hostname_providers_priority", []string{"os", "gce", "os"})
+
+	_, err := GetWithProvider(context.TODO())
+	assert.ErrorContains(t, err, `provider "os" listed more than once`)
+}
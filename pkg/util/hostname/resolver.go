@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"fmt"
+)
+
+// fallbackProviderName is the Provider GetWithProvider (or a Resolver built
+// with NewResolver) reports when every other provider failed and the
+// fallback function set by SetFallbackFunc, or WithFallback, produced one.
+const fallbackProviderName = "fallback"
+
+// Resolver resolves a host's name by trying a sequence of providers, in
+// order, until one succeeds. GetWithProvider wraps a process-wide Resolver;
+// NewResolver builds an isolated one, for embedders that need a different
+// provider set, order, or fallback without touching global state (the
+// providerPriority configuration or the process-wide cache.Cache).
+type Resolver interface {
+	Resolve(ctx context.Context) (Data, error)
+}
+
+// fallbackFunc is the process-wide Resolver's final fallback, set by
+// SetFallbackFunc; nil means GetWithProvider just returns an error when
+// every provider fails.
+var fallbackFunc func(ctx context.Context) (string, error)
+
+// SetFallbackFunc overrides the function GetWithProvider falls back to when
+// every provider in its resolution chain fails to produce a hostname. It's
+// meant for embedders with a final source the built-in providers don't
+// cover (e.g. a Kubernetes downward-API file, or a Nomad allocation ID);
+// pass nil to restore the default of no fallback.
+func SetFallbackFunc(fn func(ctx context.Context) (string, error)) {
+	fallbackFunc = fn
+}
+
+// defaultResolverType is the process-wide Resolver GetWithProvider wraps.
+// Unlike a Resolver built with NewResolver, its provider order is read from
+// configuration (providerPriority) on every call, so hostname_providers_priority
+// and the package-level provider stubs used by tests keep working.
+type defaultResolverType struct{}
+
+func (defaultResolverType) Resolve(ctx context.Context) (Data, error) {
+	order, err := providerPriority()
+	if err != nil {
+		return Data{}, err
+	}
+
+	if data, ok := resolveFromOrder(ctx, order, providerConstructors); ok {
+		return data, nil
+	}
+
+	if fallbackFunc != nil {
+		if h, err := fallbackFunc(ctx); err == nil && h != "" {
+			return Data{Hostname: h, Provider: fallbackProviderName}, nil
+		}
+	}
+
+	return Data{}, fmt.Errorf("unable to reliably determine the host's name")
+}
+
+var defaultResolver Resolver = defaultResolverType{}
+
+// resolveFromOrder tries each named provider in order, returning the first
+// one that detects a hostname.
+func resolveFromOrder(ctx context.Context, order []string, providers map[string]func() Provider) (Data, bool) {
+	for _, name := range order {
+		ctor, ok := providers[name]
+		if !ok {
+			continue
+		}
+		p := ctor()
+		if !p.Detect(ctx) {
+			continue
+		}
+		h, err := p.Hostname(ctx)
+		if err != nil {
+			continue
+		}
+		return Data{Hostname: h, Provider: p.Name()}, true
+	}
+	return Data{}, false
+}
+
+// Option configures a Resolver built by NewResolver.
+type Option func(*resolverOptions)
+
+type resolverOptions struct {
+	order     []string
+	providers map[string]func() Provider
+	fallback  func(ctx context.Context) (string, error)
+}
+
+func newResolverOptions() *resolverOptions {
+	providers := make(map[string]func() Provider, len(providerConstructors))
+	for name, ctor := range providerConstructors {
+		providers[name] = ctor
+	}
+	return &resolverOptions{
+		order:     append([]string(nil), defaultProviderPriority...),
+		providers: providers,
+	}
+}
+
+// funcProvider adapts a simple (ctx) (string, bool) detect function into a
+// Provider, so the With* Options below don't each need their own type.
+type funcProvider struct {
+	name     string
+	detect   func(ctx context.Context) (string, bool)
+	hostname string
+}
+
+func (p *funcProvider) Name() string { return p.name }
+func (p *funcProvider) Detect(ctx context.Context) bool {
+	h, ok := p.detect(ctx)
+	p.hostname = h
+	return ok
+}
+func (p *funcProvider) Hostname(context.Context) (string, error) { return p.hostname, nil }
+
+// WithConfigHostname overrides the "configuration" provider.
+func WithConfigHostname(fn func() string) Option {
+	return func(o *resolverOptions) {
+		o.providers["configuration"] = func() Provider {
+			return &funcProvider{name: configProviderName, detect: func(context.Context) (string, bool) {
+				h := fn()
+				return h, h != ""
+			}}
+		}
+	}
+}
+
+// WithHostnameFile overrides the "file" provider.
+func WithHostnameFile(fn func() (string, bool)) Option {
+	return func(o *resolverOptions) {
+		o.providers["file"] = func() Provider {
+			return &funcProvider{name: "hostnameFile", detect: func(context.Context) (string, bool) { return fn() }}
+		}
+	}
+}
+
+// WithFargate overrides the "fargate" provider.
+func WithFargate(fn func() bool) Option {
+	return func(o *resolverOptions) {
+		o.providers["fargate"] = func() Provider {
+			return &funcProvider{name: "fargate", detect: func(context.Context) (string, bool) { return "", fn() }}
+		}
+	}
+}
+
+// WithGCE overrides the "gce" provider.
+func WithGCE(fn func(context.Context) (string, error)) Option {
+	return withCloudProvider("gce", fn)
+}
+
+// WithAzure overrides the "azure" provider.
+func WithAzure(fn func(context.Context) (string, error)) Option {
+	return withCloudProvider("azure", fn)
+}
+
+// WithEC2 overrides the "aws" provider.
+func WithEC2(fn func(context.Context) (string, error)) Option {
+	return withCloudProvider("aws", fn)
+}
+
+// WithTencent overrides the "tencent" provider.
+func WithTencent(fn func(context.Context) (string, error)) Option {
+	return withCloudProvider("tencent", fn)
+}
+
+func withCloudProvider(name string, fn func(context.Context) (string, error)) Option {
+	return func(o *resolverOptions) {
+		o.providers[name] = func() Provider {
+			return &funcProvider{name: name, detect: func(ctx context.Context) (string, bool) {
+				h, err := fn(ctx)
+				return h, err == nil && h != ""
+			}}
+		}
+	}
+}
+
+// WithFQDN overrides the "fqdn" provider.
+func WithFQDN(fn func() (string, error)) Option {
+	return func(o *resolverOptions) {
+		o.providers["fqdn"] = func() Provider {
+			return &funcProvider{name: "fqdn", detect: func(context.Context) (string, bool) {
+				h, err := fn()
+				return h, err == nil && h != "" && !isDefaultHostname(h)
+			}}
+		}
+	}
+}
+
+// WithOS overrides the "os" provider.
+func WithOS(fn func() (string, error)) Option {
+	return func(o *resolverOptions) {
+		o.providers["os"] = func() Provider {
+			return &funcProvider{name: "os", detect: func(context.Context) (string, bool) {
+				h, err := fn()
+				return h, err == nil && h != "" && !isDefaultHostname(h)
+			}}
+		}
+	}
+}
+
+// WithFallback sets the Resolver's final fallback, tried if every provider
+// in its order fails to produce a hostname.
+func WithFallback(fn func(ctx context.Context) (string, error)) Option {
+	return func(o *resolverOptions) { o.fallback = fn }
+}
+
+type resolver struct {
+	order     []string
+	providers map[string]func() Provider
+	fallback  func(ctx context.Context) (string, error)
+}
+
+func (r *resolver) Resolve(ctx context.Context) (Data, error) {
+	if data, ok := resolveFromOrder(ctx, r.order, r.providers); ok {
+		return data, nil
+	}
+
+	if r.fallback != nil {
+		if h, err := r.fallback(ctx); err == nil && h != "" {
+			return Data{Hostname: h, Provider: fallbackProviderName}, nil
+		}
+	}
+
+	return Data{}, fmt.Errorf("unable to reliably determine the host's name")
+}
+
+// NewResolver builds an isolated Resolver: unlike GetWithProvider, it
+// doesn't read hostname_providers_priority, doesn't share the process-wide
+// cache.Cache, and its providers default to the same built-in ones
+// GetWithProvider uses unless overridden by an Option. This is meant for
+// embedders (or callers like the trace-agent) that need their own hostname
+// source without racing the global resolution chain or its cache.
+func NewResolver(opts ...Option) Resolver {
+	ro := newResolverOptions()
+	for _, opt := range opts {
+		opt(ro)
+	}
+	return &resolver{order: ro.order, providers: ro.providers, fallback: ro.fallback}
+}
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFallbackFunc(t *testing.T) {
+	setupHostnameTest(t, testCase{})
+	t.Cleanup(func() { SetFallbackFunc(nil) })
+
+	SetFallbackFunc(func(context.Context) (string, error) {
+		return "hostname-from-fallback", nil
+	})
+
+	data, err := GetWithProvider(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-fallback", data.Hostname)
+	assert.Equal(t, fallbackProviderName, data.Provider)
+}
+
+func TestSetFallbackFuncNotUsedWhenAProviderSucceeds(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	t.Cleanup(func() { SetFallbackFunc(nil) })
+
+	SetFallbackFunc(func(context.Context) (string, error) {
+		return "hostname-from-fallback", nil
+	})
+
+	data, err := GetWithProvider(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-os", data.Hostname)
+	assert.Equal(t, "os", data.Provider)
+}
+
+func TestNewResolverIsolatedFromGlobalState(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+
+	r := NewResolver(
+		WithOS(func() (string, error) { return "", fmt.Errorf("no os hostname here") }),
+		WithFallback(func(context.Context) (string, error) { return "hostname-from-embedder", nil }),
+	)
+
+	data, err := r.Resolve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-embedder", data.Hostname)
+	assert.Equal(t, fallbackProviderName, data.Provider)
+
+	// GetWithProvider, using the process-wide resolver, is unaffected.
+	global, err := GetWithProvider(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-os", global.Hostname)
+}
+
+func TestNewResolverOverridesProvider(t *testing.T) {
+	setupHostnameTest(t, testCase{})
+
+	r := NewResolver(
+		WithConfigHostname(func() string { return "" }),
+		WithHostnameFile(func() (string, bool) { return "", false }),
+		WithFargate(func() bool { return false }),
+		WithGCE(func(context.Context) (string, error) { return "", fmt.Errorf("no gce") }),
+		WithAzure(func(context.Context) (string, error) { return "", fmt.Errorf("no azure") }),
+		WithEC2(func(context.Context) (string, error) { return "hostname-from-injected-ec2", nil }),
+	)
+
+	data, err := r.Resolve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "hostname-from-injected-ec2", data.Hostname)
+	assert.Equal(t, "aws", data.Provider)
+}
+
+func TestNewResolverWithTencent(t *testing.T) {
+	setupHostnameTest(t, testCase{})
+
+	r := NewResolver(
+		WithConfigHostname(func() string { return "" }),
+		WithHostnameFile(func() (string, bool) { return "", false }),
+		WithFargate(func() bool { return false }),
+		WithGCE(func(context.Context) (string, error) { return "", fmt.Errorf("no gce") }),
+		WithAzure(func(context.Context) (string, error) { return "", fmt.Errorf("no azure") }),
+		WithTencent(func(context.Context) (string, error) { return "hostname-from-injected-tencent", nil }),
+	)
+
+	// tencent isn't in defaultProviderPriority, so it isn't tried even
+	// though it's been overridden.
+	data, err := r.Resolve(context.TODO())
+	assert.Error(t, err)
+	assert.Empty(t, data.Hostname)
+}
+
+func TestNewResolverNoProviderNoFallback(t *testing.T) {
+	setupHostnameTest(t, testCase{})
+
+	r := NewResolver(
+		WithConfigHostname(func() string { return "" }),
+		WithHostnameFile(func() (string, bool) { return "", false }),
+		WithFargate(func() bool { return false }),
+		WithGCE(func(context.Context) (string, error) { return "", fmt.Errorf("no gce") }),
+		WithAzure(func(context.Context) (string, error) { return "", fmt.Errorf("no azure") }),
+		WithEC2(func(context.Context) (string, error) { return "", fmt.Errorf("no ec2") }),
+		WithFQDN(func() (string, error) { return "", fmt.Errorf("no fqdn") }),
+		WithOS(func() (string, error) { return "", fmt.Errorf("no os") }),
+	)
+
+	_, err := r.Resolve(context.TODO())
+	assert.Error(t, err)
+}
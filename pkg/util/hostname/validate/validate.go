@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package validate sanitizes and validates hostnames.
+package validate
+
+import "strings"
+
+// cleanHostnameDirReplacer replaces characters that are unsafe to use in a
+// single path segment on at least one of our supported platforms (path
+// separators, and ':' which Windows rejects in file names).
+var cleanHostnameDirReplacer = strings.NewReplacer(
+	"/", "_",
+	"\\", "_",
+	":", "_",
+)
+
+// CleanHostnameDir returns hostname with any character that would be unsafe
+// to use as a single path segment replaced by an underscore, so it can be
+// used as a directory name (e.g. the flare archive's root directory).
+func CleanHostnameDir(hostname string) string {
+	return cleanHostnameDirReplacer.Replace(hostname)
+}
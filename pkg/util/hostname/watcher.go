@@ -0,0 +1,206 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const watcherTelemetrySubsystem = "hostname"
+
+var watcherChanges = telemetry.NewCounter(
+	watcherTelemetrySubsystem,
+	"changes_total",
+	[]string{"provider", "policy"},
+	"Number of times the hostname Watcher detected a change from the cached hostname, by new provider and refresh policy",
+)
+
+// RefreshPolicy controls what a Watcher does once it has detected that the
+// resolved hostname changed.
+type RefreshPolicy string
+
+const (
+	// RefreshPolicyLog only reports the change (log, telemetry, Subscribe
+	// channels); the cached hostname is left untouched.
+	RefreshPolicyLog RefreshPolicy = "log"
+	// RefreshPolicyUpdate additionally replaces the cached hostname, so the
+	// next GetWithProvider call picks up the new value.
+	RefreshPolicyUpdate RefreshPolicy = "update"
+	// RefreshPolicyRequireManual behaves like RefreshPolicyLog: it's a
+	// distinct value so operators can express, in configuration, that a
+	// human must decide whether to adopt the new name, rather than
+	// overloading RefreshPolicyLog for that meaning.
+	RefreshPolicyRequireManual RefreshPolicy = "require_manual"
+)
+
+// Change describes a hostname resolved by a Watcher that differs from the
+// previously cached one.
+type Change struct {
+	Old        string
+	New        string
+	Provider   string
+	DetectedAt time.Time
+}
+
+// Watcher periodically re-resolves the hostname with a Resolver and reports
+// any change from the currently cached value. It's disabled (Start is a
+// no-op) unless built with a positive interval.
+type Watcher struct {
+	interval time.Duration
+	policy   RefreshPolicy
+	resolver Resolver
+
+	subsMu sync.Mutex
+	subs   []chan Change
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatcher builds a Watcher that re-resolves the hostname with resolver
+// every interval, applying policy to any change it detects. An interval of 0
+// disables automatic ticking; Check can still be called directly, e.g. from
+// a test. A nil resolver defaults to the process-wide resolver GetWithProvider
+// uses.
+func NewWatcher(interval time.Duration, policy RefreshPolicy, resolver Resolver) *Watcher {
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+	return &Watcher{
+		interval: interval,
+		policy:   policy,
+		resolver: resolver,
+	}
+}
+
+// NewWatcherFromConfig builds a Watcher using the hostname_refresh_interval
+// (seconds; 0 disables it) and hostname_refresh_policy ("log", "update", or
+// "require_manual"; defaults to "log") configuration settings.
+func NewWatcherFromConfig() *Watcher {
+	interval := time.Duration(config.Datadog().GetInt("hostname_refresh_interval")) * time.Second
+	policy := RefreshPolicy(config.Datadog().GetString("hostname_refresh_policy"))
+	if policy == "" {
+		policy = RefreshPolicyLog
+	}
+	return NewWatcher(interval, policy, defaultResolver)
+}
+
+// Subscribe returns a channel that receives every Change this Watcher
+// detects from here on. The channel is buffered (capacity 1): while a
+// subscriber hasn't drained a Change, every later one is dropped for it
+// rather than blocking the Watcher.
+func (w *Watcher) Subscribe() <-chan Change {
+	ch := make(chan Change, 1)
+	w.subsMu.Lock()
+	w.subs = append(w.subs, ch)
+	w.subsMu.Unlock()
+	return ch
+}
+
+// Start runs the Watcher's ticking loop until ctx is done or Stop is called.
+// It's a no-op if the Watcher was built with a non-positive interval.
+func (w *Watcher) Start(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Stop halts a Watcher started with Start, blocking until its loop goroutine
+// has exited. It's a no-op if the Watcher was never started.
+func (w *Watcher) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+// Check re-resolves the hostname once, comparing it against the currently
+// cached value. On a change, it logs a warning, increments a telemetry
+// counter, notifies subscribers, and - if the Watcher's policy is
+// RefreshPolicyUpdate - replaces the cached hostname.
+func (w *Watcher) Check(ctx context.Context) {
+	var oldHostname string
+	cached, primed := cache.Cache.Get(cache.BuildAgentKey(hostnameCacheKey))
+	if primed {
+		oldHostname = cached.(Data).Hostname
+	}
+
+	data, err := w.resolver.Resolve(ctx)
+	if err != nil {
+		log.Warnf("hostname watcher: could not re-resolve the hostname: %s", err)
+		return
+	}
+
+	// Nothing was cached yet (e.g. GetWithProvider was never called before
+	// this tick): there's no prior hostname to have changed from, so treat
+	// this resolution as the baseline rather than reporting a spurious
+	// change from "".
+	if !primed {
+		return
+	}
+
+	if data.Hostname == oldHostname {
+		return
+	}
+
+	change := Change{
+		Old:        oldHostname,
+		New:        data.Hostname,
+		Provider:   data.Provider,
+		DetectedAt: time.Now(),
+	}
+
+	log.Warnf("detected a hostname change from %q to %q (provider: %s)", change.Old, change.New, change.Provider)
+	watcherChanges.Inc(data.Provider, string(w.policy))
+
+	if w.policy == RefreshPolicyUpdate {
+		cache.Cache.Set(cache.BuildAgentKey(hostnameCacheKey), data, cache.NoExpiration)
+	}
+
+	w.notify(change)
+}
+
+func (w *Watcher) notify(change Change) {
+	w.subsMu.Lock()
+	defer w.subsMu.Unlock()
+	for _, sub := range w.subs {
+		select {
+		case sub <- change:
+		default:
+			// the subscriber hasn't drained its previous change yet; drop
+			// this one rather than block the watcher on a slow reader.
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package hostname
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+)
+
+func primeHostnameCache(t *testing.T) {
+	t.Helper()
+	_, err := GetWithProvider(context.TODO())
+	require.NoError(t, err)
+}
+
+func cachedHostname(t *testing.T) string {
+	t.Helper()
+	cached, found := cache.Cache.Get(cache.BuildAgentKey(hostnameCacheKey))
+	require.True(t, found)
+	return cached.(Data).Hostname
+}
+
+func TestWatcherDetectsChangeAndNotifiesSubscribers(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	primeHostnameCache(t)
+
+	w := NewWatcher(0, RefreshPolicyLog, defaultResolver)
+	ch := w.Subscribe()
+
+	osHostname = func() (string, error) { return "hostname-from-os-renamed", nil }
+	w.Check(context.TODO())
+
+	select {
+	case change := <-ch:
+		assert.Equal(t, "hostname-from-os", change.Old)
+		assert.Equal(t, "hostname-from-os-renamed", change.New)
+		assert.Equal(t, "os", change.Provider)
+	default:
+		t.Fatal("expected a Change to be delivered to the subscriber")
+	}
+
+	// RefreshPolicyLog only reports the change; the cache keeps the old value.
+	assert.Equal(t, "hostname-from-os", cachedHostname(t))
+}
+
+func TestWatcherUpdatePolicyReplacesCache(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	primeHostnameCache(t)
+
+	w := NewWatcher(0, RefreshPolicyUpdate, defaultResolver)
+	ch := w.Subscribe()
+
+	osHostname = func() (string, error) { return "hostname-from-os-renamed", nil }
+	w.Check(context.TODO())
+	<-ch
+
+	assert.Equal(t, "hostname-from-os-renamed", cachedHostname(t))
+}
+
+func TestWatcherRequireManualPolicyDoesNotUpdateCache(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	primeHostnameCache(t)
+
+	w := NewWatcher(0, RefreshPolicyRequireManual, defaultResolver)
+	ch := w.Subscribe()
+
+	osHostname = func() (string, error) { return "hostname-from-os-renamed", nil }
+	w.Check(context.TODO())
+	<-ch
+
+	assert.Equal(t, "hostname-from-os", cachedHostname(t))
+}
+
+func TestWatcherColdStartDoesNotNotify(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	// Deliberately not calling primeHostnameCache: the cache starts empty,
+	// as it would before the first GetWithProvider call.
+
+	w := NewWatcher(0, RefreshPolicyLog, defaultResolver)
+	ch := w.Subscribe()
+
+	w.Check(context.TODO())
+
+	select {
+	case change := <-ch:
+		t.Fatalf("did not expect a Change on a cold start, got %+v", change)
+	default:
+	}
+}
+
+func TestWatcherNoChangeNoNotification(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	primeHostnameCache(t)
+
+	w := NewWatcher(0, RefreshPolicyLog, defaultResolver)
+	ch := w.Subscribe()
+
+	w.Check(context.TODO())
+
+	select {
+	case <-ch:
+		t.Fatal("did not expect a Change when the hostname hasn't changed")
+	default:
+	}
+}
+
+func TestWatcherStartStopTicks(t *testing.T) {
+	setupHostnameTest(t, testCase{OS: true})
+	primeHostnameCache(t)
+
+	w := NewWatcher(time.Millisecond, RefreshPolicyUpdate, defaultResolver)
+	ch := w.Subscribe()
+
+	osHostname = func() (string, error) { return "hostname-from-os-renamed", nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.Start(ctx)
+	defer w.Stop()
+
+	select {
+	case change := <-ch:
+		assert.Equal(t, "hostname-from-os-renamed", change.New)
+	case <-time.After(time.Second):
+		t.Fatal("expected Start's ticking loop to detect the change")
+	}
+}
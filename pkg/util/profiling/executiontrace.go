@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// executionTraceCollector periodically captures a short runtime/trace
+// execution trace window and uploads it to the profiling intake as its own
+// profile type, on its own ticker independent of the pprof profiler's
+// Period/CPUDuration.
+//
+// Only one runtime/trace trace can be active process-wide at a time, so mu
+// also guards against a slow upload still being in flight when the next tick
+// fires: captureAndUpload skips (rather than blocks on) a window that would
+// overlap the previous one, so a slow intake can't cause traces to pile up.
+type executionTraceCollector struct {
+	client   *http.Client
+	url      string
+	duration time.Duration
+
+	mu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startExecutionTraceCollector starts a collector capturing a
+// settings.ExecutionTraceDuration-long trace window every
+// settings.ExecutionTracePeriod, uploading each one over client. Call
+// stopCollecting to stop it.
+func startExecutionTraceCollector(settings Settings, client *http.Client) *executionTraceCollector {
+	c := &executionTraceCollector{
+		client:   client,
+		url:      settings.ProfilingURL,
+		duration: settings.ExecutionTraceDuration,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.run(settings.ExecutionTracePeriod)
+	return c
+}
+
+func (c *executionTraceCollector) run(period time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.captureAndUpload()
+		}
+	}
+}
+
+func (c *executionTraceCollector) captureAndUpload() {
+	if !c.mu.TryLock() {
+		log.Debugf("profiling: skipping execution trace window, the previous one is still capturing or uploading")
+		return
+	}
+	defer c.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		log.Warnf("profiling: could not start execution trace: %s", err)
+		return
+	}
+	time.Sleep(c.duration)
+	trace.Stop()
+
+	if err := c.upload(buf.Bytes()); err != nil {
+		log.Warnf("profiling: could not upload execution trace: %s", err)
+	}
+}
+
+// upload POSTs data to the profiling intake as a multipart "go_execution_trace.trace" part,
+// the same field-naming convention the pprof profiler uses for its own profile types.
+func (c *executionTraceCollector) upload(data []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("data[go_execution_trace.trace]", "go_execution_trace.trace")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d uploading execution trace", resp.StatusCode)
+	}
+	return nil
+}
+
+// stopCollecting stops the collector's ticker and waits for any in-flight
+// capture/upload to finish.
+func (c *executionTraceCollector) stopCollecting() {
+	close(c.stop)
+	<-c.done
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package profiling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionTraceCollectorUploadsWindows(t *testing.T) {
+	var uploads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&uploads, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		ProfilingURL:           server.URL,
+		ExecutionTracePeriod:   10 * time.Millisecond,
+		ExecutionTraceDuration: 5 * time.Millisecond,
+	}
+
+	c := startExecutionTraceCollector(settings, server.Client())
+	defer c.stopCollecting()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&uploads) >= 2
+	}, time.Second, 5*time.Millisecond, "expected at least 2 execution trace windows to be uploaded")
+}
+
+func TestExecutionTraceCollectorSkipsOverlappingWindows(t *testing.T) {
+	var uploads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&uploads, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		ProfilingURL: server.URL,
+		// A period shorter than the window duration forces every tick after
+		// the first to land while a capture is still in flight; those ticks
+		// must be skipped rather than overlap, since only one runtime/trace
+		// trace can be active process-wide at a time.
+		ExecutionTracePeriod:   5 * time.Millisecond,
+		ExecutionTraceDuration: 50 * time.Millisecond,
+	}
+
+	c := startExecutionTraceCollector(settings, server.Client())
+	time.Sleep(120 * time.Millisecond)
+	c.stopCollecting()
+
+	// Back-to-back 50ms windows starting at ~5ms, ~55ms and ~105ms fit in the
+	// 120ms sleep, and stopCollecting waits for the third to upload.
+	assert.LessOrEqual(t, atomic.LoadInt32(&uploads), int32(3), "overlapping ticks should have been skipped, not queued")
+}
+
+func TestExecutionTraceCollectorStopWaitsForInFlightCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		ProfilingURL:           server.URL,
+		ExecutionTracePeriod:   1 * time.Millisecond,
+		ExecutionTraceDuration: 20 * time.Millisecond,
+	}
+
+	c := startExecutionTraceCollector(settings, server.Client())
+	time.Sleep(5 * time.Millisecond)
+	c.stopCollecting()
+
+	// stopCollecting must not return until the in-flight capture/upload has
+	// finished; acquiring the mutex here should never block.
+	locked := c.mu.TryLock()
+	assert.True(t, locked, "expected the collector's mutex to be free once stopCollecting returns")
+	if locked {
+		c.mu.Unlock()
+	}
+}
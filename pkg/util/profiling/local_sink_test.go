@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package profiling
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSinkWritesRequestBodyToDisk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	sink, err := newLocalSink(dir)
+	require.NoError(t, err)
+	sink.maxFileSize = 1024
+
+	client := &http.Client{Transport: sink}
+	resp, err := client.Post(server.URL, "application/octet-stream", bytes.NewReader([]byte("profile-payload")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, sink.flush())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Equal(t, "profile-payload", string(content))
+}
+
+func TestLocalSinkRotatesPastMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := newLocalSink(dir)
+	require.NoError(t, err)
+	sink.maxFileSize = 4
+
+	require.NoError(t, sink.write([]byte("aaaaa")))
+	require.NoError(t, sink.write([]byte("bbbbb")))
+	require.NoError(t, sink.flush())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
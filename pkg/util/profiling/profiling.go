@@ -8,6 +8,10 @@ package profiling
 
 import (
 	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
@@ -16,9 +20,25 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
 
+// minGoVersionForExecutionTrace is the earliest Go runtime the execution
+// trace collector is enabled on; older runtimes lack the execution tracer
+// improvements (e.g. bounded trace buffer flushing) it relies on to keep
+// upload size in check.
+const minGoVersionForExecutionTrace = "go1.19"
+
 var (
 	mu      sync.RWMutex
 	running bool
+
+	// activeSink is the local file sink wired up by the last Start call that
+	// set Settings.LocalSinkDir, if any. It's kept around so Flush and Stop
+	// can close its current file.
+	activeSink *localSink
+
+	// activeExecTrace is the execution trace collector started by the last
+	// Start call that set Settings.WithExecutionTrace, if any. It's kept
+	// around so Stop can shut it down.
+	activeExecTrace *executionTraceCollector
 )
 
 const (
@@ -50,6 +70,15 @@ func Start(settings Settings) error {
 		types = append(types, profiler.MutexProfile)
 	}
 
+	collectExecTrace := false
+	if settings.WithExecutionTrace {
+		if goVersionAtLeast(minGoVersionForExecutionTrace) {
+			collectExecTrace = true
+		} else {
+			log.Warnf("profiling: execution trace collection requires at least %s, running %s; skipping it", minGoVersionForExecutionTrace, runtime.Version())
+		}
+	}
+
 	options := []profiler.Option{
 		profiler.WithURL(settings.ProfilingURL),
 		profiler.WithEnv(settings.Env),
@@ -66,6 +95,17 @@ func Start(settings Settings) error {
 		options = append(options, profiler.WithUDS(settings.Socket))
 	}
 
+	execTraceClient := http.DefaultClient
+	if settings.LocalSinkDir != "" {
+		sink, err := newLocalSink(settings.LocalSinkDir)
+		if err != nil {
+			return err
+		}
+		activeSink = sink
+		execTraceClient = &http.Client{Transport: sink}
+		options = append(options, profiler.WithHTTPClient(execTraceClient))
+	}
+
 	// If block or mutex profiling was configured via runtime configuration, pass current
 	// values to profiler. This prevents profiler from resetting mutex profile rate to the
 	// default value; and enables collection of blocking profile data if it is enabled.
@@ -85,13 +125,17 @@ func Start(settings Settings) error {
 	}
 
 	err := profiler.Start(options...)
+	if err != nil {
+		return err
+	}
 
-	if err == nil {
-		running = true
-		log.Debugf("Profiling started! Submitting to: %s", settings.ProfilingURL)
+	running = true
+	if collectExecTrace {
+		activeExecTrace = startExecutionTraceCollector(settings, execTraceClient)
 	}
+	log.Debugf("Profiling started! Submitting to: %s", settings.ProfilingURL)
 
-	return err
+	return nil
 }
 
 // Stop stops the profiler if running - idempotent; this function is thread-safe.
@@ -102,6 +146,29 @@ func Stop() {
 		profiler.Stop()
 		running = false
 	}
+	if activeExecTrace != nil {
+		activeExecTrace.stopCollecting()
+		activeExecTrace = nil
+	}
+	if activeSink != nil {
+		if err := activeSink.flush(); err != nil {
+			log.Warnf("profiling: could not flush local sink on stop: %s", err)
+		}
+		activeSink = nil
+	}
+}
+
+// Flush closes the current file of the local sink set up via
+// Settings.LocalSinkDir, if any, so its contents are safely on disk without
+// stopping the profiler. It's a no-op if no local sink is configured; this
+// function is thread-safe.
+func Flush() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if activeSink == nil {
+		return nil
+	}
+	return activeSink.flush()
 }
 
 // IsRunning returns true if the profiler is running; this function is thread-safe.
@@ -112,6 +179,51 @@ func IsRunning() bool {
 	return v
 }
 
+// goVersionAtLeast reports whether the running Go runtime is at least as new
+// as minVersion, a "goX.Y" string such as "go1.19". It's conservative: if
+// runtime.Version() doesn't parse (e.g. a non-release "devel" build), it
+// returns true rather than disabling the feature it gates.
+func goVersionAtLeast(minVersion string) bool {
+	return goVersionAtLeastForVersion(minVersion, runtime.Version())
+}
+
+// goVersionAtLeastForVersion is goVersionAtLeast with the running version
+// passed in explicitly, so tests can exercise it without depending on the Go
+// version the test binary was built with.
+func goVersionAtLeastForVersion(minVersion, gotVersion string) bool {
+	wantMajor, wantMinor, ok := parseGoVersion(minVersion)
+	if !ok {
+		return true
+	}
+	gotMajor, gotMinor, ok := parseGoVersion(gotVersion)
+	if !ok {
+		return true
+	}
+	if gotMajor != wantMajor {
+		return gotMajor > wantMajor
+	}
+	return gotMinor >= wantMinor
+}
+
+// parseGoVersion extracts the major and minor version numbers from a
+// "goX.Y" or "goX.Y.Z" version string.
+func parseGoVersion(version string) (major int, minor int, ok bool) {
+	version = strings.TrimPrefix(version, "go")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
 // GetBaseProfilingTags returns the standard tags that should be included in all internal profiling
 func GetBaseProfilingTags(extraTags []string) []string {
 	tags := make([]string, 0, len(extraTags)+2)
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package profiling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	major, minor, ok := parseGoVersion("go1.21.3")
+	assert.True(t, ok)
+	assert.Equal(t, 1, major)
+	assert.Equal(t, 21, minor)
+
+	_, _, ok = parseGoVersion("devel")
+	assert.False(t, ok)
+}
+
+func TestGoVersionAtLeast(t *testing.T) {
+	assert.True(t, goVersionAtLeast("go1.0"))
+	assert.False(t, goVersionAtLeastForVersion("go1.99", "go1.21.3"))
+	assert.True(t, goVersionAtLeastForVersion("go1.19", "go1.21.3"))
+	assert.True(t, goVersionAtLeastForVersion("go1.21", "go1.21.3"))
+}
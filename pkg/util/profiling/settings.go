@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package profiling
+
+import "time"
+
+const (
+	defaultProfilePeriod          = 1 * time.Minute
+	defaultCPUDuration            = 15 * time.Second
+	defaultExecutionTraceDuration = 1 * time.Second
+)
+
+// Settings when specified are used to initialize the internal profiler.
+type Settings struct {
+	// ProfilingURL is the profile intake URL.
+	ProfilingURL string
+
+	// Env is the name of the environment to use in the app.
+	Env string
+
+	// Service is the name of a profiled piece of software.
+	Service string
+
+	// Tags specifies a list of tags to be applied to profiles.
+	Tags []string
+
+	// Socket, when set, makes the profiler connect to the local trace agent
+	// over a Unix domain socket instead of ProfilingURL.
+	Socket string
+
+	// Period specifies the interval at which to collect profiles.
+	Period time.Duration
+
+	// CPUDuration specifies the length of the CPU profiling window.
+	CPUDuration time.Duration
+
+	// MutexProfileFraction carries the current value of
+	// runtime.SetMutexProfileFraction so the profiler doesn't reset it.
+	MutexProfileFraction int
+
+	// BlockProfileRate carries the current value of
+	// runtime.SetBlockProfileRate so the profiler doesn't reset it.
+	BlockProfileRate int
+
+	// WithGoroutineProfile enables goroutine profiling.
+	WithGoroutineProfile bool
+
+	// WithBlockProfile enables block profiling.
+	WithBlockProfile bool
+
+	// WithMutexProfile enables mutex profiling.
+	WithMutexProfile bool
+
+	// WithDeltaProfiles enables delta profiles for the supported profile types.
+	WithDeltaProfiles bool
+
+	// WithExecutionTrace enables periodic collection of Go execution traces
+	// (runtime/trace), uploaded alongside the pprof profiles. It surfaces
+	// scheduler latency and goroutine blocking events pprof samples miss, at
+	// the cost of a short stop-the-world-adjacent window every
+	// ExecutionTracePeriod. Ignored on Go versions older than
+	// minGoVersionForExecutionTrace.
+	WithExecutionTrace bool
+
+	// ExecutionTracePeriod is how often to capture an execution trace window
+	// when WithExecutionTrace is set. Defaults to Period.
+	ExecutionTracePeriod time.Duration
+
+	// ExecutionTraceDuration is how long each captured execution trace
+	// window lasts. Defaults to defaultExecutionTraceDuration.
+	ExecutionTraceDuration time.Duration
+
+	// CustomAttributes are tag names that will be used as additional
+	// dimensions in the Datadog UI.
+	CustomAttributes []string
+
+	// LocalSinkDir, when set, makes the profiler additionally write every
+	// pprof payload it would upload to a rotating set of files under this
+	// directory, for collecting profiles in air-gapped environments that
+	// have no path to Datadog's intake.
+	LocalSinkDir string
+}
+
+// applyDefaults fills in zero-valued fields of s with their defaults.
+func (s *Settings) applyDefaults() {
+	if s.Period <= 0 {
+		s.Period = defaultProfilePeriod
+	}
+	if s.CPUDuration <= 0 {
+		s.CPUDuration = defaultCPUDuration
+	}
+	if s.ExecutionTracePeriod <= 0 {
+		s.ExecutionTracePeriod = s.Period
+	}
+	if s.ExecutionTraceDuration <= 0 {
+		s.ExecutionTraceDuration = defaultExecutionTraceDuration
+	}
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package scrubber redacts known secrets (API/app keys, tokens, passwords)
+// from configuration and diagnostic data before it leaves the host, e.g. in
+// a flare or a metadata payload.
+package scrubber
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rule describes one family of sensitive keys, how to redact their value,
+// and the ID callers can use to tell which rule fired (see ScrubBytesRules
+// and ScrubYAMLRules).
+type rule struct {
+	id     string
+	keys   map[string]struct{}
+	redact func(value string) string
+}
+
+func keySet(keys ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set
+}
+
+// redactAll always replaces value wholesale, regardless of its shape.
+func redactAll(string) string { return "********" }
+
+// redactHexKeepLast replaces value with stars, keeping the last 5 characters
+// visible, but only when value is exactly length hex characters (Datadog API
+// and app keys have a fixed length); anything else is fully redacted, since a
+// value that isn't a well-formed key can't be partially shown safely.
+func redactHexKeepLast(length int) func(string) string {
+	hex := regexp.MustCompile(fmt.Sprintf(`^[a-fA-F0-9]{%d}$`, length))
+	return func(value string) string {
+		if hex.MatchString(value) {
+			return strings.Repeat("*", length-5) + value[length-5:]
+		}
+		return "********"
+	}
+}
+
+var defaultRules = []rule{
+	{id: "api_key", keys: keySet("api_key"), redact: redactHexKeepLast(32)},
+	{id: "app_key", keys: keySet("app_key"), redact: redactHexKeepLast(40)},
+	{id: "token", keys: keySet("token", "auth_token"), redact: redactAll},
+	{id: "password", keys: keySet("password", "passwd", "pwd"), redact: redactAll},
+}
+
+// ruleForKey returns the rule governing key (case-insensitive), if any.
+func ruleForKey(key string) (rule, bool) {
+	key = strings.ToLower(key)
+	for _, r := range defaultRules {
+		if _, ok := r.keys[key]; ok {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// sensitiveKeyValue matches a "key: value" or "key": "value" pair for any of
+// the keys in defaultRules, on a single line. Everything outside the match
+// (indentation, comments, other lines) is left untouched.
+var sensitiveKeyValue = regexp.MustCompile(`(?i)("?\b(?:api_key|app_key|token|auth_token|password|passwd|pwd)\b"?)\s*:\s*("?)([^"\n,}]*)("?)`)
+
+// ScrubBytes redacts known secrets found in data as "key: value" pairs,
+// preserving everything else (comments, indentation, surrounding lines) byte
+// for byte. An unquoted value runs to the end of its line (or to a ',' or
+// '}'), so a comment trailing it on the same line is replaced along with
+// it. It never fails; the error return exists for symmetry with
+// scrubbers that parse their input (see flare/helpers' YAML-aware scrubbing).
+func ScrubBytes(data []byte) ([]byte, error) {
+	scrubbed, _, err := ScrubBytesRules(data)
+	return scrubbed, err
+}
+
+// ScrubBytesRules behaves like ScrubBytes, but also returns the IDs of every
+// rule in defaultRules ("api_key", "app_key", "token", "password") that
+// redacted at least one match, sorted and deduplicated - e.g. for a flare
+// manifest recording why a given file was flagged as scrubbed.
+func ScrubBytesRules(data []byte) ([]byte, []string, error) {
+	seen := map[string]struct{}{}
+	scrubbed := sensitiveKeyValue.ReplaceAllFunc(data, func(match []byte) []byte {
+		return scrubMatch(match, seen)
+	})
+	return scrubbed, sortedKeys(seen), nil
+}
+
+func scrubMatch(match []byte, seen map[string]struct{}) []byte {
+	sub := sensitiveKeyValue.FindSubmatch(match)
+	key := strings.Trim(string(sub[1]), `"`)
+	value := string(sub[3])
+
+	r, ok := ruleForKey(key)
+	if !ok {
+		return match
+	}
+	seen[r.id] = struct{}{}
+	return []byte(fmt.Sprintf(`%s: "%s"`, sub[1], r.redact(value)))
+}
+
+// sortedKeys returns set's keys, sorted, for deterministic rule-ID output.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
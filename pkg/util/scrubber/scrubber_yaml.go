@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scrubber
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScrubYAML parses data as YAML and redacts the value of every mapping key
+// governed by defaultRules, wherever it appears in the document. Unlike
+// ScrubBytes, a redacted value fully replaces its original node (e.g. a YAML
+// list of tokens becomes a single "********" scalar): a YAML-aware caller
+// (like the flare builder, for .yaml/.yml destinations) can't otherwise
+// faithfully hide a multi-line secret. ok is false if data doesn't parse as
+// YAML, in which case the caller should fall back to ScrubBytes.
+func ScrubYAML(data []byte) (scrubbed []byte, ok bool) {
+	scrubbed, _, ok = ScrubYAMLRules(data)
+	return
+}
+
+// ScrubYAMLRules behaves like ScrubYAML, but also returns the IDs (see
+// ScrubBytesRules) of every defaultRules rule that redacted at least one
+// mapping key.
+func ScrubYAMLRules(data []byte) (scrubbed []byte, ruleIDs []string, ok bool) {
+	doc, ok := parseYAMLDoc(data)
+	if !ok {
+		return nil, nil, false
+	}
+
+	seen := map[string]struct{}{}
+	redactYAMLNode(doc, func(key string) (string, bool) {
+		r, ok := ruleForKey(key)
+		if !ok {
+			return "", false
+		}
+		return r.id, true
+	}, seen)
+
+	scrubbed, ok = encodeYAMLDoc(doc)
+	if !ok {
+		return nil, nil, false
+	}
+	return scrubbed, sortedKeys(seen), true
+}
+
+// ScrubYAMLKeys behaves like ScrubYAML, but against a caller-supplied set of
+// mapping keys instead of the built-in sensitive-key set. This lets a
+// caller (e.g. the flare builder's registered scrubbers) redact additional,
+// integration-specific keys the same node-aware way, without duplicating
+// the YAML parse/re-encode logic.
+func ScrubYAMLKeys(data []byte, keys []string) (scrubbed []byte, ok bool) {
+	doc, ok := parseYAMLDoc(data)
+	if !ok {
+		return nil, false
+	}
+
+	set := keySet(keys...)
+	redactYAMLNode(doc, func(key string) (string, bool) {
+		_, ok := set[key]
+		return "", ok
+	}, nil)
+
+	return encodeYAMLDoc(doc)
+}
+
+func parseYAMLDoc(data []byte) (*yaml.Node, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil, false
+	}
+	return &doc, true
+}
+
+func encodeYAMLDoc(doc *yaml.Node) (scrubbed []byte, ok bool) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, false
+	}
+	if err := enc.Close(); err != nil {
+		return nil, false
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), true
+}
+
+// redactYAMLNode walks node, replacing the value of every mapping key for
+// which isSensitive returns ok wholesale with a "********" scalar. If
+// isSensitive also returns a non-empty ruleID, that ID is recorded in seen
+// (seen may be nil, e.g. when the caller doesn't track rule IDs).
+func redactYAMLNode(node *yaml.Node, isSensitive func(key string) (ruleID string, ok bool), seen map[string]struct{}) {
+	if node.Kind != yaml.MappingNode {
+		for _, child := range node.Content {
+			redactYAMLNode(child, isSensitive, seen)
+		}
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if ruleID, ok := isSensitive(strings.ToLower(key.Value)); ok {
+			if ruleID != "" && seen != nil {
+				seen[ruleID] = struct{}{}
+			}
+			*value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "********", Style: yaml.DoubleQuotedStyle}
+			continue
+		}
+		redactYAMLNode(value, isSensitive, seen)
+	}
+}
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package messagestrings
+
+// Event IDs logged by the Windows config upgrade path (CheckAndUpgradeConfigOptions),
+// alongside the existing MSG_WARNING_PROGRAMDATA_ERROR / MSG_WARN_CONFIGUPGRADE_FAILED
+// IDs. They follow the same allocation as the rest of this package: informational
+// IDs in the 0x1xxx range, warnings in 0x2xxx.
+const (
+	// MSG_CONFIGUPGRADE_DRYRUN_DIFF reports the diff a dry-run config upgrade
+	// would apply, without having applied it.
+	MSG_CONFIGUPGRADE_DRYRUN_DIFF = 0x1010
+
+	// MSG_CONFIGUPGRADE_BACKUP_CREATED reports the backup suffix a config
+	// upgrade created before importing the legacy config.
+	MSG_CONFIGUPGRADE_BACKUP_CREATED = 0x1011
+
+	// MSG_CONFIGUPGRADE_ROLLBACK reports that an upgraded config failed
+	// validation and was rolled back to its pre-upgrade backup.
+	MSG_CONFIGUPGRADE_ROLLBACK = 0x2010
+
+	// MSG_CONFIGUPGRADE_APPLIED reports that a config upgrade completed and
+	// was applied successfully.
+	MSG_CONFIGUPGRADE_APPLIED = 0x1012
+)
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+// eventSubscriberBufferSize is the size of the channel Pulumi writes engine
+// events to before eventFanOut relays them to every subscriber.
+const eventSubscriberBufferSize = 100
+
+// eventFanOut relays the structured engine event stream of a single Pulumi
+// operation (today: Up) to every channel and handler registered via
+// WithEventChannel and WithEventHandler, so multiple subscribers (per-resource
+// metrics, live TUIs, fail-fast diagnostics checks) can attach to the same run.
+type eventFanOut struct {
+	channels []chan<- events.EngineEvent
+	handlers []func(events.EngineEvent)
+}
+
+func (f *eventFanOut) isEmpty() bool {
+	return f == nil || (len(f.channels) == 0 && len(f.handlers) == 0)
+}
+
+// subscribe starts relaying a fresh buffered channel of engine events to f's
+// subscribers plus extra (used internally, e.g. to tap SummaryEvent for
+// Datadog eventing), and returns that channel for the caller to pass to
+// optup.EventStreams, along with a function to call once the Pulumi operation
+// that will write to it has returned. Returns a nil channel if there is
+// nothing to relay to, so the caller can skip wiring optup.EventStreams entirely.
+func (f *eventFanOut) subscribe(extra ...func(events.EngineEvent)) (chan events.EngineEvent, func()) {
+	if f.isEmpty() && len(extra) == 0 {
+		return nil, func() {}
+	}
+
+	ch := make(chan events.EngineEvent, eventSubscriberBufferSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range ch {
+			for _, subscriber := range f.channels {
+				subscriber <- event
+			}
+			for _, handler := range f.handlers {
+				handler(event)
+			}
+			for _, handler := range extra {
+				handler(event)
+			}
+		}
+	}()
+
+	return ch, func() {
+		close(ch)
+		<-done
+	}
+}
+
+// summaryEventTags converts a SummaryEvent into the tags sendEventToDatadog
+// expects, so callers don't have to hand-roll resource_count/duration/changes
+// tags from the raw engine event themselves.
+func summaryEventTags(summary events.SummaryEventMetadata) []string {
+	resourceCount := 0
+	for _, count := range summary.ResourceChanges {
+		resourceCount += count
+	}
+
+	return []string{
+		fmt.Sprintf("resource_count:%d", resourceCount),
+		fmt.Sprintf("duration_seconds:%d", summary.DurationSeconds),
+		fmt.Sprintf("changes:%t", resourceCount > 0),
+	}
+}
@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// journalFileName is the journal's file name under the shared e2e workspace
+// root directory.
+const journalFileName = "stack-operations.journal"
+
+// journalOperation identifies which StackManager operation a journalEntry records.
+type journalOperation string
+
+const (
+	journalOperationUp      journalOperation = "up"
+	journalOperationDestroy journalOperation = "destroy"
+	journalOperationRemove  journalOperation = "remove"
+)
+
+// journalStatus tracks an entry's lifecycle. An entry left at
+// journalStatusStarted when the process restarts means the operation was
+// interrupted mid-flight (the process crashed or was killed) rather than
+// having actually finished.
+type journalStatus string
+
+const (
+	journalStatusStarted   journalStatus = "started"
+	journalStatusCompleted journalStatus = "completed"
+	journalStatusFailed    journalStatus = "failed"
+)
+
+// journalEntry is one write-ahead record of a StackManager operation against
+// a single stack: what was attempted, how many times, and how it ended.
+type journalEntry struct {
+	StackID     string           `json:"stack_id"`
+	Operation   journalOperation `json:"operation"`
+	Status      journalStatus    `json:"status"`
+	Attempt     int              `json:"attempt"`
+	OperationID int              `json:"operation_id,omitempty"`
+	StartedAt   time.Time        `json:"started_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// stackJournal is an append-only, crash-safe log of StackManager operations,
+// stored as newline-delimited JSON under the e2e workspace root directory so
+// a restarted process can find operations that were interrupted mid-flight
+// (and so never reached a terminal status) and recover the stacks they left
+// locked, instead of silently leaking a Pulumi lock.
+type stackJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newStackJournal opens (creating the directory and file if necessary) the
+// journal at filepath.Join(workspaceRootDir, journalFileName).
+func newStackJournal(workspaceRootDir string) (*stackJournal, error) {
+	if err := os.MkdirAll(workspaceRootDir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating workspace root directory %s: %w", workspaceRootDir, err)
+	}
+	return &stackJournal{path: filepath.Join(workspaceRootDir, journalFileName)}, nil
+}
+
+// record appends entry to the journal.
+func (j *stackJournal) record(entry journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening journal %s: %w", j.path, err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling journal entry: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing journal entry: %w", err)
+	}
+	return nil
+}
+
+// entries returns every entry recorded in the journal, in the order they
+// were written. A missing journal file is treated as empty, not an error.
+func (j *stackJournal) entries() ([]journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening journal %s: %w", j.path, err)
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parsing journal entry in %s: %w", j.path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal %s: %w", j.path, err)
+	}
+
+	return entries, nil
+}
+
+// latestByStack reduces the journal to each stack's most recently recorded entry.
+func (j *stackJournal) latestByStack() (map[string]journalEntry, error) {
+	entries, err := j.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]journalEntry, len(entries))
+	for _, entry := range entries {
+		latest[entry.StackID] = entry
+	}
+	return latest, nil
+}
+
+// trackOperation records a journalStatusStarted entry for stackID/op, runs
+// do, then records the terminal entry (journalStatusCompleted or
+// journalStatusFailed, carrying do's error message and operationID when
+// available), before returning do's error unchanged. Journal write failures
+// are only logged: a broken journal must never block the underlying Pulumi
+// operation it is merely observing.
+func (j *stackJournal) trackOperation(stackID string, op journalOperation, attempt int, do func() (operationID int, err error)) error {
+	started := journalEntry{StackID: stackID, Operation: op, Status: journalStatusStarted, Attempt: attempt, StartedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := j.record(started); err != nil {
+		fmt.Printf("Error recording journal entry: %v\n", err)
+	}
+
+	operationID, opErr := do()
+
+	final := started
+	final.UpdatedAt = time.Now()
+	final.OperationID = operationID
+	if opErr != nil {
+		final.Status = journalStatusFailed
+		final.Error = opErr.Error()
+	} else {
+		final.Status = journalStatusCompleted
+	}
+	if err := j.record(final); err != nil {
+		fmt.Printf("Error recording journal entry: %v\n", err)
+	}
+
+	return opErr
+}
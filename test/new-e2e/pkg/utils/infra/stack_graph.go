@@ -0,0 +1,282 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/pkg/runner"
+)
+
+// OutputMapper derives a stack's config from its own static config and the
+// UpResults of the stacks it depends on (keyed by stack name, as declared in
+// StackNode.DependsOn), so a child stack can consume a parent's outputs
+// (e.g. a cluster's kubeconfig, a VPC's subnet IDs).
+type OutputMapper func(ownConfig runner.ConfigMap, dependencyOutputs map[string]auto.UpResult) runner.ConfigMap
+
+// StackNode describes one stack in a StackGraph.
+type StackNode struct {
+	// Name is passed to StackManager.GetStack as the stack name.
+	Name string
+	// DeployFunc is the stack's Pulumi program.
+	DeployFunc pulumi.RunFunc
+	// Config is the node's own, static configuration, independent of any
+	// dependency outputs. Mapper (if set) receives it and returns the config
+	// actually passed to Up.
+	Config runner.ConfigMap
+	// DependsOn lists the names of stacks that must be up, and whose
+	// UpResult is available to Mapper, before this node is brought up.
+	DependsOn []string
+	// Mapper derives this node's final config from Config and the UpResults
+	// of the stacks named in DependsOn. May be nil if the node doesn't need
+	// any dependency's outputs.
+	Mapper OutputMapper
+	// Options are extra GetStackOptions applied to this node's Up, after
+	// WithConfigMap.
+	Options []GetStackOption
+}
+
+// StackGraph runs a DAG of named stacks through a StackManager: independent
+// stacks are brought up concurrently (bounded by maxParallel), stacks that
+// depend on others wait for their dependencies and can consume their
+// UpResult.Outputs via OutputMapper, and Cleanup tears everything down in
+// reverse topological order. This targets multi-stage e2e scenarios (e.g.
+// network -> cluster -> agent -> workload) that would otherwise require
+// hand-serializing GetStack calls and manually plumbing outputs between them.
+type StackGraph struct {
+	sm          *StackManager
+	maxParallel int
+
+	mu      sync.Mutex
+	nodes   map[string]*StackNode
+	order   []string
+	results map[string]auto.UpResult
+	stacks  map[string]*auto.Stack
+}
+
+// NewStackGraph creates an empty StackGraph backed by sm. maxParallel bounds
+// how many stacks with satisfied dependencies are brought up at once; 0 or
+// negative means unbounded.
+func NewStackGraph(sm *StackManager, maxParallel int) *StackGraph {
+	return &StackGraph{
+		sm:          sm,
+		maxParallel: maxParallel,
+		nodes:       map[string]*StackNode{},
+		results:     map[string]auto.UpResult{},
+		stacks:      map[string]*auto.Stack{},
+	}
+}
+
+// AddStack registers a stack node in the graph. It must be called before Up;
+// adding a node after Up has started is not supported.
+func (g *StackGraph) AddStack(node StackNode) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.nodes[node.Name]; !exists {
+		g.order = append(g.order, node.Name)
+	}
+	g.nodes[node.Name] = &node
+}
+
+// Up brings up every stack registered with AddStack in topological order,
+// running stacks whose dependencies are already up concurrently up to
+// maxParallel at a time. On error, it stops scheduling new stacks, waits for
+// in-flight ones to finish, and returns the UpResults collected so far
+// alongside the error; the caller is responsible for calling Cleanup.
+func (g *StackGraph) Up(ctx context.Context) (map[string]auto.UpResult, error) {
+	levels, err := g.topoLevels()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := newSemaphore(g.maxParallel)
+	for _, level := range levels {
+		if err := g.upLevel(ctx, level, tokens); err != nil {
+			return g.snapshotResults(), err
+		}
+	}
+
+	return g.snapshotResults(), nil
+}
+
+// upLevel brings up every node in level concurrently and joins their errors.
+func (g *StackGraph) upLevel(ctx context.Context, level []string, tokens *semaphore) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(level))
+
+	for i, name := range level {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			tokens.acquire()
+			defer tokens.release()
+
+			errs[i] = g.upNode(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (g *StackGraph) upNode(ctx context.Context, name string) error {
+	g.mu.Lock()
+	node := g.nodes[name]
+	depOutputs := make(map[string]auto.UpResult, len(node.DependsOn))
+	for _, dep := range node.DependsOn {
+		depOutputs[dep] = g.results[dep]
+	}
+	g.mu.Unlock()
+
+	config := node.Config
+	if node.Mapper != nil {
+		config = node.Mapper(config, depOutputs)
+	}
+
+	options := append([]GetStackOption{WithConfigMap(config)}, node.Options...)
+	stack, upResult, err := g.sm.GetStackNoDeleteOnFailure(ctx, name, node.DeployFunc, options...)
+	if err != nil {
+		return fmt.Errorf("bringing up stack %q: %w", name, err)
+	}
+
+	g.mu.Lock()
+	g.results[name] = upResult
+	g.stacks[name] = stack
+	g.mu.Unlock()
+
+	return nil
+}
+
+func (g *StackGraph) snapshotResults() map[string]auto.UpResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	results := make(map[string]auto.UpResult, len(g.results))
+	for name, result := range g.results {
+		results[name] = result
+	}
+	return results
+}
+
+// Cleanup destroys every stack this StackGraph brought up, in reverse
+// topological order (a stack is destroyed only after everything that depends
+// on it). Stacks that were never brought up (Up never ran, or failed before
+// reaching them) are skipped.
+func (g *StackGraph) Cleanup(ctx context.Context) []error {
+	levels, err := g.topoLevels()
+	if err != nil {
+		return []error{err}
+	}
+
+	var allErrs []error
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, name := range levels[i] {
+			g.mu.Lock()
+			stack := g.stacks[name]
+			g.mu.Unlock()
+
+			if stack == nil {
+				continue
+			}
+
+			if err := g.sm.destroyAndRemoveStack(ctx, name, stack, nil, nil); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("destroying stack %q: %w", name, err))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// topoLevels groups the graph's nodes into Kahn's-algorithm levels: level 0
+// has no dependencies, level 1 depends only on level 0, and so on. Nodes
+// within a level can be brought up concurrently. Returns an error if a node
+// depends on a name that was never added, or if the graph has a cycle.
+func (g *StackGraph) topoLevels() ([][]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	remaining := make(map[string][]string, len(g.nodes))
+	for _, name := range g.order {
+		for _, dep := range g.nodes[name].DependsOn {
+			if _, ok := g.nodes[dep]; !ok {
+				return nil, fmt.Errorf("stack %q depends on %q, which was never added to the graph", name, dep)
+			}
+		}
+		remaining[name] = append([]string(nil), g.nodes[name].DependsOn...)
+	}
+
+	var levels [][]string
+	for len(remaining) > 0 {
+		var level []string
+		for _, name := range g.order {
+			if _, pending := remaining[name]; pending && len(remaining[name]) == 0 {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			return nil, errors.New("stack graph has a dependency cycle")
+		}
+
+		for _, name := range level {
+			delete(remaining, name)
+		}
+		for name, deps := range remaining {
+			remaining[name] = removeAll(deps, level)
+		}
+
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+func removeAll(deps []string, done []string) []string {
+	doneSet := make(map[string]struct{}, len(done))
+	for _, name := range done {
+		doneSet[name] = struct{}{}
+	}
+
+	filtered := deps[:0]
+	for _, dep := range deps {
+		if _, isDone := doneSet[dep]; !isDone {
+			filtered = append(filtered, dep)
+		}
+	}
+	return filtered
+}
+
+// semaphore bounds concurrency to n goroutines at a time; n <= 0 means unbounded.
+type semaphore struct {
+	tokens chan struct{}
+}
+
+func newSemaphore(n int) *semaphore {
+	if n <= 0 {
+		return &semaphore{}
+	}
+	return &semaphore{tokens: make(chan struct{}, n)}
+}
+
+func (s *semaphore) acquire() {
+	if s.tokens != nil {
+		s.tokens <- struct{}{}
+	}
+}
+
+func (s *semaphore) release() {
+	if s.tokens != nil {
+		<-s.tokens
+	}
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGraph(nodes ...StackNode) *StackGraph {
+	g := NewStackGraph(nil, 0)
+	for _, n := range nodes {
+		g.AddStack(n)
+	}
+	return g
+}
+
+// levelSets converts topoLevels' output into a slice of sets, so assertions
+// don't depend on the (unspecified) order of nodes within a level.
+func levelSets(levels [][]string) []map[string]struct{} {
+	sets := make([]map[string]struct{}, len(levels))
+	for i, level := range levels {
+		set := make(map[string]struct{}, len(level))
+		for _, name := range level {
+			set[name] = struct{}{}
+		}
+		sets[i] = set
+	}
+	return sets
+}
+
+func TestTopoLevelsDisconnectedNodes(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "a"},
+		StackNode{Name: "b"},
+		StackNode{Name: "c"},
+	)
+
+	levels, err := g.topoLevels()
+	require.NoError(t, err)
+	require.Len(t, levels, 1)
+	assert.Equal(t, []map[string]struct{}{{"a": {}, "b": {}, "c": {}}}, levelSets(levels))
+}
+
+func TestTopoLevelsLinearChain(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "network"},
+		StackNode{Name: "cluster", DependsOn: []string{"network"}},
+		StackNode{Name: "agent", DependsOn: []string{"cluster"}},
+	)
+
+	levels, err := g.topoLevels()
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]struct{}{
+		{"network": {}},
+		{"cluster": {}},
+		{"agent": {}},
+	}, levelSets(levels))
+}
+
+func TestTopoLevelsDiamond(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "base"},
+		StackNode{Name: "left", DependsOn: []string{"base"}},
+		StackNode{Name: "right", DependsOn: []string{"base"}},
+		StackNode{Name: "top", DependsOn: []string{"left", "right"}},
+	)
+
+	levels, err := g.topoLevels()
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]struct{}{
+		{"base": {}},
+		{"left": {}, "right": {}},
+		{"top": {}},
+	}, levelSets(levels))
+}
+
+func TestTopoLevelsCycle(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "a", DependsOn: []string{"b"}},
+		StackNode{Name: "b", DependsOn: []string{"a"}},
+	)
+
+	_, err := g.topoLevels()
+	assert.ErrorContains(t, err, "dependency cycle")
+}
+
+func TestTopoLevelsSelfCycle(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "a", DependsOn: []string{"a"}},
+	)
+
+	_, err := g.topoLevels()
+	assert.ErrorContains(t, err, "dependency cycle")
+}
+
+func TestTopoLevelsUnknownDependency(t *testing.T) {
+	g := newTestGraph(
+		StackNode{Name: "a", DependsOn: []string{"missing"}},
+	)
+
+	_, err := g.topoLevels()
+	assert.ErrorContains(t, err, `depends on "missing"`)
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -23,7 +24,10 @@ import (
 	"github.com/DataDog/datadog-api-client-go/v2/api/datadogV1"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optremove"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
@@ -60,10 +64,19 @@ var (
 // RetryStrategyFromFn is a function that given the current error and the number of retries, returns the type of retry to perform and a list of options to modify the configuration
 type RetryStrategyFromFn func(error, int) (RetryType, []GetStackOption)
 
+// ReRefreshThenUp is a RetryType (alongside ReUp, ReCreate, NoRetry) that runs a Refresh before the
+// next Up attempt, for errors caused by drift between Pulumi's state and the real infrastructure
+// (e.g. "resource ... does not exist").
+const ReRefreshThenUp RetryType = "ReRefreshThenUp"
+
 // StackManager handles
 type StackManager struct {
 	stacks      *safeStackMap
 	knownErrors []knownError
+	journal     *stackJournal
+
+	orphansMu      sync.Mutex
+	orphanedStacks map[string]journalOperation
 
 	// GetRetryStrategyFrom defines how to handle retries. By default points to StackManager.getRetryStrategyFrom but can be overridden
 	GetRetryStrategyFrom RetryStrategyFromFn
@@ -117,15 +130,111 @@ func GetStackManager() *StackManager {
 }
 
 func newStackManager() (*StackManager, error) {
+	profile := runner.GetProfile()
+	// GetWorkspacePath namespaces every stack under a shared parent directory;
+	// its parent is therefore the stable root the journal lives in regardless
+	// of which stack happens to touch it first.
+	workspaceRootDir := filepath.Dir(profile.GetWorkspacePath(e2eWorkspaceDirectory))
+
+	journal, err := newStackJournal(workspaceRootDir)
+	if err != nil {
+		return nil, fmt.Errorf("opening stack operations journal: %w", err)
+	}
+
 	sm := &StackManager{
-		stacks:      newSafeStackMap(),
-		knownErrors: getKnownErrors(),
+		stacks:         newSafeStackMap(),
+		knownErrors:    getKnownErrors(),
+		journal:        journal,
+		orphanedStacks: map[string]journalOperation{},
 	}
 	sm.GetRetryStrategyFrom = sm.getRetryStrategyFrom
 
+	sm.recoverInProgress(context.Background())
+
 	return sm, nil
 }
 
+// recoverInProgress scans the journal for operations that were started but
+// never reached a terminal status (the process that ran them was killed or
+// crashed mid-operation), cancels each affected stack's in-flight Pulumi
+// operation to release its lock, and records the interruption as failed so
+// it isn't mistaken for still-in-progress on the next restart. Interrupted
+// Destroy/Remove operations are remembered so RecoverOrphanedStacks (and
+// therefore Cleanup) can finish tearing them down; interrupted Up operations
+// are left for the caller to resume with a normal GetStack call.
+func (sm *StackManager) recoverInProgress(ctx context.Context) {
+	latest, err := sm.journal.latestByStack()
+	if err != nil {
+		fmt.Printf("Error reading stack operations journal: %v\n", err)
+		return
+	}
+
+	for stackID, entry := range latest {
+		if entry.Status != journalStatusStarted {
+			continue
+		}
+
+		fmt.Printf("Found %s on stack %s interrupted mid-operation in the stack operations journal, attempting to cancel its Pulumi lock\n", entry.Operation, stackID)
+
+		stack, err := sm.resolveStack(ctx, stackID)
+		if err != nil {
+			fmt.Printf("Error resolving stack %s to recover from interrupted %s: %v\n", stackID, entry.Operation, err)
+			continue
+		}
+
+		if err := cancelStack(stack, defaultStackCancelTimeout); err != nil {
+			fmt.Printf("Error cancelling stack %s's Pulumi operation during recovery: %v\n", stackID, err)
+			continue
+		}
+
+		entry.Status = journalStatusFailed
+		entry.Error = "recovered: process restarted mid-operation"
+		entry.UpdatedAt = time.Now()
+		if err := sm.journal.record(entry); err != nil {
+			fmt.Printf("Error recording recovery in stack operations journal: %v\n", err)
+		}
+
+		if entry.Operation == journalOperationDestroy || entry.Operation == journalOperationRemove {
+			sm.orphansMu.Lock()
+			sm.orphanedStacks[stackID] = entry.Operation
+			sm.orphansMu.Unlock()
+		}
+	}
+}
+
+// RecoverOrphanedStacks finishes tearing down stacks whose Destroy or Remove
+// was interrupted mid-operation in a previous process (see
+// recoverInProgress), by re-running destroyAndRemoveStack against them.
+// Cleanup calls this before its normal sweep.
+func (sm *StackManager) RecoverOrphanedStacks(ctx context.Context) []error {
+	sm.orphansMu.Lock()
+	orphaned := make(map[string]journalOperation, len(sm.orphanedStacks))
+	for stackID, op := range sm.orphanedStacks {
+		orphaned[stackID] = op
+	}
+	sm.orphansMu.Unlock()
+
+	var errs []error
+	for stackID := range orphaned {
+		stack, err := sm.resolveStack(ctx, stackID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolving orphaned stack %q: %w", stackID, err))
+			continue
+		}
+
+		if err := sm.destroyAndRemoveStack(ctx, stackID, stack, nil, nil); err != nil {
+			errs = append(errs, fmt.Errorf("recovering orphaned stack %q: %w", stackID, err))
+			continue
+		}
+
+		sm.orphansMu.Lock()
+		delete(sm.orphanedStacks, stackID)
+		sm.orphansMu.Unlock()
+	}
+
+	return errs
+}
+
 // GetStack creates or return a stack based on stack name and config, if error occurs during stack creation it destroy all the resources created
 func (sm *StackManager) GetStack(ctx context.Context, name string, config runner.ConfigMap, deployFunc pulumi.RunFunc, failOnMissing bool) (_ *auto.Stack, _ auto.UpResult, err error) {
 	defer func() {
@@ -159,6 +268,16 @@ type getStackParams struct {
 	UpTimeout          time.Duration
 	DestroyTimeout     time.Duration
 	CancelTimeout      time.Duration
+	GitSource          *auto.GitRepo
+	EventSubscribers   eventFanOut
+	Backend            *BackendConfig
+}
+
+func (p *getStackParams) backend() *BackendConfig {
+	if p.Backend == nil {
+		p.Backend = &BackendConfig{}
+	}
+	return p.Backend
 }
 
 // GetStackOption is a function that sets a parameter for GetStack function
@@ -213,6 +332,77 @@ func WithCancelTimeout(cancelTimeout time.Duration) GetStackOption {
 	}
 }
 
+// WithGitSource configures the stack to check out its Pulumi program from a
+// Git repository instead of running the inline pulumi.RunFunc passed to
+// GetStack/GetStackNoDeleteOnFailure, mirroring Pulumi's
+// NewStackRemoteSource. This lets a test pin its e2e Pulumi program to a
+// separate repo and ref (branch or commit) per run.
+func WithGitSource(repo auto.GitRepo) GetStackOption {
+	return func(p *getStackParams) {
+		p.GitSource = &repo
+	}
+}
+
+// WithEventChannel subscribes ch to the structured Pulumi engine event stream
+// (ResourcePreEvent, ResOutputsEvent, DiagnosticEvent, SummaryEvent, ...)
+// produced while the stack is brought up. ch is never closed by the stack
+// manager; the caller owns it. Multiple channels and handlers can be
+// registered across several GetStackOption calls; see WithEventHandler.
+func WithEventChannel(ch chan<- events.EngineEvent) GetStackOption {
+	return func(p *getStackParams) {
+		p.EventSubscribers.channels = append(p.EventSubscribers.channels, ch)
+	}
+}
+
+// WithEventHandler subscribes handler to the structured Pulumi engine event
+// stream produced while the stack is brought up. See WithEventChannel.
+func WithEventHandler(handler func(events.EngineEvent)) GetStackOption {
+	return func(p *getStackParams) {
+		p.EventSubscribers.handlers = append(p.EventSubscribers.handlers, handler)
+	}
+}
+
+// BackendConfig selects the Pulumi state backend a stack's workspace stores
+// its checkpoints in, instead of inheriting whatever PULUMI_BACKEND_URL
+// happens to already be set in the environment. URL supports file://, s3://,
+// azblob://, gs://, and Pulumi Service URLs (https://app.pulumi.com or a
+// self-hosted equivalent). Per-stack locking is handled natively by whichever
+// backend URL is selected (the cloud-storage backends lock via a lock file
+// alongside the stack's checkpoint, the service backend always locks), so
+// concurrent CI jobs targeting the same stack name still serialize safely.
+type BackendConfig struct {
+	URL             string
+	EnvVars         map[string]string
+	SecretsProvider string
+}
+
+// WithBackendURL sets the Pulumi state backend a stack's workspace stores its
+// checkpoints in. See BackendConfig.
+func WithBackendURL(url string) GetStackOption {
+	return func(p *getStackParams) {
+		p.backend().URL = url
+	}
+}
+
+// WithBackendCredentials sets the environment variables the backend's cloud
+// storage client reads its credentials from, e.g.
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY for s3://, or
+// AZURE_STORAGE_ACCOUNT/AZURE_STORAGE_KEY for azblob://.
+func WithBackendCredentials(envVars map[string]string) GetStackOption {
+	return func(p *getStackParams) {
+		p.backend().EnvVars = envVars
+	}
+}
+
+// WithSecretsProvider sets the Pulumi secrets provider used to encrypt the
+// stack's secret config and outputs, e.g. "passphrase",
+// "awskms://alias/my-key", or "azurekeyvault://my-vault.vault.azure.net/keys/my-key".
+func WithSecretsProvider(secretsProvider string) GetStackOption {
+	return func(p *getStackParams) {
+		p.backend().SecretsProvider = secretsProvider
+	}
+}
+
 // GetStackNoDeleteOnFailure creates or return a stack based on stack name and config, if error occurs during stack creation, it will not destroy the created resources. Using this can lead to resource leaks.
 func (sm *StackManager) GetStackNoDeleteOnFailure(ctx context.Context, name string, deployFunc pulumi.RunFunc, options ...GetStackOption) (_ *auto.Stack, _ auto.UpResult, err error) {
 	defer func() {
@@ -232,25 +422,183 @@ func (sm *StackManager) DeleteStack(ctx context.Context, name string, logWriter
 		}
 	}()
 
-	stack, ok := sm.stacks.Get(name)
-	if !ok {
-		// Build configuration from profile
-		profile := runner.GetProfile()
-		stackName := buildStackName(profile.NamePrefix(), name)
-		workspace, err := buildWorkspace(ctx, profile, stackName, func(*pulumi.Context) error { return nil })
+	stack, err := sm.resolveStack(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return sm.destroyAndRemoveStack(ctx, name, stack, logWriter, nil)
+}
+
+// resolveStack returns the stack tracked under name, selecting it from its
+// workspace if the stack manager hasn't seen it yet (e.g. the process that
+// ran GetStack has restarted, or the stack was only ever brought up by
+// another caller).
+func (sm *StackManager) resolveStack(ctx context.Context, name string) (*auto.Stack, error) {
+	if stack, ok := sm.stacks.Get(name); ok {
+		return stack, nil
+	}
+
+	profile := runner.GetProfile()
+	stackName := buildStackName(profile.NamePrefix(), name)
+	workspace, err := buildWorkspace(ctx, profile, stackName, func(*pulumi.Context) error { return nil }, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	newStack, err := auto.SelectStack(ctx, stackName, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newStack, nil
+}
+
+// Preview runs `pulumi preview` against an existing stack, reporting the
+// planned changes without applying them. It reuses the timeout and
+// Datadog-eventing machinery getStack uses for Up.
+func (sm *StackManager) Preview(ctx context.Context, name string, options ...GetStackOption) (_ auto.PreviewResult, err error) {
+	defer func() {
 		if err != nil {
-			return err
+			err = common.InternalError{Err: err}
 		}
+	}()
 
-		newStack, err := auto.SelectStack(ctx, stackName, workspace)
+	stack, err := sm.resolveStack(ctx, name)
+	if err != nil {
+		return auto.PreviewResult{}, err
+	}
+
+	params := getDefaultGetStackParams()
+	for _, opt := range options {
+		opt(&params)
+	}
+
+	loggingOptions, err := sm.getLoggingOptions()
+	if err != nil {
+		return auto.PreviewResult{}, err
+	}
+
+	previewCtx, cancel := context.WithTimeout(ctx, params.UpTimeout)
+	defer cancel()
+
+	previewResult, err := stack.Preview(previewCtx, optpreview.DebugLogging(loggingOptions))
+	if err != nil {
+		sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : error on Pulumi stack preview", name), err.Error(), []string{"operation:preview", "result:fail", fmt.Sprintf("stack:%s", stack.Name())})
+		return previewResult, err
+	}
+
+	sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : success on Pulumi stack preview", name), "", []string{"operation:preview", "result:ok", fmt.Sprintf("stack:%s", stack.Name())})
+	return previewResult, nil
+}
+
+// Refresh runs `pulumi refresh` against an existing stack to reconcile
+// Pulumi's state with the real infrastructure, retrying on any error (after
+// cancelling the stack's operation on timeout) up to stackUpMaxRetry times,
+// with the same machinery getStack uses for Up.
+func (sm *StackManager) Refresh(ctx context.Context, name string, options ...GetStackOption) (_ auto.RefreshResult, err error) {
+	defer func() {
 		if err != nil {
-			return err
+			err = common.InternalError{Err: err}
 		}
+	}()
 
-		stack = &newStack
+	stack, err := sm.resolveStack(ctx, name)
+	if err != nil {
+		return auto.RefreshResult{}, err
 	}
 
-	return sm.destroyAndRemoveStack(ctx, name, stack, logWriter, nil)
+	params := getDefaultGetStackParams()
+	for _, opt := range options {
+		opt(&params)
+	}
+
+	loggingOptions, err := sm.getLoggingOptions()
+	if err != nil {
+		return auto.RefreshResult{}, err
+	}
+	logger := params.LogWriter
+
+	refreshCount := 0
+	var refreshResult auto.RefreshResult
+	var refreshErr error
+	for {
+		refreshCount++
+		refreshCtx, cancel := context.WithTimeout(ctx, params.UpTimeout)
+		refreshResult, refreshErr = stack.Refresh(refreshCtx, optrefresh.DebugLogging(loggingOptions))
+		cancel()
+
+		if refreshErr == nil {
+			sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : success on Pulumi stack refresh", name), "", []string{"operation:refresh", "result:ok", fmt.Sprintf("stack:%s", stack.Name()), fmt.Sprintf("retries:%d", refreshCount)})
+			return refreshResult, nil
+		}
+
+		// handle timeout
+		contextCauseErr := context.Cause(refreshCtx)
+		if errors.Is(contextCauseErr, context.DeadlineExceeded) {
+			sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : timeout on Pulumi stack refresh", name), "", []string{"operation:refresh", fmt.Sprintf("stack:%s", stack.Name())})
+			fmt.Fprint(logger, "Timeout during stack refresh, trying to cancel stack's operation\n")
+			if cancelErr := cancelStack(stack, params.CancelTimeout); cancelErr != nil {
+				fmt.Fprintf(logger, "Giving up on error during attempt to cancel stack operation: %v\n", cancelErr)
+				return refreshResult, cancelErr
+			}
+		}
+
+		sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : error on Pulumi stack refresh", name), refreshErr.Error(), []string{"operation:refresh", "result:fail", fmt.Sprintf("stack:%s", stack.Name()), fmt.Sprintf("retries:%d", refreshCount)})
+
+		if refreshCount > stackUpMaxRetry {
+			fmt.Fprintf(logger, "Giving up on error during stack refresh: %v\n", refreshErr)
+			return refreshResult, refreshErr
+		}
+		fmt.Fprintf(logger, "Retrying stack refresh on error: %v\n", refreshErr)
+	}
+}
+
+// MigrateStackBackend exports name's state from its current backend and
+// imports it into a stack selected under target, so a stack's state can be
+// moved between backends (e.g. local file:// during development to s3:// in
+// CI) without losing its resources. The stack manager tracks the migrated
+// stack under target afterwards; callers should pass matching WithBackendURL/
+// WithBackendCredentials/WithSecretsProvider options to every later GetStack
+// call for name.
+func (sm *StackManager) MigrateStackBackend(ctx context.Context, name string, target BackendConfig) (err error) {
+	defer func() {
+		if err != nil {
+			err = common.InternalError{Err: err}
+		}
+	}()
+
+	stack, err := sm.resolveStack(ctx, name)
+	if err != nil {
+		return fmt.Errorf("resolving stack %q before migration: %w", name, err)
+	}
+
+	deployment, err := stack.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("exporting stack %q: %w", name, err)
+	}
+
+	profile := runner.GetProfile()
+	stackName := buildStackName(profile.NamePrefix(), name)
+	targetWorkspace, err := buildWorkspace(ctx, profile, stackName, func(*pulumi.Context) error { return nil }, nil, &target)
+	if err != nil {
+		return fmt.Errorf("building target workspace for stack %q: %w", name, err)
+	}
+
+	targetStack, err := auto.SelectStack(ctx, stackName, targetWorkspace)
+	if auto.IsSelectStack404Error(err) {
+		targetStack, err = auto.NewStack(ctx, stackName, targetWorkspace)
+	}
+	if err != nil {
+		return fmt.Errorf("selecting stack %q under target backend: %w", name, err)
+	}
+
+	if err := targetStack.Import(ctx, deployment); err != nil {
+		return fmt.Errorf("importing stack %q into target backend: %w", name, err)
+	}
+
+	sm.stacks.Set(name, &targetStack)
+	return nil
 }
 
 // ForceRemoveStackConfiguration removes the configuration files pulumi creates for managing a stack.
@@ -276,6 +624,10 @@ func (sm *StackManager) ForceRemoveStackConfiguration(ctx context.Context, name
 func (sm *StackManager) Cleanup(ctx context.Context) []error {
 	var errors []error
 
+	for _, err := range sm.RecoverOrphanedStacks(ctx) {
+		errors = append(errors, common.InternalError{Err: err})
+	}
+
 	sm.stacks.Range(func(stackID string, stack *auto.Stack) {
 		err := sm.destroyAndRemoveStack(ctx, stackID, stack, nil, nil)
 		if err != nil {
@@ -376,7 +728,10 @@ func (sm *StackManager) destroyStack(ctx context.Context, stackID string, stack
 	for {
 		downCount++
 		destroyContext, cancel := context.WithTimeout(ctx, defaultStackDestroyTimeout)
-		_, destroyErr = stack.Destroy(destroyContext, progressStreamsDestroyOption, optdestroy.DebugLogging(loggingOptions))
+		destroyErr = sm.journal.trackOperation(stackID, journalOperationDestroy, downCount, func() (int, error) {
+			destroyResult, err := stack.Destroy(destroyContext, progressStreamsDestroyOption, optdestroy.DebugLogging(loggingOptions))
+			return destroyResult.Summary.Version, err
+		})
 		cancel()
 		if destroyErr == nil {
 			sendEventToDatadog(ddEventSender, fmt.Sprintf("[E2E] Stack %s : success on Pulumi stack destroy", stackID), "", []string{"operation:destroy", "result:ok", fmt.Sprintf("stack:%s", stack.Name()), fmt.Sprintf("retries:%d", downCount)})
@@ -418,7 +773,9 @@ func (sm *StackManager) removeStack(ctx context.Context, stackID string, stack *
 	for {
 		removeCount++
 		removeContext, cancel := context.WithTimeout(ctx, defaultStackRemoveTimeout)
-		err = stack.Workspace().RemoveStack(removeContext, stack.Name())
+		err = sm.journal.trackOperation(stackID, journalOperationRemove, removeCount, func() (int, error) {
+			return 0, stack.Workspace().RemoveStack(removeContext, stack.Name())
+		})
 		cancel()
 		if err == nil {
 			sendEventToDatadog(ddEventSender, fmt.Sprintf("[E2E] Stack %s : success on Pulumi stack remove", stackID), "", []string{"operation:remove", "result:ok", fmt.Sprintf("stack:%s", stack.Name()), fmt.Sprintf("retries:%d", removeCount)})
@@ -461,7 +818,7 @@ func (sm *StackManager) getStack(ctx context.Context, name string, deployFunc pu
 	}
 	stack, _ := sm.stacks.Get(name)
 	if stack == nil {
-		workspace, err := buildWorkspace(ctx, profile, stackName, deployFunc)
+		workspace, err := buildWorkspace(ctx, profile, stackName, deployFunc, params.GitSource, params.Backend)
 		if err != nil {
 			return nil, auto.UpResult{}, err
 		}
@@ -476,7 +833,7 @@ func (sm *StackManager) getStack(ctx context.Context, name string, deployFunc pu
 
 		stack = &newStack
 		sm.stacks.Set(name, stack)
-	} else {
+	} else if params.GitSource == nil {
 		stack.Workspace().SetProgram(deployFunc)
 	}
 
@@ -500,9 +857,24 @@ func (sm *StackManager) getStack(ctx context.Context, name string, deployFunc pu
 
 	for {
 		upCount++
+		upOpts := []optup.Option{progressStreamsUpOption, optup.DebugLogging(loggingOptions)}
+		eventCh, waitEvents := params.EventSubscribers.subscribe(func(event events.EngineEvent) {
+			if event.SummaryEvent != nil {
+				sendEventToDatadog(params.DatadogEventSender, fmt.Sprintf("[E2E] Stack %s : Pulumi stack up summary", name), "", summaryEventTags(*event.SummaryEvent))
+			}
+		})
+		if eventCh != nil {
+			upOpts = append(upOpts, optup.EventStreams(eventCh))
+		}
+
 		upCtx, cancel := context.WithTimeout(ctx, params.UpTimeout)
 		now := time.Now()
-		upResult, upError = stack.Up(upCtx, progressStreamsUpOption, optup.DebugLogging(loggingOptions))
+		upError = sm.journal.trackOperation(name, journalOperationUp, upCount, func() (int, error) {
+			var err error
+			upResult, err = stack.Up(upCtx, upOpts...)
+			return upResult.Summary.Version, err
+		})
+		waitEvents()
 		fmt.Fprintf(logger, "Stack up took %v at attempt %v\n", time.Since(now), upCount)
 		cancel()
 
@@ -542,6 +914,12 @@ func (sm *StackManager) getStack(ctx context.Context, name string, deployFunc pu
 		case NoRetry:
 			fmt.Fprintf(logger, "Giving up on error during stack up: %v\n", upError)
 			return stack, upResult, upError
+		case ReRefreshThenUp:
+			fmt.Fprintf(logger, "Refreshing stack before retrying stack up: %v\n", upError)
+			if _, refreshErr := sm.Refresh(ctx, name, WithLogWriter(logger), WithDatadogEventSender(params.DatadogEventSender), WithUpTimeout(params.UpTimeout), WithCancelTimeout(params.CancelTimeout)); refreshErr != nil {
+				fmt.Fprintf(logger, "Giving up after error refreshing stack before retry: %v\n", refreshErr)
+				return stack, upResult, refreshErr
+			}
 		}
 
 		if len(changedOpts) > 0 {
@@ -565,33 +943,71 @@ func (sm *StackManager) getStack(ctx context.Context, name string, deployFunc pu
 	return stack, upResult, upError
 }
 
-func buildWorkspace(ctx context.Context, profile runner.Profile, stackName string, runFunc pulumi.RunFunc) (auto.Workspace, error) {
-	project := workspace.Project{
-		Name:           tokens.PackageName(profile.ProjectName()),
-		Runtime:        workspace.NewProjectRuntimeInfo("go", nil),
-		Description:    pulumi.StringRef("E2E Test inline project"),
-		StackConfigDir: stackName,
-		Config: map[string]workspace.ProjectConfigType{
-			// Always disable
-			"pulumi:disable-default-providers": {
-				Value: []string{"*"},
-			},
-		},
-	}
-
+// buildWorkspace creates the local workspace backing a stack. When gitSource
+// is non-nil, the workspace checks out its Pulumi program from that Git
+// repository instead of running runFunc inline, mirroring Pulumi's
+// NewStackRemoteSource: see WithGitSource.
+func buildWorkspace(ctx context.Context, profile runner.Profile, stackName string, runFunc pulumi.RunFunc, gitSource *auto.GitRepo, backend *BackendConfig) (auto.Workspace, error) {
 	// create workspace directory
 	workspaceStackDir := profile.GetWorkspacePath(stackName)
 	if err := os.MkdirAll(workspaceStackDir, 0o700); err != nil {
 		return nil, fmt.Errorf("unable to create temporary folder at: %s, err: %w", workspaceStackDir, err)
 	}
 
-	fmt.Printf("Creating workspace for stack: %s at %s\n", stackName, workspaceStackDir)
-	return auto.NewLocalWorkspace(ctx,
-		auto.Project(project),
-		auto.Program(runFunc),
+	envVars := defaultWorkspaceEnvVars
+	var projectBackend *workspace.ProjectBackend
+	if backend != nil {
+		if backend.URL != "" {
+			envVars = mergeEnvVars(envVars, map[string]string{"PULUMI_BACKEND_URL": backend.URL})
+			projectBackend = &workspace.ProjectBackend{URL: backend.URL}
+		}
+		envVars = mergeEnvVars(envVars, backend.EnvVars)
+	}
+
+	opts := []auto.LocalWorkspaceOption{
 		auto.WorkDir(workspaceStackDir),
-		auto.EnvVars(defaultWorkspaceEnvVars),
-	)
+		auto.EnvVars(envVars),
+	}
+	if backend != nil && backend.SecretsProvider != "" {
+		opts = append(opts, auto.SecretsProvider(backend.SecretsProvider))
+	}
+
+	if gitSource != nil {
+		fmt.Printf("Creating workspace for stack: %s at %s from git source %s\n", stackName, workspaceStackDir, gitSource.URL)
+		opts = append(opts, auto.Repo(*gitSource))
+	} else {
+		project := workspace.Project{
+			Name:           tokens.PackageName(profile.ProjectName()),
+			Runtime:        workspace.NewProjectRuntimeInfo("go", nil),
+			Description:    pulumi.StringRef("E2E Test inline project"),
+			StackConfigDir: stackName,
+			Backend:        projectBackend,
+			Config: map[string]workspace.ProjectConfigType{
+				// Always disable
+				"pulumi:disable-default-providers": {
+					Value: []string{"*"},
+				},
+			},
+		}
+
+		fmt.Printf("Creating workspace for stack: %s at %s\n", stackName, workspaceStackDir)
+		opts = append(opts, auto.Project(project), auto.Program(runFunc))
+	}
+
+	return auto.NewLocalWorkspace(ctx, opts...)
+}
+
+// mergeEnvVars returns a new map holding base's entries overridden by
+// override's, leaving both inputs untouched.
+func mergeEnvVars(base, override map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
 }
 
 func buildStackName(namePrefix, stackName string) string {